@@ -5,3 +5,7 @@ import _ "embed"
 //go:embed word_utf8.txt
 var LocationNameBytes []byte
 
+// LocationNameBig5Bytes 与 LocationNameBytes 行号一致的 Big5 原文，即游戏中存放的字节
+//
+//go:embed word_big5.txt
+var LocationNameBig5Bytes []byte
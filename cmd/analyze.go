@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"wcediter/wcsave/batch"
+	"wcediter/wcsave/stride"
+)
+
+// analyzeResult 分析结果，用于 JSON 输出
+type analyzeResult struct {
+	Files      []string           `json:"files"`
+	SelfTest   string             `json:"selfTest"` // 自检失败的原因，通过时为空
+	Candidates []stride.Candidate `json:"candidates"`
+}
+
+// runAnalyze 在多个存档的未解析区域中查找重复的定长记录
+// 用法: wcediter analyze [-min-stride 16] [-max-stride 512] [-min-count 3] [-top 0] [-json] 存档目录、文件或通配符...
+func runAnalyze(args []string) int {
+	defaults := stride.DefaultOptions()
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	minStride := fs.Int("min-stride", defaults.MinStride, "最小记录长度")
+	maxStride := fs.Int("max-stride", defaults.MaxStride, "最大记录长度")
+	minCount := fs.Int("min-count", defaults.MinCount, "最少记录数")
+	top := fs.Int("top", 0, "只显示覆盖字节数最多的若干个候选，0 表示全部")
+	jsonOutput := fs.Bool("json", false, "以 JSON 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Println("用法: wcediter analyze [-min-stride 16] [-max-stride 512] [-min-count 3] [-top 0] [-json] 存档目录、文件或通配符...")
+		fs.PrintDefaults()
+		return 2
+	}
+	if *minStride < 1 || *maxStride < *minStride {
+		fmt.Println("错误: 记录长度范围无效")
+		return 2
+	}
+
+	files, err := batch.ResolveFiles(fs.Args())
+	if err != nil {
+		fmt.Printf("查找存档失败: %v\n", err)
+		return 1
+	}
+
+	options := stride.Options{MinStride: *minStride, MaxStride: *maxStride, MinCount: *minCount}
+	candidates, err := stride.AnalyzeFiles(files, options)
+	if err != nil {
+		printError("读取存档失败", err)
+		return 1
+	}
+	// 自检: 应当重新找到已知的角色记录
+	known, selfTestErr := stride.SelfTest(candidates, options)
+
+	if *top > 0 && len(candidates) > *top {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Stride*candidates[i].Count > candidates[j].Stride*candidates[j].Count
+		})
+		candidates = candidates[:*top]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Offset < candidates[j].Offset
+		})
+	}
+
+	if *jsonOutput {
+		result := analyzeResult{Files: files, Candidates: candidates}
+		if selfTestErr != nil {
+			result.SelfTest = selfTestErr.Error()
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Printf("输出结果失败: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("分析了%d个存档\n", len(files))
+	if selfTestErr != nil {
+		fmt.Printf("自检失败: %v\n", selfTestErr)
+	} else {
+		fmt.Printf("自检通过: %s\n", known)
+	}
+	fmt.Printf("\n候选记录表（%d个）:\n", len(candidates))
+	for _, candidate := range candidates {
+		fmt.Println(candidate)
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"wcediter/wcsave/batch"
+	"wcediter/wcsave/preset"
+)
+
+// runBatch 将修改规格批量应用到多个存档
+// 用法: wcediter batch (-spec 规格.json | -preset 名称1,名称2) [-dry-run] [-workers N] 目录或通配符...
+func runBatch(args []string) int {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	specFile := fs.String("spec", "", "JSON 格式的修改规格文件")
+	presetNames := fs.String("preset", "", "预设名称，多个以逗号分隔，按顺序应用")
+	target := fs.String("target", "", "作用对象：all、index:N（从1开始）或 name:名字1,名字2，默认使用规格中的设置")
+	configFile := fs.String("config", "./wcediter.ini", "包含预设的配置文件")
+	presetDir := fs.String("dir", "./presets", "预设目录")
+	dryRun := fs.Bool("dry-run", false, "只显示将要进行的修改，不写入文件")
+	workers := fs.Int("workers", 0, "并发处理的存档数，默认为CPU核数")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if (*specFile == "") == (*presetNames == "") || fs.NArg() == 0 {
+		fmt.Println("用法: wcediter batch (-spec 规格.json | -preset 名称1,名称2) [-dry-run] [-workers N] 目录或通配符...")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	presets, err := loadBatchPresets(*specFile, *presetNames, *configFile, *presetDir)
+	if err != nil {
+		fmt.Printf("读取修改规格失败: %v\n", err)
+		return 1
+	}
+
+	files, err := batch.ResolveFiles(fs.Args())
+	if err != nil {
+		fmt.Printf("查找存档失败: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Println("没有找到任何存档")
+		return 1
+	}
+
+	results := batch.Run(files, presets, batch.Options{Target: *target, DryRun: *dryRun, Workers: *workers})
+
+	// 输出结果表
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "文件\t状态\t修改数\t角色\t说明")
+	for _, result := range results {
+		status := "已保存"
+		note := ""
+		switch {
+		case result.Err != nil:
+			status = "失败"
+			note = result.Err.Error()
+			failed++
+		case len(result.Changes) == 0:
+			status = "无修改"
+		case *dryRun:
+			status = "预览"
+		default:
+			note = "备份: " + result.Backup
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", result.File, status, len(result.Changes), strings.Join(result.Characters, ","), note)
+	}
+	w.Flush()
+
+	// 预览模式下列出每个字段的修改
+	if *dryRun {
+		for _, result := range results {
+			if result.Err != nil || len(result.Changes) == 0 {
+				continue
+			}
+			fmt.Printf("\n%s:\n", result.File)
+			for _, change := range result.Changes {
+				fmt.Printf("  %s\n", change)
+			}
+		}
+	}
+
+	fmt.Printf("\n共%d个存档，失败%d个\n", len(results), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadBatchPresets 读取 JSON 规格文件，或按名称从配置文件和预设目录中查找预设
+func loadBatchPresets(specFile, presetNames, configFile, presetDir string) ([]preset.Preset, error) {
+	if specFile != "" {
+		p, err := preset.LoadJSON(specFile)
+		if err != nil {
+			return nil, err
+		}
+		return []preset.Preset{p}, nil
+	}
+
+	all, err := preset.LoadAll(configFile, presetDir)
+	if err != nil {
+		return nil, err
+	}
+	presets := make([]preset.Preset, 0)
+	for _, name := range strings.Split(presetNames, ",") {
+		p, ok := preset.Find(all, strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("未找到预设: %s", name)
+		}
+		presets = append(presets, p)
+	}
+	return presets, nil
+}
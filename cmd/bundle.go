@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/bundle"
+	"wcediter/wcsave/models"
+)
+
+// runBundle 导出或导入存档包
+// 用法: wcediter bundle export [-variant Save|Sald|Sav0] 存档目录 输出文件.wcbundle
+//
+//	wcediter bundle import 存档包.wcbundle 目标目录
+func runBundle(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "export":
+			return runBundleExport(args[1:])
+		case "import":
+			return runBundleImport(args[1:])
+		}
+	}
+	fmt.Println("用法: wcediter bundle export [-variant Save|Sald|Sav0] 存档目录 输出文件" + bundle.Extension)
+	fmt.Println("      wcediter bundle import 存档包" + bundle.Extension + " 目标目录")
+	return 2
+}
+
+// runBundleExport 将目录中一个版本的全部存档和 WC.cfg 打包
+func runBundleExport(args []string) int {
+	fs := flag.NewFlagSet("bundle export", flag.ContinueOnError)
+	variant := fs.String("variant", "", "存档版本: "+strings.Join(wcsave.Variants, "、")+"，目录中只有一个版本时可省略")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Println("用法: wcediter bundle export [-variant Save|Sald|Sav0] 存档目录 输出文件" + bundle.Extension)
+		fs.PrintDefaults()
+		return 2
+	}
+	dir, output := fs.Arg(0), fs.Arg(1)
+
+	if *variant == "" {
+		gameDir, err := wcsave.OpenGameDir(dir)
+		if err != nil {
+			printError("打开存档目录失败", err)
+			return 1
+		}
+		variants := gameDir.VariantNames()
+		switch len(variants) {
+		case 0:
+			fmt.Printf("%s中没有存档文件\n", dir)
+			return 1
+		case 1:
+			*variant = variants[0]
+		default:
+			fmt.Printf("%s中有多个版本的存档（%s），请用 -variant 指定\n", dir, strings.Join(variants, "、"))
+			return 2
+		}
+	}
+
+	manifest, err := bundle.ExportFile(dir, *variant, output)
+	if err != nil {
+		printError("导出存档包失败", err)
+		return 1
+	}
+	printManifest(manifest)
+	fmt.Printf("已导出 %d 个文件: %s\n", len(manifest.Files), output)
+	return 0
+}
+
+// runBundleImport 校验存档包并安装到目标目录
+func runBundleImport(args []string) int {
+	fs := flag.NewFlagSet("bundle import", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Println("用法: wcediter bundle import 存档包" + bundle.Extension + " 目标目录")
+		return 2
+	}
+
+	manifest, installed, err := bundle.Import(fs.Arg(0), fs.Arg(1))
+	for _, item := range installed {
+		switch {
+		case item.Same:
+			fmt.Printf("  %s: 内容相同，未修改\n", item.Path)
+		case item.Backup != "":
+			fmt.Printf("  %s: 已替换，原文件备份为 %s\n", item.Path, item.Backup)
+		default:
+			fmt.Printf("  %s: 已写入\n", item.Path)
+		}
+	}
+	if err != nil {
+		printError("导入存档包失败", err)
+		return 1
+	}
+	printManifest(manifest)
+	return 0
+}
+
+// printManifest 显示存档包清单中的队伍和进度概况
+func printManifest(manifest bundle.Manifest) {
+	fmt.Printf("版本: %s，打包时间: %s\n", manifest.Variant, manifest.Created.Format("2006-01-02 15:04:05"))
+	for _, slot := range manifest.Slots {
+		if slot.Error != "" {
+			fmt.Printf("  %s: 读取角色失败: %s\n", slot.File, slot.Error)
+			continue
+		}
+		members := make([]string, 0, len(slot.Party))
+		for _, member := range slot.Party {
+			members = append(members, fmt.Sprintf("%s Lv%d", member.Name, member.Level))
+		}
+		fmt.Printf("  %s: %s\n", slot.File, strings.Join(members, ", "))
+	}
+	for i, progress := range manifest.Progress {
+		fmt.Printf("  进度%d: %s\n", i+1, progressText(progress))
+	}
+}
+
+// progressText 返回进度的显示文本
+func progressText(progress models.ProgressInfo) string {
+	if progress.LocationName != "" {
+		return fmt.Sprintf("%s（%d）", strings.TrimSpace(progress.LocationName), progress.ProgressID)
+	}
+	return fmt.Sprintf("进度%d", progress.ProgressID)
+}
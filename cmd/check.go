@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"wcediter/wcsave/batch"
+	"wcediter/wcsave/check"
+)
+
+// runCheck 检查存档完整性
+// 用法: wcediter check [-json] [-strict] 存档文件、目录或通配符...
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "以 JSON 格式输出报告")
+	strict := fs.Bool("strict", false, "有警告时也返回非零退出码")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Println("用法: wcediter check [-json] [-strict] 存档文件、目录或通配符...")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	files, err := batch.ResolveFiles(fs.Args())
+	if err != nil {
+		fmt.Printf("查找存档失败: %v\n", err)
+		return 1
+	}
+
+	reports := make([]check.Report, 0, len(files))
+	exitCode := 0
+	for _, file := range files {
+		report := check.File(file)
+		reports = append(reports, report)
+		if !report.OK() || (*strict && report.Worst() == check.Warning) {
+			exitCode = 1
+		}
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			fmt.Printf("输出报告失败: %v\n", err)
+			return 1
+		}
+		return exitCode
+	}
+
+	for _, report := range reports {
+		fmt.Print(report)
+	}
+	return exitCode
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+)
+
+// loadCustomLayout 读取配置文件旁的自定义布局（wcediter.layout.yaml 等）
+func loadCustomLayout(configFile string) error {
+	path, err := layout.LoadBeside(configFile)
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		fmt.Printf("已加载自定义布局: %s（%d个角色字段，%d个文件字段）\n", path, len(layout.Extra.Character), len(layout.Extra.File))
+	}
+	return nil
+}
+
+// editableCustomFields 返回交互模式中可以修改的自定义角色字段（整数类型）
+func editableCustomFields() []layout.Field {
+	fields := make([]layout.Field, 0, len(layout.Extra.Character))
+	for _, field := range layout.Extra.Character {
+		if field.Type.Integer() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// printCharacterCustomFields 输出角色的自定义字段
+func printCharacterCustomFields(editor *wcsave.SaveEditor, char models.CharacterInfo) {
+	for _, field := range layout.Extra.Character {
+		fmt.Printf("%s: %s\n", field.Label, editor.CustomText(field.At(char.Position)))
+	}
+}
+
+// printFileCustomFields 输出文件级的自定义字段
+func printFileCustomFields(editor *wcsave.SaveEditor) {
+	if len(layout.Extra.File) == 0 {
+		return
+	}
+	fmt.Println("\n=== 自定义字段 ===")
+	for _, field := range layout.Extra.File {
+		fmt.Printf("%s: %s\n", field.Label, editor.CustomText(field))
+	}
+}
+
+// printCustomChanges 比较保存前后文件中的自定义字段
+func printCustomChanges(editor *wcsave.SaveEditor, sourceFilePath, destFilePath string) {
+	if len(layout.Extra.Character) == 0 && len(layout.Extra.File) == 0 {
+		return
+	}
+	before, err := os.ReadFile(sourceFilePath)
+	if err != nil {
+		return
+	}
+	after, err := os.ReadFile(destFilePath)
+	if err != nil {
+		return
+	}
+
+	changed := false
+	compare := func(group string, field layout.Field) {
+		oldText, newText := layout.Decode(field, before), layout.Decode(field, after)
+		if oldText != newText {
+			if !changed {
+				fmt.Println("\n自定义字段修改对比:")
+				changed = true
+			}
+			fmt.Printf("%s%s: [%s] -> [%s] ✓\n", group, field.Label, oldText, newText)
+		}
+	}
+	for _, char := range editor.Characters {
+		for _, field := range layout.Extra.Character {
+			compare(char.Name+" ", field.At(char.Position))
+		}
+	}
+	for _, field := range layout.Extra.File {
+		compare("", field)
+	}
+}
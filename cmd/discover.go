@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"wcediter/wcsave/discover"
+
+	"gopkg.in/ini.v1"
+)
+
+// runDiscover 查找磁盘上的游戏存档目录
+// 用法: wcediter discover [-depth 6] [-add] [-config wcediter.ini] [搜索目录...]
+func runDiscover(args []string) int {
+	fs := flag.NewFlagSet("discover", flag.ContinueOnError)
+	maxDepth := fs.Int("depth", discover.DefaultMaxDepth, "最大搜索深度")
+	add := fs.Bool("add", false, "将找到的存档添加到配置文件的选择记录中")
+	configFile := fs.String("config", "./wcediter.ini", "配置文件路径")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	roots := fs.Args()
+	if len(roots) == 0 {
+		roots = discover.DefaultRoots()
+	}
+	fmt.Printf("搜索目录: %v\n", roots)
+
+	// Ctrl+C 停止搜索，已找到的结果仍然输出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var records []discover.Record
+	err := discover.Scan(ctx, roots, discover.Options{MaxDepth: *maxDepth}, func(result discover.Result) {
+		for _, record := range result.Records() {
+			fmt.Printf("  %s: %s\n", record.Tag, record.Path)
+			records = append(records, record)
+		}
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("搜索已取消")
+	} else if err != nil {
+		printError("搜索失败", err)
+		return 1
+	}
+	fmt.Printf("共找到%d组存档\n", len(records))
+
+	if *add && len(records) > 0 {
+		added, err := addRecords(*configFile, records)
+		if err != nil {
+			printError("写入配置文件失败", err)
+			return 1
+		}
+		fmt.Printf("已添加%d条选择记录到 %s\n", added, *configFile)
+	}
+	return 0
+}
+
+// addRecords 将存档添加到配置文件的 [Records] 节，跳过已有的路径，标签重复时添加数字后缀
+func addRecords(configFile string, records []discover.Record) (int, error) {
+	cfg, err := ini.LooseLoad(configFile)
+	if err != nil {
+		return 0, err
+	}
+
+	usedTags := make(map[string]bool)
+	usedPaths := make(map[string]bool)
+	for _, name := range []string{"default_Records", "Records"} {
+		for _, key := range cfg.Section(name).Keys() {
+			usedTags[key.Name()] = true
+			usedPaths[key.String()] = true
+		}
+	}
+
+	added := 0
+	section := cfg.Section("Records")
+	for _, record := range records {
+		if usedPaths[record.Path] {
+			continue
+		}
+		tag := record.Tag
+		for counter := 1; usedTags[tag]; counter++ {
+			tag = fmt.Sprintf("%s(%d)", record.Tag, counter)
+		}
+		section.Key(tag).SetValue(record.Path)
+		usedTags[tag] = true
+		usedPaths[record.Path] = true
+		added++
+	}
+	return added, cfg.SaveTo(configFile)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"wcediter/wcsave/layout"
+)
+
+// runLayout 处理存档布局相关的操作
+// 用法: wcediter layout export -format imhex|010|kaitai [-target save|cfg] [-output 文件] [-config wcediter.ini]
+func runLayout(args []string) int {
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Println("用法: wcediter layout export -format imhex|010|kaitai [-target save|cfg] [-output 文件] [-config wcediter.ini]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("layout export", flag.ContinueOnError)
+	format := fs.String("format", "", "模板格式: "+strings.Join(layout.Formats, "、"))
+	target := fs.String("target", layout.TargetSave, "导出的文件布局: save（存档）或 cfg（WC.cfg）")
+	output := fs.String("output", "", "输出文件，默认输出到标准输出")
+	configFile := fs.String("config", "./wcediter.ini", "配置文件路径，同目录中的自定义布局一并导出")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *format == "" || fs.NArg() != 0 {
+		fmt.Println("用法: wcediter layout export -format imhex|010|kaitai [-target save|cfg] [-output 文件] [-config wcediter.ini]")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	// 模板可能输出到标准输出，提示信息写入标准错误
+	path, err := layout.LoadBeside(*configFile)
+	if err != nil {
+		printError("读取自定义布局失败", err)
+		return 1
+	}
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "已加载自定义布局: %s\n", path)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			printError("创建输出文件失败", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := layout.Export(w, *format, *target); err != nil {
+		printError("导出失败", err)
+		return 1
+	}
+	if *output != "" {
+		fmt.Printf("已导出 %s 模板: %s\n", *format, *output)
+	}
+	return 0
+}
@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -13,11 +14,58 @@ import (
 	"wcediter/wcsave/models"
 )
 
+// subcommands 子命令列表，未匹配到子命令时使用原有的交互式编辑模式
+var subcommands = map[string]func(args []string) int{
+	"analyze":      runAnalyze,
+	"apply-preset": runApplyPreset,
+	"batch":        runBatch,
+	"bundle":       runBundle,
+	"check":        runCheck,
+	"discover":     runDiscover,
+	"layout":       runLayout,
+	"patch":        runPatch,
+	"repair":       runRepair,
+	"script":       runScript,
+	"serve":        runServe,
+	"strings":      runStrings,
+}
+
+// openProgress 读取 -progress 指定的进度信息到 editor，返回实际读取的进度文件
+// path 为目录时按游戏目录读取其中的 WC.cfg，并返回 GameDir 以关联各版本的存档文件；
+// 为文件时直接读取（可以是 WC_backup.cfg 等任意文件名），返回的 GameDir 为 nil
+func openProgress(editor *wcsave.SaveEditor, path string) (string, *wcsave.GameDir, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if !info.IsDir() {
+		_, err := editor.ReadProgress(path)
+		return path, nil, err
+	}
+
+	gameDir, err := wcsave.OpenGameDir(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if gameDir.ConfigErr != nil {
+		return "", nil, gameDir.ConfigErr
+	}
+	editor.ProgressInfos = gameDir.Progress
+	return gameDir.ConfigPath(), gameDir, nil
+}
+
 func main() {
+	// 优先处理子命令
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	// 命令行参数解析
 	sourceFilePathFlag := flag.String("input", "", "输入存档文件路径")
 	destFilePathFlag := flag.String("output", "", "输出存档文件路径")
-	progressFilePathFlag := flag.String("progress", "", "读取进度信息（WC.cfg 文件路径）")
+	progressFilePathFlag := flag.String("progress", "", "读取进度信息（游戏目录或进度文件路径，如 WC.cfg）")
 	flag.Parse()
 
 	// 使用命令行参数
@@ -28,7 +76,7 @@ func main() {
 	// 如果提供了 -progress 参数，读取并显示进度信息
 	if progressFilePath != "" {
 		editor := wcsave.NewSaveEditor()
-		progressInfos, err := editor.ReadProgress(progressFilePath)
+		cfgPath, gameDir, err := openProgress(editor, progressFilePath)
 		if err != nil {
 			fmt.Printf("读取进度文件失败: %v\n", err)
 			os.Exit(1)
@@ -37,14 +85,28 @@ func main() {
 		fmt.Println("===================================")
 		fmt.Println("游戏进度信息")
 		fmt.Println("===================================")
-		fmt.Printf("进度文件: %s\n\n", progressFilePath)
+		fmt.Printf("进度文件: %s\n\n", cfgPath)
 		fmt.Println("=== 进度列表 ===")
 
-		for i, info := range progressInfos {
+		for i, info := range editor.ProgressInfos {
 			fmt.Printf("\n进度 %d:\n", i+1)
 			fmt.Printf("  进度编号: %d\n", info.ProgressID)
 			fmt.Printf("  位置编号: %d\n", info.LocationID)
 			fmt.Printf("  位置名称: %s\n", info.LocationName)
+
+			// 指定游戏目录时列出各版本中对应该进度的存档文件
+			if gameDir == nil {
+				continue
+			}
+			var files []string
+			for _, set := range gameDir.Sets {
+				if slices.Contains(set.Indexes(), i+1) {
+					files = append(files, wcsave.SlotFileName(set.Variant, i+1))
+				}
+			}
+			if len(files) > 0 {
+				fmt.Printf("  存档文件: %s\n", strings.Join(files, "、"))
+			}
 		}
 
 		fmt.Println("\n操作完成！")
@@ -74,7 +136,20 @@ func main() {
 	fmt.Println("使用说明:")
 	fmt.Println("  -input <文件路径>  指定输入存档文件路径 (必需，除非使用 -progress)")
 	fmt.Println("  -output <文件路径> 指定输出存档文件路径 (可选)")
-	fmt.Println("  -progress <文件路径> 读取进度信息 (WC.cfg 文件路径)")
+	fmt.Println("  -progress <文件路径> 读取进度信息 (游戏目录或进度文件路径，如 WC.cfg)")
+	fmt.Println("子命令:")
+	fmt.Println("  analyze            在多个存档的未解析区域中查找重复的定长记录")
+	fmt.Println("  apply-preset       将预设应用到存档")
+	fmt.Println("  batch              将修改规格批量应用到多个存档")
+	fmt.Println("  bundle             导出或导入包含全部存档和 WC.cfg 的存档包")
+	fmt.Println("  check              检查存档完整性")
+	fmt.Println("  discover           查找磁盘上的游戏存档目录")
+	fmt.Println("  layout             导出存档布局模板（ImHex、010 Editor、Kaitai Struct）")
+	fmt.Println("  patch              生成补丁，或将补丁应用到多个存档")
+	fmt.Println("  repair             根据参考存档修复损坏的存档")
+	fmt.Println("  script             运行脚本修改存档")
+	fmt.Println("  serve              启动本地 HTTP/JSON 接口服务")
+	fmt.Println("  strings            查找存档中的 Big5 文本")
 	fmt.Println("例如:")
 	fmt.Println("  读取存档: go run main.go -input Save.dat -output Save_modified.dat")
 	fmt.Println("  读取进度: go run main.go -progress WC.cfg")
@@ -101,13 +176,19 @@ func main() {
 		}
 	}
 
+	// 自定义布局中的字段会随内置字段一起显示和修改
+	if err := loadCustomLayout("./wcediter.ini"); err != nil {
+		fmt.Printf("读取自定义布局失败: %v\n", err)
+		os.Exit(1)
+	}
+
 	// 创建存档编辑器实例
 	editor := wcsave.NewSaveEditor()
 
 	// 读取存档数据
 	err := editor.ReadSave(sourceFilePath)
 	if err != nil {
-		fmt.Printf("读取存档文件失败: %v\n", err)
+		printError("读取存档文件失败", err)
 		os.Exit(1)
 	}
 
@@ -137,6 +218,7 @@ func main() {
 		fmt.Printf("防御: %d\n", char.Data.Defense)
 		fmt.Printf("运气: %d\n", char.Data.Luck)
 		fmt.Printf("等级: %d\n", char.Data.Level)
+		printCharacterCustomFields(editor, char)
 	}
 
 	// 角色列表概览
@@ -150,6 +232,14 @@ func main() {
 	fmt.Println("\n=== 银两数据 ===")
 	fmt.Printf("银两: %d\n", editor.MoneyInfo.Value)
 
+	// 显示位置数据
+	if len(editor.PositionInfo.RawBytes) > 0 {
+		fmt.Println("\n=== 位置数据 ===")
+		fmt.Printf("地图: %d\n", editor.PositionInfo.MapID)
+		fmt.Printf("坐标: (%d, %d)\n", editor.PositionInfo.X, editor.PositionInfo.Y)
+	}
+	printFileCustomFields(editor)
+
 	// 只有在指定了输出文件时才显示修改功能
 	var needModifications bool = false
 	if destFilePath != "" {
@@ -203,11 +293,15 @@ func main() {
 					fmt.Println("12. 防御")
 					fmt.Println("13. 运气")
 					fmt.Println("14. 等级")
+					customFields := editableCustomFields()
+					for j, field := range customFields {
+						fmt.Printf("%d. %s\n", 15+j, field.Label)
+					}
 					fmt.Println("0. 完成该角色的修改")
 
 					attrChoiceStr := getUserInput("请选择要修改的属性编号: ")
 					attrChoice, err := strconv.Atoi(attrChoiceStr)
-					if err != nil || attrChoice < 0 || attrChoice > 14 {
+					if err != nil || attrChoice < 0 || attrChoice > 14+len(customFields) {
 						fmt.Println("无效的属性编号，请重新选择")
 						continue
 					}
@@ -216,6 +310,28 @@ func main() {
 						break
 					}
 
+					// 自定义字段以原始字节修改的方式写入
+					if attrChoice > 14 {
+						field := customFields[attrChoice-15].At(char.Position)
+						if len(field.Enum) > 0 {
+							for _, value := range field.EnumValues() {
+								fmt.Printf("  %s\n", field.Format(value))
+							}
+						}
+						oldText := editor.CustomText(field)
+						value, parseErr := field.Parse(getUserInput(fmt.Sprintf("请输入新的%s值（当前%s）: ", field.Label, oldText)))
+						if parseErr != nil {
+							fmt.Println(parseErr)
+							continue
+						}
+						if setErr := editor.SetCustomValue(field, value); setErr != nil {
+							fmt.Println(setErr)
+							continue
+						}
+						fmt.Printf("%s 修改成功: %s -> %s\n", field.Label, oldText, editor.CustomText(field))
+						continue
+					}
+
 					var valueName string
 					var is32Bit bool
 
@@ -347,7 +463,7 @@ func main() {
 			fmt.Println("\n=== 保存修改 ===")
 			err = editor.SaveChanges(sourceFilePath, destFilePath)
 			if err != nil {
-				fmt.Printf("保存修改失败: %v\n", err)
+				printError("保存修改失败", err)
 			} else {
 				fmt.Printf("已创建修改后的文件: %s\n", destFilePath)
 
@@ -452,9 +568,20 @@ func main() {
 						}
 					}
 				}
+
+				// 自定义字段对比
+				printCustomChanges(editor, sourceFilePath, destFilePath)
 			}
 		}
 	}
 
 	fmt.Println("\n操作完成！")
 }
+
+// printError 输出错误信息，能判断错误类型时附加处理建议
+func printError(message string, err error) {
+	fmt.Printf("%s: %v\n", message, err)
+	if advice := wcsave.Advice(err); advice != "" {
+		fmt.Printf("建议: %s\n", advice)
+	}
+}
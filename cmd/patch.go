@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/batch"
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/patch"
+)
+
+// runPatch 生成补丁或将补丁应用到存档
+// 用法: wcediter patch create [-output 文件] 原存档 修改后的存档
+//
+//	wcediter patch apply [-force] 补丁文件 存档文件、目录或通配符...
+func runPatch(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "create":
+			return runPatchCreate(args[1:])
+		case "apply":
+			return runPatchApply(args[1:])
+		}
+	}
+	fmt.Println("用法: wcediter patch create [-output 文件] 原存档 修改后的存档")
+	fmt.Println("      wcediter patch apply [-force] 补丁文件 存档文件、目录或通配符...")
+	return 2
+}
+
+// runPatchCreate 比较两个存档，输出补丁
+func runPatchCreate(args []string) int {
+	fs := flag.NewFlagSet("patch create", flag.ContinueOnError)
+	output := fs.String("output", "", "输出补丁文件（"+patch.Extension+"），默认输出到标准输出")
+	configFile := fs.String("config", "./wcediter.ini", "配置文件路径，同目录中的自定义布局字段按字段记录")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Println("用法: wcediter patch create [-output 文件] 原存档 修改后的存档")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	// 补丁可能输出到标准输出，提示信息写入标准错误
+	if !loadPatchLayout(*configFile) {
+		return 1
+	}
+
+	p, err := patch.CreateFiles(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		printError("生成补丁失败", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			printError("创建输出文件失败", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := patch.Write(w, p); err != nil {
+		printError("写入补丁失败", err)
+		return 1
+	}
+	for _, op := range p.Ops {
+		fmt.Fprintf(os.Stderr, "  %s\n", op)
+	}
+	fmt.Fprintf(os.Stderr, "共%d项修改\n", len(p.Ops))
+	return 0
+}
+
+// runPatchApply 将补丁应用到一个或多个存档，直接修改存档文件
+func runPatchApply(args []string) int {
+	fs := flag.NewFlagSet("patch apply", flag.ContinueOnError)
+	force := fs.Bool("force", false, "存档的原内容与补丁不一致时仍然应用")
+	configFile := fs.String("config", "./wcediter.ini", "配置文件路径，补丁包含自定义字段时需要加载相同的布局")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 2 {
+		fmt.Println("用法: wcediter patch apply [-force] 补丁文件 存档文件、目录或通配符...")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	if !loadPatchLayout(*configFile) {
+		return 1
+	}
+
+	p, err := patch.Load(fs.Arg(0))
+	if err != nil {
+		printError("读取补丁失败", err)
+		return 1
+	}
+
+	files, err := batch.ResolveFiles(fs.Args()[1:])
+	if err != nil {
+		fmt.Printf("查找存档失败: %v\n", err)
+		return 1
+	}
+
+	exitCode := 0
+	for _, file := range files {
+		editor := wcsave.NewSaveEditor()
+		if err := editor.ReadSave(file); err != nil {
+			printError(file+": 读取存档失败", err)
+			exitCode = 1
+			continue
+		}
+
+		result, err := p.Apply(editor, *force)
+		for _, conflict := range result.Conflicts {
+			fmt.Printf("%s: %s，当前为 %s\n", file, conflict.Op, conflict.Current)
+		}
+		if err != nil {
+			if errors.Is(err, patch.ErrConflict) {
+				fmt.Printf("%s: 跳过: %v\n", file, err)
+			} else {
+				fmt.Printf("%s: 应用补丁失败: %v\n", file, err)
+			}
+			exitCode = 1
+			continue
+		}
+
+		if err := editor.SaveChanges(file, file); err != nil {
+			printError(file+": 保存失败", err)
+			exitCode = 1
+			continue
+		}
+		fmt.Printf("%s: 已应用%d项修改\n", file, result.Applied)
+	}
+	return exitCode
+}
+
+// loadPatchLayout 加载配置文件旁的自定义布局，失败时输出错误并返回 false
+func loadPatchLayout(configFile string) bool {
+	path, err := layout.LoadBeside(configFile)
+	if err != nil {
+		printError("读取自定义布局失败", err)
+		return false
+	}
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "已加载自定义布局: %s\n", path)
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/preset"
+)
+
+// runApplyPreset 将预设应用到一个或多个存档
+// 用法: wcediter apply-preset -preset 名称 [-target all|index:N|name:名字] [-output 文件] Save3.dat ...
+func runApplyPreset(args []string) int {
+	fs := flag.NewFlagSet("apply-preset", flag.ContinueOnError)
+	presetName := fs.String("preset", "", "预设名称")
+	target := fs.String("target", "", "作用对象：all、index:N（从1开始）或 name:名字1,名字2，默认使用预设中的设置")
+	configFile := fs.String("config", "./wcediter.ini", "包含预设的配置文件")
+	presetDir := fs.String("dir", "./presets", "预设目录")
+	output := fs.String("output", "", "输出存档文件路径（仅处理单个存档时可用），默认直接修改输入文件")
+	list := fs.Bool("list", false, "列出所有可用的预设")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	presets, err := preset.LoadAll(*configFile, *presetDir)
+	if err != nil {
+		fmt.Printf("读取预设失败: %v\n", err)
+		return 1
+	}
+
+	if *list {
+		for _, p := range presets {
+			fields := make([]string, 0, len(p.Actions))
+			for _, action := range p.Actions {
+				fields = append(fields, fmt.Sprintf("%s %s %g", action.Field, action.Op, action.Value))
+			}
+			fmt.Printf("%s [%s]: %s\n", p.Name, p.Target, strings.Join(fields, ", "))
+		}
+		return 0
+	}
+
+	if *presetName == "" || fs.NArg() == 0 {
+		fmt.Println("用法: wcediter apply-preset -preset 名称 [-target all|index:N|name:名字] [-output 文件] 存档文件...")
+		fs.PrintDefaults()
+		return 2
+	}
+	if *output != "" && fs.NArg() > 1 {
+		fmt.Println("错误: 处理多个存档时不能指定 -output")
+		return 2
+	}
+
+	p, ok := preset.Find(presets, *presetName)
+	if !ok {
+		fmt.Printf("未找到预设: %s\n", *presetName)
+		return 1
+	}
+
+	exitCode := 0
+	for _, sourceFilePath := range fs.Args() {
+		destFilePath := sourceFilePath
+		if *output != "" {
+			destFilePath = *output
+		}
+
+		editor := wcsave.NewSaveEditor()
+		if err := editor.ReadSave(sourceFilePath); err != nil {
+			printError(sourceFilePath+": 读取存档失败", err)
+			exitCode = 1
+			continue
+		}
+
+		result, err := p.Apply(editor, *target)
+		if err != nil {
+			fmt.Printf("%s: 应用预设失败: %v\n", sourceFilePath, err)
+			exitCode = 1
+			continue
+		}
+
+		if err := editor.SaveChanges(sourceFilePath, destFilePath); err != nil {
+			printError(sourceFilePath+": 保存失败", err)
+			exitCode = 1
+			continue
+		}
+
+		fmt.Printf("%s -> %s: 已修改角色 [%s]", sourceFilePath, destFilePath, strings.Join(result.Characters, ", "))
+		if result.Money {
+			fmt.Printf("，银两 %d", editor.MoneyInfo.Value)
+		}
+		fmt.Println()
+	}
+
+	return exitCode
+}
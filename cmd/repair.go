@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"wcediter/wcsave/repair"
+)
+
+// runRepair 根据参考存档修复损坏的存档，写入修复后的副本
+// 用法: wcediter repair [-reference Save0.dat] [-output 文件] [-yes] [-dry-run] Save3.dat
+func runRepair(args []string) int {
+	fs := flag.NewFlagSet("repair", flag.ContinueOnError)
+	referencePath := fs.String("reference", "", "同类存档中已知完好的参考文件，默认使用同目录中编号为0的存档")
+	output := fs.String("output", "", "修复后的存档路径，默认为 原文件名.repaired.dat")
+	yes := fs.Bool("yes", false, "不询问直接写入")
+	dryRun := fs.Bool("dry-run", false, "只显示将要进行的修复")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Println("用法: wcediter repair [-reference Save0.dat] [-output 文件] [-yes] [-dry-run] 存档文件")
+		fs.PrintDefaults()
+		return 2
+	}
+	sourceFilePath := fs.Arg(0)
+
+	if *referencePath == "" {
+		*referencePath = repair.DefaultReference(sourceFilePath)
+		if *referencePath == "" {
+			fmt.Println("错误: 无法确定参考存档，请使用 -reference 指定")
+			return 2
+		}
+	}
+	destFilePath := *output
+	if destFilePath == "" {
+		destFilePath = strings.TrimSuffix(sourceFilePath, ".dat") + ".repaired.dat"
+	}
+
+	result, err := repair.File(sourceFilePath, *referencePath)
+	if err != nil {
+		fmt.Printf("修复失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("存档: %s\n参考: %s\n", sourceFilePath, *referencePath)
+	if len(result.Fixes) == 0 {
+		fmt.Println("没有需要修复的内容")
+		return 0
+	}
+
+	fmt.Printf("\n将进行%d处修复:\n", len(result.Fixes))
+	for _, fix := range result.Fixes {
+		fmt.Println(fix)
+	}
+	if len(result.Report.Issues) > 0 {
+		fmt.Printf("\n修复后仍存在的问题:\n%s", result.Report)
+	}
+
+	if *dryRun {
+		return 0
+	}
+	if !*yes {
+		fmt.Printf("\n写入 %s ？(y/N): ", destFilePath)
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Scan()
+		if answer := strings.ToLower(strings.TrimSpace(scanner.Text())); answer != "y" && answer != "yes" {
+			fmt.Println("已取消")
+			return 1
+		}
+	}
+
+	backupPath, err := result.Write(destFilePath)
+	if err != nil {
+		fmt.Printf("写入失败: %v\n", err)
+		return 1
+	}
+	if backupPath != "" {
+		fmt.Printf("原文件已备份为: %s\n", backupPath)
+	}
+	fmt.Printf("已写入修复后的存档: %s\n", destFilePath)
+	return 0
+}
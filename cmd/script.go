@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/script"
+)
+
+// runScript 运行脚本修改存档
+// 用法: wcediter script [-progress WC.cfg] [-output 文件] [-dry-run] edit.js Save3.dat
+func runScript(args []string) int {
+	fs := flag.NewFlagSet("script", flag.ContinueOnError)
+	progressFile := fs.String("progress", "", "游戏目录或进度文件路径，如 WC.cfg，默认使用存档所在目录中的 WC.cfg")
+	output := fs.String("output", "", "输出存档文件路径，默认直接修改输入文件")
+	dryRun := fs.Bool("dry-run", false, "只运行脚本并显示结果，不写入文件")
+	timeout := fs.Duration("timeout", script.DefaultTimeout, "脚本最长运行时间")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() != 2 {
+		fmt.Println("用法: wcediter script [-progress WC.cfg] [-output 文件] [-dry-run] 脚本.js 存档文件")
+		fs.PrintDefaults()
+		return 2
+	}
+	scriptFile, sourceFilePath := fs.Arg(0), fs.Arg(1)
+	destFilePath := sourceFilePath
+	if *output != "" {
+		destFilePath = *output
+	}
+
+	source, err := os.ReadFile(scriptFile)
+	if err != nil {
+		fmt.Printf("读取脚本失败: %v\n", err)
+		return 1
+	}
+
+	editor := wcsave.NewSaveEditor()
+	if err := editor.ReadSave(sourceFilePath); err != nil {
+		printError("读取存档失败", err)
+		return 1
+	}
+
+	// 进度信息是可选的，未指定时尝试读取存档所在游戏目录中的 WC.cfg
+	if *progressFile != "" {
+		if _, _, err := openProgress(editor, *progressFile); err != nil {
+			fmt.Printf("读取进度文件失败: %v\n", err)
+			return 1
+		}
+	} else if gameDir, err := wcsave.OpenGameDir(filepath.Dir(sourceFilePath)); err == nil && gameDir.ConfigErr == nil {
+		editor.ProgressInfos = gameDir.Progress
+	}
+
+	result, err := script.Run(editor, string(source), script.Options{Timeout: *timeout, Output: os.Stdout})
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	fmt.Printf("已修改角色 [%s]", strings.Join(result.Characters, ", "))
+	if result.Money {
+		fmt.Printf("，银两 %d", editor.MoneyInfo.Value)
+	}
+	if result.Position {
+		fmt.Printf("，位置 地图%d (%d, %d)", editor.PositionInfo.MapID, editor.PositionInfo.X, editor.PositionInfo.Y)
+	}
+	fmt.Println()
+
+	if *dryRun {
+		return 0
+	}
+	if err := editor.SaveChanges(sourceFilePath, destFilePath); err != nil {
+		printError("保存失败", err)
+		return 1
+	}
+	fmt.Printf("已保存到 %s\n", destFilePath)
+	return 0
+}
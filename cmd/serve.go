@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"wcediter/wcsave/server"
+)
+
+// runServe 启动本地 HTTP/JSON 接口服务
+// 用法: wcediter serve --addr 127.0.0.1:8080 --root ./saves
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "监听地址")
+	root := fs.String("root", ".", "存档根目录，接口只能访问该目录内的文件")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	srv, err := server.NewServer(*root)
+	if err != nil {
+		fmt.Printf("无效的根目录: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("存档根目录: %s\n", srv.Root)
+	fmt.Printf("接口地址: http://%s/api/saves\n", *addr)
+	fmt.Printf("接口描述: http://%s/api/openapi.json\n", *addr)
+	if err := http.ListenAndServe(*addr, srv.Handler()); err != nil {
+		log.Printf("服务已停止: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/batch"
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/textscan"
+)
+
+// stringsResult 一个文件中找到的文本，用于 JSON 输出
+type stringsResult struct {
+	File    string           `json:"file"`
+	Matches []textscan.Match `json:"matches"`
+}
+
+// runStrings 查找存档中的 Big5 文本
+// 用法: wcediter strings [-min 2] [-locations] [-json] 存档文件、目录或通配符...
+func runStrings(args []string) int {
+	fs := flag.NewFlagSet("strings", flag.ContinueOnError)
+	minChars := fs.Int("min", textscan.DefaultMinChars, "最少字数")
+	locationsOnly := fs.Bool("locations", false, "只显示与位置名称表相同的文本")
+	jsonOutput := fs.Bool("json", false, "以 JSON 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Println("用法: wcediter strings [-min 2] [-locations] [-json] 存档文件、目录或通配符...")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	files, err := batch.ResolveFiles(fs.Args())
+	if err != nil {
+		fmt.Printf("查找存档失败: %v\n", err)
+		return 1
+	}
+
+	results := make([]stringsResult, 0, len(files))
+	for _, file := range files {
+		matches, err := textscan.ScanFile(file, *minChars)
+		if err != nil {
+			printError(fmt.Sprintf("读取 %s 失败", file), err)
+			return 1
+		}
+		// 能按存档读取时，标注位于已知字段中的文本（如角色名）
+		editor := wcsave.NewSaveEditor()
+		if editor.ReadSave(file) == nil {
+			textscan.Annotate(matches, layout.Regions(editor.Characters, editor.MoneyInfo, editor.PositionInfo))
+		}
+		if *locationsOnly {
+			filtered := matches[:0]
+			for _, match := range matches {
+				if len(match.LocationIDs) > 0 {
+					filtered = append(filtered, match)
+				}
+			}
+			matches = filtered
+		}
+		results = append(results, stringsResult{File: file, Matches: matches})
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			fmt.Printf("输出结果失败: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	for _, result := range results {
+		fmt.Printf("=== %s（%d处）===\n", result.File, len(result.Matches))
+		for _, match := range result.Matches {
+			fmt.Printf("0x%06X %3d  %s", match.Offset, match.Length, match.Text)
+			if len(match.LocationIDs) > 0 {
+				fmt.Printf("  [位置%v]", match.LocationIDs)
+			}
+			if match.Field != "" {
+				fmt.Printf("  <%s>", match.Field)
+			}
+			fmt.Println()
+		}
+	}
+	return 0
+}
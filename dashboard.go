@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"wcediter/wcsave"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newSlotDashboard 创建存档概况面板，并排显示一组存档中五个进度的队伍、银两、位置和修改时间
+// 返回面板和刷新函数，刷新函数参数为选择记录中的存档路径；点击卡片时以进度索引调用 onOpen
+func newSlotDashboard(onOpen func(progressIndex int)) (*fyne.Container, func(saveFilePath string)) {
+	grid := container.NewGridWithColumns(wcsave.SlotCount - 1)
+
+	// 快速切换选择记录时，只显示最后一次刷新的结果
+	// refresh 和结果处理都在界面线程中执行，不需要加锁
+	generation := 0
+
+	refresh := func(saveFilePath string) {
+		generation++
+		current := generation
+
+		grid.Objects = []fyne.CanvasObject{widget.NewLabel("正在读取存档...")}
+		grid.Refresh()
+
+		go func() {
+			_, slotSet, _, err := wcsave.OpenSlotFile(saveFilePath)
+			var summaries []wcsave.SlotSummary
+			if err == nil {
+				indexes := make([]int, 0, wcsave.SlotCount-1)
+				for i := 1; i < wcsave.SlotCount; i++ {
+					indexes = append(indexes, i)
+				}
+				summaries = slotSet.Summaries(indexes)
+			}
+
+			fyne.Do(func() {
+				if current != generation {
+					return
+				}
+
+				if err != nil {
+					log.Printf("读取存档概况失败: %v", err)
+					grid.Objects = []fyne.CanvasObject{widget.NewLabel(err.Error())}
+				} else {
+					grid.Objects = make([]fyne.CanvasObject, 0, len(summaries))
+					for _, summary := range summaries {
+						grid.Objects = append(grid.Objects, newSlotCard(summary, onOpen))
+					}
+				}
+				grid.Refresh()
+			})
+		}()
+	}
+
+	return grid, refresh
+}
+
+// newSlotCard 创建单个存档的概况卡片，整张卡片可点击
+func newSlotCard(summary wcsave.SlotSummary, onOpen func(progressIndex int)) fyne.CanvasObject {
+	progressIndex := summary.Index - 1
+	title := fmt.Sprintf("进度%d", summary.Index)
+	if progressIndex < len(progressNames) {
+		title = progressNames[progressIndex]
+	}
+
+	lines := make([]fyne.CanvasObject, 0)
+	if summary.Err != nil {
+		lines = append(lines, wrappedLabel("无法读取: "+summary.Err.Error()))
+	} else {
+		for _, char := range summary.Characters {
+			lines = append(lines,
+				widget.NewLabelWithStyle(fmt.Sprintf("%s Lv%d", char.Name, char.Data.Level), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				widget.NewLabel(fmt.Sprintf("生命 %d/%d", char.Data.CurrentHP, char.Data.MaxHP)),
+				widget.NewLabel(fmt.Sprintf("内力 %d/%d", char.Data.CurrentMP, char.Data.MaxMP)),
+			)
+		}
+		lines = append(lines,
+			widget.NewLabel(fmt.Sprintf("银两: %d", summary.Money)),
+			widget.NewLabel(fmt.Sprintf("地图 %d (%d, %d)", summary.Position.MapID, summary.Position.X, summary.Position.Y)),
+		)
+	}
+
+	subtitle := ""
+	if !summary.ModTime.IsZero() {
+		subtitle = summary.ModTime.Format("2006-01-02 15:04")
+	}
+	card := widget.NewCard(title, subtitle, container.NewVBox(lines...))
+
+	// 卡片中的文字不处理点击，点击会落到下层的按钮上
+	button := widget.NewButton("", func() {
+		onOpen(progressIndex)
+	})
+	if summary.Err != nil {
+		button.Disable()
+	}
+	return container.NewStack(button, card)
+}
+
+// wrappedLabel 创建自动换行的标签
+func wrappedLabel(text string) *widget.Label {
+	label := widget.NewLabel(text)
+	label.Wrapping = fyne.TextWrapWord
+	return label
+}
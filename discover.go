@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"wcediter/wcsave/discover"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showDiscoverDialog 选择搜索目录后在后台查找游戏存档目录，
+// 用户勾选的结果添加为选择记录后调用 onAdded
+func showDiscoverDialog(parent fyne.Window, onAdded func()) {
+	rootsEntry := widget.NewMultiLineEntry()
+	rootsEntry.SetPlaceHolder("每行一个目录，留空则搜索主目录和已挂载的磁盘")
+	rootsEntry.SetMinRowsVisible(4)
+
+	browseButton := widget.NewButton("添加目录...", func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			text := strings.TrimSpace(rootsEntry.Text)
+			if text != "" {
+				text += "\n"
+			}
+			rootsEntry.SetText(text + uri.Path())
+		}, parent)
+	})
+
+	content := container.NewBorder(nil, container.NewHBox(browseButton), nil, nil, rootsEntry)
+	setupDialog := dialog.NewCustomConfirm("自动查找存档", "开始搜索", "取消", content, func(start bool) {
+		if !start {
+			return
+		}
+		var roots []string
+		for _, line := range strings.Split(rootsEntry.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				roots = append(roots, line)
+			}
+		}
+		if len(roots) == 0 {
+			roots = discover.DefaultRoots()
+		}
+		runDiscover(parent, roots, onAdded)
+	}, parent)
+	setupDialog.Resize(fyne.NewSize(500, 300))
+	setupDialog.Show()
+}
+
+// runDiscover 在后台搜索，显示进度并可随时停止，结束后显示找到的存档
+func runDiscover(parent fyne.Window, roots []string, onAdded func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	currentLabel := widget.NewLabel("")
+	currentLabel.Truncation = fyne.TextTruncateEllipsis
+	foundLabel := widget.NewLabel("已找到 0 组存档")
+	stopButton := widget.NewButton("停止", cancel)
+	progressDialog := dialog.NewCustomWithoutButtons("正在查找存档",
+		container.NewVBox(widget.NewProgressBarInfinite(), currentLabel, foundLabel, container.NewCenter(stopButton)), parent)
+	progressDialog.Resize(fyne.NewSize(500, 200))
+	progressDialog.Show()
+
+	log.Printf("开始查找存档，搜索目录: %v", roots)
+	go func() {
+		var records []discover.Record
+		lastUpdate := time.Time{}
+		opts := discover.Options{
+			// 目录很多，限制界面刷新频率
+			Visit: func(dir string) {
+				if time.Since(lastUpdate) < 100*time.Millisecond {
+					return
+				}
+				lastUpdate = time.Now()
+				fyne.Do(func() { currentLabel.SetText(dir) })
+			},
+		}
+		err := discover.Scan(ctx, roots, opts, func(result discover.Result) {
+			records = append(records, result.Records()...)
+			count := len(records)
+			fyne.Do(func() { foundLabel.SetText(fmt.Sprintf("已找到 %d 组存档", count)) })
+		})
+		cancel()
+
+		fyne.Do(func() {
+			progressDialog.Hide()
+			if err != nil && !errors.Is(err, context.Canceled) {
+				dialog.ShowError(fmt.Errorf("查找存档失败: %v", err), parent)
+				return
+			}
+			log.Printf("查找存档结束，共找到 %d 组存档", len(records))
+			showDiscoverResults(parent, records, onAdded)
+		})
+	}()
+}
+
+// showDiscoverResults 列出尚未记录的存档，添加用户勾选的项
+func showDiscoverResults(parent fyne.Window, records []discover.Record, onAdded func()) {
+	known := make(map[string]bool)
+	for _, item := range fileRecords {
+		known[item.Path] = true
+	}
+
+	options := make([]string, 0, len(records))
+	byOption := make(map[string]discover.Record)
+	for _, record := range records {
+		if known[record.Path] {
+			continue
+		}
+		option := fmt.Sprintf("%s（%s）", record.Tag, record.Path)
+		options = append(options, option)
+		byOption[option] = record
+	}
+	if len(options) == 0 {
+		dialog.ShowInformation("自动查找存档", "没有找到新的存档目录", parent)
+		return
+	}
+
+	checks := widget.NewCheckGroup(options, nil)
+	checks.SetSelected(options)
+	resultDialog := dialog.NewCustomConfirm("找到的存档", "添加选中项", "取消", container.NewVScroll(checks), func(add bool) {
+		if !add {
+			return
+		}
+		for _, option := range checks.Selected {
+			record := byOption[option]
+			fileRecords = append(fileRecords, FileRecordItem{Tag: uniqueRecordTag(record.Tag), Path: record.Path})
+		}
+		saveFileRecords()
+		log.Printf("已添加 %d 条自动查找的选择记录", len(checks.Selected))
+		if onAdded != nil {
+			onAdded()
+		}
+	}, parent)
+	resultDialog.Resize(fyne.NewSize(600, 400))
+	resultDialog.Show()
+}
+
+// uniqueRecordTag 标签与已有记录重复时添加数字后缀
+func uniqueRecordTag(tag string) string {
+	used := make(map[string]bool)
+	for _, item := range fileRecords {
+		used[item.Tag] = true
+	}
+	unique := tag
+	for counter := 1; used[unique]; counter++ {
+		unique = fmt.Sprintf("%s(%d)", tag, counter)
+	}
+	return unique
+}
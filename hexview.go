@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image/color"
+	"log"
+	"strconv"
+	"strings"
+
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 每行显示的字节数
+const hexBytesPerRow = 16
+
+// 各类区域的背景色
+var hexRegionColors = map[layout.Kind]color.Color{
+	layout.KindCharacter:  color.NRGBA{R: 0x42, G: 0x8B, B: 0xCA, A: 0x60},
+	layout.KindGap:        color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0x50},
+	layout.KindTerminator: color.NRGBA{R: 0xE6, G: 0x7E, B: 0x22, A: 0x80},
+	layout.KindParty:      color.NRGBA{R: 0x8E, G: 0x44, B: 0xAD, A: 0x70},
+	layout.KindMoney:      color.NRGBA{R: 0xF1, G: 0xC4, B: 0x0F, A: 0x80},
+	layout.KindPosition:   color.NRGBA{R: 0x27, G: 0xAE, B: 0x60, A: 0x70},
+	layout.KindCustom:     color.NRGBA{R: 0xE7, G: 0x4C, B: 0x3C, A: 0x60},
+}
+
+// 各类区域在图例中的名称
+var hexRegionNames = []struct {
+	kind layout.Kind
+	name string
+}{
+	{layout.KindCharacter, "角色字段"},
+	{layout.KindGap, "未解析"},
+	{layout.KindTerminator, "结束标记"},
+	{layout.KindParty, "队伍人数"},
+	{layout.KindMoney, "银两"},
+	{layout.KindPosition, "位置"},
+	{layout.KindCustom, "自定义"},
+}
+
+// hexView 十六进制查看/编辑面板
+type hexView struct {
+	session  *saveSession    // 所属的存档
+	data     []byte          // 文件内容（已叠加未保存的修改）
+	edited   map[int64]bool  // 未保存修改涉及的字节
+	regions  []layout.Region // 已标注的区域
+	selected int64           // 当前选中的字节位置，-1表示未选中
+
+	list          *widget.List
+	overlay       *fyne.Container // 提示框所在的图层
+	tooltip       *fyne.Container
+	tooltipText   *widget.Label
+	selectedLabel *widget.Label
+	valueEntry    *widget.Entry
+}
+
+// hexByteCell 单个字节的显示单元，支持点击选中和悬停提示
+type hexByteCell struct {
+	widget.BaseWidget
+	view       *hexView
+	offset     int64
+	background *canvas.Rectangle
+	text       *canvas.Text
+}
+
+func newHexByteCell(view *hexView) *hexByteCell {
+	cell := &hexByteCell{
+		view:       view,
+		offset:     -1,
+		background: canvas.NewRectangle(color.Transparent),
+		text:       canvas.NewText("00", theme.Color(theme.ColorNameForeground)),
+	}
+	cell.text.TextStyle = fyne.TextStyle{Monospace: true}
+	cell.text.Alignment = fyne.TextAlignCenter
+	cell.ExtendBaseWidget(cell)
+	return cell
+}
+
+// CreateRenderer 实现 fyne.Widget 接口
+func (c *hexByteCell) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(c.background, container.NewPadded(c.text)))
+}
+
+// Tapped 点击时选中该字节
+func (c *hexByteCell) Tapped(*fyne.PointEvent) {
+	if c.offset >= 0 {
+		c.view.selectOffset(c.offset)
+	}
+}
+
+// MouseIn 鼠标进入时显示提示
+func (c *hexByteCell) MouseIn(e *desktop.MouseEvent) {
+	if c.offset >= 0 {
+		c.view.showTooltip(c.offset, e.AbsolutePosition)
+	}
+}
+
+// MouseMoved 实现 desktop.Hoverable 接口
+func (c *hexByteCell) MouseMoved(*desktop.MouseEvent) {}
+
+// MouseOut 鼠标离开时隐藏提示
+func (c *hexByteCell) MouseOut() {
+	c.view.hideTooltip()
+}
+
+// 更新字节单元的显示内容
+func (c *hexByteCell) update(offset int64) {
+	c.offset = offset
+	if offset < 0 || offset >= int64(len(c.view.data)) {
+		c.text.Text = "  "
+		c.background.FillColor = color.Transparent
+	} else {
+		c.text.Text = fmt.Sprintf("%02X", c.view.data[offset])
+		c.background.FillColor = color.Transparent
+		if region, ok := layout.Find(c.view.regions, offset); ok {
+			c.background.FillColor = hexRegionColors[region.Kind]
+		}
+		if offset == c.view.selected {
+			c.background.FillColor = theme.Color(theme.ColorNameSelection)
+		}
+	}
+
+	c.text.Color = theme.Color(theme.ColorNameForeground)
+	if c.view.edited[offset] {
+		c.text.Color = theme.Color(theme.ColorNameError)
+	}
+	c.background.Refresh()
+	c.text.Refresh()
+}
+
+// 创建存档的十六进制查看/编辑面板
+func (s *saveSession) createHexView() fyne.CanvasObject {
+	view := &hexView{session: s, selected: -1}
+	if err := view.reload(); err != nil {
+		log.Printf("加载十六进制数据失败: %v", err)
+		return widget.NewLabel(fmt.Sprintf("无法读取存档数据: %v", err))
+	}
+
+	view.list = widget.NewList(
+		func() int {
+			return (len(view.data) + hexBytesPerRow - 1) / hexBytesPerRow
+		},
+		func() fyne.CanvasObject {
+			offsetText := canvas.NewText("00000000", theme.Color(theme.ColorNamePlaceHolder))
+			offsetText.TextStyle = fyne.TextStyle{Monospace: true}
+			row := container.NewHBox(offsetText)
+			for i := 0; i < hexBytesPerRow; i++ {
+				row.Add(newHexByteCell(view))
+			}
+			return row
+		},
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			row := o.(*fyne.Container)
+			base := int64(id) * hexBytesPerRow
+			offsetText := row.Objects[0].(*canvas.Text)
+			offsetText.Text = fmt.Sprintf("%08X", base)
+			offsetText.Refresh()
+			for i := 0; i < hexBytesPerRow; i++ {
+				row.Objects[i+1].(*hexByteCell).update(base + int64(i))
+			}
+		},
+	)
+
+	// 悬停提示框，位于列表之上的独立图层，不拦截鼠标事件
+	view.tooltipText = widget.NewLabel("")
+	view.tooltip = container.NewStack(
+		canvas.NewRectangle(theme.Color(theme.ColorNameOverlayBackground)),
+		view.tooltipText,
+	)
+	view.tooltip.Hide()
+	view.overlay = container.NewWithoutLayout(view.tooltip)
+
+	// 跳转控件
+	gotoEntry := widget.NewEntry()
+	gotoEntry.SetPlaceHolder("位置（十进制或0x十六进制）")
+	gotoButton := widget.NewButton("跳转", func() {
+		offset, err := parseHexOffset(gotoEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, s.window)
+			return
+		}
+		view.selectOffset(offset)
+	})
+	quickJumps := container.NewHBox(
+		widget.NewButton("角色记录", func() { view.selectOffset(models.CharacterStartPosition) }),
+		widget.NewButton("银两", func() { view.selectOffset(s.editor.MoneyInfo.Position) }),
+		widget.NewButton("位置", func() { view.selectOffset(s.editor.PositionInfo.Position) }),
+	)
+
+	// 修改控件
+	view.selectedLabel = widget.NewLabel("未选中字节")
+	view.selectedLabel.Wrapping = fyne.TextWrapWord
+	view.valueEntry = widget.NewEntry()
+	view.valueEntry.SetPlaceHolder("新的字节值，如 FF 或 E8 03 00 00")
+	applyButton := widget.NewButton("修改", func() {
+		view.applyEdit(view.valueEntry.Text)
+	})
+
+	// 图例
+	legend := container.NewHBox()
+	for _, item := range hexRegionNames {
+		swatch := canvas.NewRectangle(hexRegionColors[item.kind])
+		swatch.SetMinSize(fyne.NewSize(14, 14))
+		legend.Add(container.NewCenter(swatch))
+		legend.Add(widget.NewLabel(item.name))
+	}
+
+	controls := container.NewVBox(
+		container.NewBorder(nil, nil, nil, container.NewHBox(gotoButton, quickJumps), gotoEntry),
+		view.selectedLabel,
+		container.NewBorder(nil, nil, nil, applyButton, view.valueEntry),
+		widget.NewLabel("修改在点击“保存修改”后与其他修改一同写入存档"),
+		legend,
+	)
+
+	return container.NewBorder(nil, controls, nil, nil, container.NewStack(view.list, view.overlay))
+}
+
+// 从当前存档重新读取数据并叠加未保存的修改
+func (v *hexView) reload() error {
+	if v.session.editor == nil {
+		return fmt.Errorf("没有加载的存档文件")
+	}
+	// 与区域标注一样取自编辑器内存中的状态，而非磁盘上的文件
+	data, err := v.session.editor.CurrentBytes(0, models.SaveFileSize)
+	if err != nil {
+		return err
+	}
+	v.data = data
+	v.edited = make(map[int64]bool)
+	for _, edit := range v.session.editor.RawEdits {
+		for i := range edit.Data {
+			v.edited[edit.Position+int64(i)] = true
+		}
+	}
+	v.regions = layout.Regions(v.session.editor.Characters, v.session.editor.MoneyInfo, v.session.editor.PositionInfo)
+	return nil
+}
+
+// 描述指定位置所属的字段及其解码值
+func (v *hexView) describe(offset int64) string {
+	text := fmt.Sprintf("位置 %d (0x%X)", offset, offset)
+	if region, ok := layout.Find(v.regions, offset); ok {
+		text += fmt.Sprintf("\n%s · %s\n值: %s", region.Group, region.Field.Label, layout.Decode(region.Field, v.data))
+	} else {
+		text += "\n未知区域"
+	}
+	return text
+}
+
+// 选中指定位置的字节并滚动到该行
+func (v *hexView) selectOffset(offset int64) {
+	if offset < 0 || offset >= int64(len(v.data)) {
+		dialog.ShowError(fmt.Errorf("位置超出文件范围: %d", offset), v.session.window)
+		return
+	}
+	v.selected = offset
+	v.selectedLabel.SetText("选中" + v.describe(offset))
+	v.valueEntry.SetText(fmt.Sprintf("%02X", v.data[offset]))
+	v.list.ScrollTo(widget.ListItemID(offset / hexBytesPerRow))
+	v.list.Refresh()
+}
+
+// 显示悬停提示
+func (v *hexView) showTooltip(offset int64, absolutePosition fyne.Position) {
+	v.tooltipText.SetText(v.describe(offset))
+	size := v.tooltipText.MinSize()
+	v.tooltip.Resize(size)
+
+	// 将鼠标的绝对坐标换算为图层内坐标，并向右下偏移避免遮挡
+	overlayPosition := fyne.CurrentApp().Driver().AbsolutePositionForObject(v.overlay)
+	position := absolutePosition.Subtract(overlayPosition).Add(fyne.NewPos(12, 16))
+	if limit := v.overlay.Size(); position.X+size.Width > limit.Width {
+		position.X = limit.Width - size.Width
+	}
+	v.tooltip.Move(position)
+	v.tooltip.Show()
+}
+
+// 隐藏悬停提示
+func (v *hexView) hideTooltip() {
+	v.tooltip.Hide()
+}
+
+// 将输入的十六进制字节写入选中位置
+func (v *hexView) applyEdit(valueText string) {
+	if v.selected < 0 {
+		dialog.ShowInformation("提示", "请先选择要修改的字节", v.session.window)
+		return
+	}
+	data, err := hex.DecodeString(strings.Join(strings.Fields(valueText), ""))
+	if err != nil || len(data) == 0 {
+		dialog.ShowError(fmt.Errorf("字节格式错误: %s", valueText), v.session.window)
+		return
+	}
+	if err := v.session.editor.UpdateRawBytes(v.selected, data); err != nil {
+		dialog.ShowError(err, v.session.window)
+		return
+	}
+
+	copy(v.data[v.selected:], data)
+	for i := range data {
+		v.edited[v.selected+int64(i)] = true
+	}
+	log.Printf("记录原始字节修改: 位置%d, % X", v.selected, data)
+	v.selectedLabel.SetText("选中" + v.describe(v.selected))
+	v.list.Refresh()
+}
+
+// 解析十进制或0x开头的十六进制位置
+func parseHexOffset(text string) (int64, error) {
+	text = strings.TrimSpace(text)
+	var offset int64
+	var err error
+	if strings.HasPrefix(strings.ToLower(text), "0x") {
+		offset, err = strconv.ParseInt(text[2:], 16, 64)
+	} else {
+		offset, err = strconv.ParseInt(text, 10, 64)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("位置格式错误: %s", text)
+	}
+	return offset, nil
+}
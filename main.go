@@ -1,15 +1,24 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
 	"wcediter/wcsave"
+	savelayout "wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+	"wcediter/wcsave/preset"
+	"wcediter/wcsave/reader"
+	"wcediter/wcsave/script"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -23,13 +32,11 @@ import (
 
 var (
 	// 全局状态
-	fyneApp         fyne.App
-	editor          *wcsave.SaveEditor
-	currentSave     string
-	currentWindow   fyne.Window
-	characterWindow fyne.Window // 角色属性编辑窗口
-	// 保存每个角色的属性输入框
-	characterPropertyInputs map[int][]*propertyInput
+	fyneApp       fyne.App
+	currentSave   string // 进度选择界面中选中的存档文件
+	currentWindow fyne.Window
+	// 已打开的存档，每个存档有独立的编辑器和角色属性窗口
+	openSessions []*saveSession
 
 	// 存档进度相关
 	progressNames = []string{"进度一", "进度二", "进度三", "进度四", "进度五"}
@@ -38,6 +45,7 @@ var (
 
 	// 配置文件相关
 	configFile  = "./wcediter.ini"
+	presetDir   = "./presets" // 预设目录，其中的 .ini 文件与配置文件中的 [preset.*] 节一同加载
 	fileRecords []FileRecordItem
 	// 固定的默认记录
 	defaultRecords = map[string]string{
@@ -252,6 +260,13 @@ func selectSaveFile(parentWindow fyne.Window, onSelect fileSelectCallback) {
 		fileDialog.Show()
 	})
 
+	// 自动查找磁盘上的存档目录，找到的存档添加到记录列表
+	discoverButton := widget.NewButton("自动查找", func() {
+		showDiscoverDialog(fileWindow, func() {
+			recordList.Refresh()
+		})
+	})
+
 	// 创建记录列表
 	recordList = widget.NewList(
 		func() int {
@@ -390,6 +405,7 @@ func selectSaveFile(parentWindow fyne.Window, onSelect fileSelectCallback) {
 		container.NewHBox(
 			layout.NewSpacer(),
 			filePicker,
+			discoverButton,
 			confirmButton,
 			widget.NewButton("取消", func() {
 				// 保存选择记录
@@ -432,16 +448,13 @@ func selectSaveFile(parentWindow fyne.Window, onSelect fileSelectCallback) {
 
 // 读取进度信息并更新进度名称列表
 func updateProgressNames(saveFilePath string, radioGroup *widget.RadioGroup) {
-	// 获取存档文件所在目录
-	saveDir := filepath.Dir(saveFilePath)
-	// 构建 WC.cfg 文件路径
-	cfgPath := filepath.Join(saveDir, "WC.cfg")
-
-	log.Printf("尝试读取进度信息，配置文件路径: %s", cfgPath)
-
-	// 创建编辑器实例用于读取进度
-	editor := wcsave.NewSaveEditor()
-	progressInfos, err := editor.ReadProgress(cfgPath)
+	// 打开存档文件所在的游戏目录，其中的 WC.cfg 记录了各进度的信息
+	var progressInfos []models.ProgressInfo
+	gameDir, err := wcsave.OpenGameDir(filepath.Dir(saveFilePath))
+	if err == nil {
+		log.Printf("尝试读取进度信息，配置文件路径: %s", gameDir.ConfigPath())
+		progressInfos, err = gameDir.Progress, gameDir.ConfigErr
+	}
 
 	if err != nil {
 		log.Printf("读取进度信息失败: %v，使用默认进度名称", err)
@@ -458,22 +471,7 @@ func updateProgressNames(saveFilePath string, radioGroup *widget.RadioGroup) {
 		}
 		progressNames = make([]string, maxProgress)
 		for i := 0; i < maxProgress; i++ {
-			info := progressInfos[i]
-			// 格式：位置名称 + 编号
-			if info.LocationName != "" && info.ProgressID > 0 {
-				progressNames[i] = fmt.Sprintf("%s（%d）", info.LocationName, info.ProgressID)
-			} else if info.LocationName != "" {
-				progressNames[i] = info.LocationName
-			} else if info.ProgressID > 0 {
-				progressNames[i] = fmt.Sprintf("进度%d", info.ProgressID)
-			} else {
-				// 如果都没有，使用默认名称
-				if i < len(defaultProgressNames) {
-					progressNames[i] = defaultProgressNames[i]
-				} else {
-					progressNames[i] = fmt.Sprintf("进度%d", i+1)
-				}
-			}
+			progressNames[i] = formatProgressName(progressInfos[i], i)
 		}
 		// 如果读取的进度少于5个，用默认名称填充
 		if len(progressNames) < 5 {
@@ -506,6 +504,21 @@ func updateProgressNames(saveFilePath string, radioGroup *widget.RadioGroup) {
 	}
 }
 
+// formatProgressName 返回进度的显示名称，格式为位置名称加编号，都没有时使用默认名称
+func formatProgressName(info models.ProgressInfo, index int) string {
+	if info.LocationName != "" && info.ProgressID > 0 {
+		return fmt.Sprintf("%s（%d）", info.LocationName, info.ProgressID)
+	} else if info.LocationName != "" {
+		return info.LocationName
+	} else if info.ProgressID > 0 {
+		return fmt.Sprintf("进度%d", info.ProgressID)
+	}
+	if index >= 0 && index < len(defaultProgressNames) {
+		return defaultProgressNames[index]
+	}
+	return fmt.Sprintf("进度%d", index+1)
+}
+
 // 创建进度选择界面
 func createProgressSelectUI(onSelect progressSelectCallback) *fyne.Container {
 	log.Println("创建进度选择界面，包含文件选择功能")
@@ -548,10 +561,27 @@ func createProgressSelectUI(onSelect progressSelectCallback) *fyne.Container {
 	})
 	radioGroup.SetSelected(progressNames[0]) // 默认选择第一个进度
 
+	// 各进度的概况卡片，点击卡片直接打开对应进度
+	dashboard, refreshDashboard := newSlotDashboard(func(progressIndex int) {
+		filePath := tagPathMap[selectedTag]
+		if filePath == "" {
+			dialog.ShowInformation("提示", "请先选择一个存档文件", currentWindow)
+			return
+		}
+		currentSave = filePath
+		onSelect(progressIndex)
+	})
+
+	// 切换存档时同时更新进度名称和概况
+	refreshSelection := func(filePath string) {
+		updateProgressNames(filePath, radioGroup)
+		refreshDashboard(filePath)
+	}
+
 	// 如果初始有选中的文件，尝试读取进度信息
 	if len(tags) > 0 && selectedTag != "" {
 		if filePath, ok := tagPathMap[selectedTag]; ok {
-			updateProgressNames(filePath, radioGroup)
+			refreshSelection(filePath)
 		}
 	}
 
@@ -559,7 +589,7 @@ func createProgressSelectUI(onSelect progressSelectCallback) *fyne.Container {
 		selectedTag = tag
 		// 当文件选择改变时，更新进度名称
 		if filePath, ok := tagPathMap[tag]; ok {
-			updateProgressNames(filePath, radioGroup)
+			refreshSelection(filePath)
 		}
 	})
 	if len(tags) > 0 {
@@ -589,7 +619,7 @@ func createProgressSelectUI(onSelect progressSelectCallback) *fyne.Container {
 				fileSelect.SetSelected(selectedTag)
 				// 更新进度名称
 				if filePath != "" {
-					updateProgressNames(filePath, radioGroup)
+					refreshSelection(filePath)
 				}
 			}
 			fileSelect.Refresh()
@@ -637,6 +667,15 @@ func createProgressSelectUI(onSelect progressSelectCallback) *fyne.Container {
 	// 创建取消按钮
 	cancelButton := widget.NewButton("取消", func() {
 		// 取消时退出窗口
+		if len(openSessions) > 0 {
+			// 进度选择窗口与存档窗口同时显示，退出前确认以免丢失未保存的修改
+			dialog.ShowConfirm("确认退出", fmt.Sprintf("还有%d个存档窗口未关闭，未保存的修改将会丢失，确定要退出吗？", len(openSessions)), func(confirmed bool) {
+				if confirmed {
+					fyneApp.Quit()
+				}
+			}, currentWindow)
+			return
+		}
 		log.Println("用户取消了进度选择，正在退出应用")
 		fyneApp.Quit() // 退出应用程序
 	})
@@ -676,6 +715,7 @@ func createProgressSelectUI(onSelect progressSelectCallback) *fyne.Container {
 		title,
 		layout.NewSpacer(),
 		radioCenterContainer,
+		dashboard,
 		layout.NewSpacer(),
 		buttonBox,
 		authorLabel,
@@ -715,103 +755,124 @@ func loadProgressAndOpenCharacterUI(progressIndex int, progressWindow fyne.Windo
 		return
 	}
 
-	// 根据用户选择的基础文件路径和进度索引生成对应的存档文件路径
-	// 例如: 如果基础文件是 "xxx0.dat"，进度1对应 "xxx1.dat"
-	ext := filepath.Ext(currentSave)
-	baseName := currentSave[:len(currentSave)-len(ext)]
-	// 替换末尾的0为对应的进度索引+1
-	baseName = baseName[:len(baseName)-1] + strconv.Itoa(progressIndex+1)
-	filePath := baseName + ext
+	// 基础文件为 "xxx0.dat"，进度1对应同一组中的 "xxx1.dat"
+	_, slotSet, _, err := wcsave.OpenSlotFile(currentSave)
+	if err != nil {
+		log.Printf("无法识别存档文件: %v", err)
+		dialog.ShowError(err, progressWindow)
+		return
+	}
+	filePath := slotSet.SlotPath(progressIndex + 1)
 
 	log.Printf("准备加载进度 %d 的文件: %s", progressIndex+1, filePath)
 
-	// 加载找到的存档文件
-	err := loadSaveFile(filePath)
+	// 同一存档只打开一个窗口，已打开时切换到该窗口
+	if s := findSession(filePath); s != nil {
+		s.window.RequestFocus()
+		return
+	}
+
+	// 加载找到的存档文件，每个存档使用独立的编辑器
+	s := &saveSession{}
+	err = s.loadSaveFile(filePath)
 	if err != nil {
 		log.Printf("加载存档失败: %v", err)
 		dialog.ShowError(fmt.Errorf("加载存档失败: %v", err), progressWindow)
 	} else {
 		log.Printf("成功加载进度: %s, 文件: %s", progressNames[progressIndex], filePath)
 
-		// 隐藏进度选择窗口
-		progressWindow.Hide()
-
-		// 创建角色属性窗口，传递进度索引
-		openCharacterWindow(progressIndex)
+		// 进度选择窗口保持显示，可以继续打开其他存档并排编辑
+		s.openCharacterWindow()
 	}
 }
 
-// 打开角色属性窗口
-func openCharacterWindow(progressIndex int) {
+// 打开存档的角色属性窗口
+func (s *saveSession) openCharacterWindow() {
 	// 创建新的角色属性窗口
 	log.Println("创建角色属性窗口...")
-	// 在标题后添加进度信息
-	title := fmt.Sprintf("角色属性编辑 - %s", progressNames[progressIndex])
-	characterWindow = fyneApp.NewWindow(title)
+	s.window = fyneApp.NewWindow(s.windowTitle())
 
 	// 设置更大的窗口大小以确保所有角色属性都能完整显示
-	characterWindow.Resize(fyne.NewSize(650, 800))
+	s.window.Resize(fyne.NewSize(650, 800))
 
 	// 设置窗口关闭时的行为
-	characterWindow.SetCloseIntercept(func() {
-		log.Println("角色属性窗口关闭中...")
-		// 重新显示进度选择窗口
-		log.Println("重新显示进度选择窗口...")
-		currentWindow.Show()
-		// 关闭窗口
-		characterWindow.Close()
+	s.window.SetOnClosed(func() {
+		log.Printf("角色属性窗口已关闭: %s", s.path)
+		s.stopSaveWatcher()
+		removeSession(s)
 	})
 
 	// 创建角色属性UI内容
 	log.Println("创建角色属性UI内容...")
-	content := createMainUI()
-	characterWindow.SetContent(content)
+	content := s.createMainUI()
+	s.window.SetContent(content)
 
 	// 显示角色属性窗口
 	log.Println("显示角色属性窗口...")
-	characterWindow.Canvas().Focus(nil)
-	characterWindow.CenterOnScreen() // 居中显示窗口
-	characterWindow.Show()
+	s.window.Canvas().Focus(nil)
+	s.window.CenterOnScreen() // 居中显示窗口
+	s.window.Show()
+	addSession(s)
+
+	// 监视游戏对存档的修改
+	s.startSaveWatcher()
 }
 
 // 加载存档文件
-func loadSaveFile(filePath string) error {
+func (s *saveSession) loadSaveFile(filePath string) error {
 	// 检查文件是否存在
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		log.Printf("错误: 存档文件不存在: %s", filePath)
 		return fmt.Errorf("存档文件不存在: %s\n请确认文件路径是否正确", filePath)
 	}
 
-	// 初始化编辑器
-	editor = wcsave.NewSaveEditor()
+	// 读取配置文件旁的自定义布局，修改后重新打开存档即可生效
+	if layoutPath, err := savelayout.LoadBeside(configFile); err != nil {
+		return fmt.Errorf("读取自定义布局失败: %v", err)
+	} else if layoutPath != "" {
+		log.Printf("已加载自定义布局: %s（%d个角色字段，%d个文件字段）", layoutPath, len(savelayout.Extra.Character), len(savelayout.Extra.File))
+	}
 
-	// 读取存档
-	err := editor.ReadSave(filePath)
+	// 按所在游戏目录打开存档，同时关联 WC.cfg 中对应的进度
+	_, slotSet, index, err := wcsave.OpenSlotFile(filePath)
 	if err != nil {
-		return fmt.Errorf("读取存档失败: %v", err)
+		return err
+	}
+	slot, err := slotSet.Slot(index)
+	if err != nil {
+		return withAdvice("读取存档失败", err)
 	}
 
-	currentSave = filePath
+	s.editor = slot.Editor
+	s.slot = slot
+	s.path = filePath
+	s.copied = false
 	log.Printf("成功加载存档: %s", filePath)
-	log.Printf("发现 %d 个角色", editor.GetCharacterCount())
+	log.Printf("发现 %d 个角色", s.editor.GetCharacterCount())
 
 	return nil
 }
 
-// 创建角色选择下拉框
-func createCharacterTabs(propertyInputs []*propertyInput) *container.AppTabs {
-	// 初始化角色属性输入框映射
-	if characterPropertyInputs == nil {
-		characterPropertyInputs = make(map[int][]*propertyInput)
+// withAdvice 在错误信息后附加针对错误类型的处理建议
+func withAdvice(message string, err error) error {
+	if advice := wcsave.Advice(err); advice != "" {
+		return fmt.Errorf("%s: %w\n%s", message, err, advice)
 	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// 创建角色选择下拉框
+func (s *saveSession) createCharacterTabs(propertyInputs []*propertyInput) *container.AppTabs {
+	// 初始化角色属性输入框映射（队伍成员可能已变化，每次重建）
+	s.propertyInputs = make(map[int][]*propertyInput)
 	// 创建标签页容器，使用底部标签样式以便更好地显示角色信息
 	tabs := container.NewAppTabs()
 	// 设置标签页位置在顶部，这是更常见的标签页布局
 	tabs.SetTabLocation(container.TabLocationTop)
 
-	if editor != nil {
-		for i := 0; i < editor.GetCharacterCount(); i++ {
-			if char, ok := editor.GetCharacterByIndex(i); ok {
+	if s.editor != nil {
+		for i := 0; i < s.editor.GetCharacterCount(); i++ {
+			if char, ok := s.editor.GetCharacterByIndex(i); ok {
 				// 为每个角色创建新的属性输入框副本
 				charPropertyInputs := make([]*propertyInput, len(propertyInputs))
 				for j, input := range propertyInputs {
@@ -821,6 +882,16 @@ func createCharacterTabs(propertyInputs []*propertyInput) *container.AppTabs {
 					charPropertyInputs[j].input.Resize(fyne.NewSize(150, 30))
 				}
 
+				// 自定义布局中的角色字段，非整数字段只显示不编辑
+				for _, field := range savelayout.Extra.Character {
+					input := createPropertyInput(customPropertyPrefix+field.Name, field.Label+":", "")
+					input.input.Wrapping = fyne.TextWrapOff
+					if !field.Type.Integer() {
+						input.input.Disable()
+					}
+					charPropertyInputs = append(charPropertyInputs, input)
+				}
+
 				// 创建角色属性的网格布局
 				inputGrid := container.New(layout.NewGridLayout(2))
 				for _, input := range charPropertyInputs {
@@ -829,13 +900,13 @@ func createCharacterTabs(propertyInputs []*propertyInput) *container.AppTabs {
 				}
 
 				// 更新角色数据到输入框
-				updateCharacterUI(i, charPropertyInputs)
+				s.updateCharacterUI(i, charPropertyInputs)
 
 				// 创建角色标签页，移除保存按钮，简化内容结构
 				tabContent := container.NewPadded(container.NewVBox(inputGrid))
 
 				// 保存角色属性输入框到全局映射
-				characterPropertyInputs[i] = charPropertyInputs
+				s.propertyInputs[i] = charPropertyInputs
 
 				tabs.Append(container.NewTabItem(fmt.Sprintf("%d. %s", i+1, char.Name), tabContent))
 			}
@@ -846,12 +917,12 @@ func createCharacterTabs(propertyInputs []*propertyInput) *container.AppTabs {
 }
 
 // 更新角色数据界面
-func updateCharacterUI(charIndex int, propertyInputs []*propertyInput) {
-	if editor == nil {
+func (s *saveSession) updateCharacterUI(charIndex int, propertyInputs []*propertyInput) {
+	if s.editor == nil {
 		return
 	}
 
-	char, ok := editor.GetCharacterByIndex(charIndex)
+	char, ok := s.editor.GetCharacterByIndex(charIndex)
 	if !ok {
 		return
 	}
@@ -887,17 +958,38 @@ func updateCharacterUI(charIndex int, propertyInputs []*propertyInput) {
 			input.input.SetText(strconv.FormatInt(int64(char.Data.Luck), 10))
 		case "Level":
 			input.input.SetText(strconv.FormatInt(int64(char.Data.Level), 10))
+		default:
+			if field, ok := customCharacterField(input.property); ok {
+				input.input.SetText(s.editor.CustomText(field.At(char.Position)))
+			}
+		}
+	}
+}
+
+// 自定义字段输入框的属性名前缀
+const customPropertyPrefix = "custom."
+
+// customCharacterField 根据输入框的属性名查找自定义角色字段
+func customCharacterField(property string) (savelayout.Field, bool) {
+	name, ok := strings.CutPrefix(property, customPropertyPrefix)
+	if !ok {
+		return savelayout.Field{}, false
+	}
+	for _, field := range savelayout.Extra.Character {
+		if field.Name == name {
+			return field, true
 		}
 	}
+	return savelayout.Field{}, false
 }
 
 // 保存角色数据更改
-func saveCharacterChanges(charIndex int, propertyInputs []*propertyInput) error {
-	if editor == nil {
+func (s *saveSession) saveCharacterChanges(charIndex int, propertyInputs []*propertyInput) error {
+	if s.editor == nil {
 		return fmt.Errorf("编辑器未初始化")
 	}
 
-	char, ok := editor.GetCharacterByIndex(charIndex)
+	char, ok := s.editor.GetCharacterByIndex(charIndex)
 	if !ok {
 		return fmt.Errorf("角色不存在")
 	}
@@ -995,11 +1087,23 @@ func saveCharacterChanges(charIndex int, propertyInputs []*propertyInput) error
 				return fmt.Errorf("等级格式错误: %v", err)
 			}
 			char.Data.Level = int16(val)
+		default:
+			field, ok := customCharacterField(input.property)
+			if !ok || !field.Type.Integer() {
+				continue
+			}
+			val, err := field.Parse(valueStr)
+			if err != nil {
+				return err
+			}
+			if err := s.editor.SetCustomValue(field.At(char.Position), val); err != nil {
+				return err
+			}
 		}
 	}
 
 	// 更新编辑器中的角色数据
-	result := editor.UpdateCharacter(charIndex, char.Data)
+	result := s.editor.UpdateCharacter(charIndex, char.Data)
 	if !result {
 		return fmt.Errorf("更新角色数据失败")
 	}
@@ -1007,14 +1111,186 @@ func saveCharacterChanges(charIndex int, propertyInputs []*propertyInput) error
 	return nil
 }
 
+// 重新构建角色属性窗口内容（队伍成员变化后使用）
+func (s *saveSession) refreshCharacterWindow() {
+	if s.window == nil {
+		return
+	}
+	s.window.SetContent(s.createMainUI())
+}
+
+// 显示添加角色对话框，以选择的现有角色为模板
+func (s *saveSession) showAddCharacterDialog() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("最多3个汉字")
+
+	// 新角色必须以有效的角色记录为模板，默认使用第一个角色
+	templateOptions := []string{}
+	for i := 0; i < s.editor.GetCharacterCount(); i++ {
+		char, _ := s.editor.GetCharacterByIndex(i)
+		templateOptions = append(templateOptions, fmt.Sprintf("%d. %s", i+1, char.Name))
+	}
+	templateSelect := widget.NewSelect(templateOptions, nil)
+	templateSelect.SetSelected(templateOptions[0])
+
+	// 成员编号不沿用模板，须另行指定且不能与队伍中已有角色重复
+	usedIDs := []string{}
+	for _, char := range s.editor.Characters {
+		usedIDs = append(usedIDs, strconv.Itoa(int(char.MemberID)))
+	}
+	memberIDEntry := widget.NewEntry()
+	memberIDEntry.SetPlaceHolder("已使用: " + strings.Join(usedIDs, ", "))
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("角色名", nameEntry),
+		widget.NewFormItem("模板", templateSelect),
+		widget.NewFormItem("成员编号", memberIDEntry),
+	}
+	dialog.ShowForm("添加角色", "添加", "取消", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		memberID, err := strconv.ParseInt(strings.TrimSpace(memberIDEntry.Text), 10, 16)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("成员编号格式错误: %v", err), s.window)
+			return
+		}
+		character := models.CharacterInfo{Name: strings.TrimSpace(nameEntry.Text), MemberID: int16(memberID)}
+		if template, ok := s.editor.GetCharacterByIndex(templateSelect.SelectedIndex()); ok {
+			character.Data = template.Data
+			character.RecordBytes = template.RecordBytes
+		}
+		if err := s.editor.AddCharacter(character); err != nil {
+			dialog.ShowError(err, s.window)
+			return
+		}
+		s.refreshCharacterWindow()
+	}, s.window)
+}
+
+// 显示应用预设对话框，currentIndex 为当前选中的角色标签页
+func (s *saveSession) showApplyPresetDialog(currentIndex int) {
+	presets, err := preset.LoadAll(configFile, presetDir)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取预设失败: %v", err), s.window)
+		return
+	}
+	if len(presets) == 0 {
+		dialog.ShowInformation("提示", fmt.Sprintf("没有可用的预设，请在 %s 中添加 [preset.名称] 节，或在 %s 目录中放置预设文件", configFile, presetDir), s.window)
+		return
+	}
+
+	presetNames := make([]string, len(presets))
+	for i, p := range presets {
+		presetNames[i] = p.Name
+	}
+	presetSelect := widget.NewSelect(presetNames, nil)
+	presetSelect.SetSelected(presetNames[0])
+
+	// 作用对象：预设默认、全部角色或当前角色
+	targetOptions := []string{"预设默认", "全部角色"}
+	targets := []string{"", "all"}
+	if char, ok := s.editor.GetCharacterByIndex(currentIndex); ok {
+		targetOptions = append(targetOptions, fmt.Sprintf("当前角色（%s）", char.Name))
+		targets = append(targets, fmt.Sprintf("index:%d", currentIndex+1))
+	}
+	targetSelect := widget.NewSelect(targetOptions, nil)
+	targetSelect.SetSelected(targetOptions[0])
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("预设", presetSelect),
+		widget.NewFormItem("作用对象", targetSelect),
+	}
+	dialog.ShowForm("应用预设", "应用", "取消", items, func(confirmed bool) {
+		if !confirmed || presetSelect.SelectedIndex() < 0 || targetSelect.SelectedIndex() < 0 {
+			return
+		}
+		p := presets[presetSelect.SelectedIndex()]
+		result, err := p.Apply(s.editor, targets[targetSelect.SelectedIndex()])
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("应用预设失败: %v", err), s.window)
+			return
+		}
+		log.Printf("应用预设%s: 角色%v, 银两%v", p.Name, result.Characters, result.Money)
+		s.refreshCharacterWindow()
+		dialog.ShowInformation("提示", fmt.Sprintf("已应用预设“%s”，点击“保存修改”后写入存档", p.Name), s.window)
+	}, s.window)
+}
+
+// 显示运行脚本对话框
+func (s *saveSession) showRunScriptDialog() {
+	sourceEntry := widget.NewMultiLineEntry()
+	sourceEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	sourceEntry.SetPlaceHolder("characters.forEach(function (c) {\n\tc.Attack = c.Level * 2;\n\tc.CurrentHP = c.MaxHP;\n});\nmoney += 1000;")
+	sourceEntry.SetMinRowsVisible(12)
+
+	outputLabel := widget.NewLabel("可用变量: characters、money、position、progress（只读），log() 输出调试信息")
+	outputLabel.Wrapping = fyne.TextWrapWord
+
+	openButton := widget.NewButton("打开脚本文件", func() {
+		fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, s.window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("读取脚本失败: %v", err), s.window)
+				return
+			}
+			sourceEntry.SetText(string(content))
+		}, s.window)
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".js"}))
+		fileDialog.Show()
+	})
+
+	runButton := widget.NewButton("运行", func() {
+		if strings.TrimSpace(sourceEntry.Text) == "" {
+			return
+		}
+
+		// 进度信息是可选的，读取失败时脚本中的 progress 为空数组
+		if _, err := s.editor.ReadProgress(s.slot.Set.Dir.ConfigPath()); err != nil {
+			log.Printf("读取进度信息失败: %v", err)
+		}
+
+		var output bytes.Buffer
+		result, err := script.Run(s.editor, sourceEntry.Text, script.Options{Output: &output})
+		if err != nil {
+			outputLabel.SetText(output.String() + err.Error())
+			return
+		}
+
+		summary := fmt.Sprintf("已修改角色 [%s]", strings.Join(result.Characters, ", "))
+		if result.Money {
+			summary += fmt.Sprintf("，银两 %d", s.editor.MoneyInfo.Value)
+		}
+		if result.Position {
+			summary += fmt.Sprintf("，位置 地图%d (%d, %d)", s.editor.PositionInfo.MapID, s.editor.PositionInfo.X, s.editor.PositionInfo.Y)
+		}
+		outputLabel.SetText(output.String() + summary + "，点击“保存修改”后写入存档")
+		s.refreshCharacterWindow()
+	})
+	runButton.Importance = widget.HighImportance
+
+	content := container.NewBorder(nil, container.NewVBox(container.NewHBox(openButton, layout.NewSpacer(), runButton), outputLabel), nil, nil, sourceEntry)
+	scriptDialog := dialog.NewCustom("运行脚本", "关闭", content, s.window)
+	scriptDialog.Resize(fyne.NewSize(600, 480))
+	scriptDialog.Show()
+}
+
 // 创建主界面
-func createMainUI() *fyne.Container {
+func (s *saveSession) createMainUI() *fyne.Container {
 	// 初始化默认值
 	moneyValue := "0"
 
 	// 如果editor为nil，创建一个空的编辑器实例
-	if editor == nil {
-		editor = wcsave.NewSaveEditor()
+	if s.editor == nil {
+		s.editor = wcsave.NewSaveEditor()
 	}
 
 	// 创建属性输入框模板
@@ -1036,11 +1312,11 @@ func createMainUI() *fyne.Container {
 	}
 
 	// 创建角色标签页
-	characterTabs := createCharacterTabs(propertyInputs)
+	characterTabs := s.createCharacterTabs(propertyInputs)
 
 	// 获取银两值
-	if editor != nil {
-		moneyValue = strconv.FormatInt(int64(editor.MoneyInfo.Value), 10)
+	if s.editor != nil {
+		moneyValue = strconv.FormatInt(int64(s.editor.MoneyInfo.Value), 10)
 	}
 
 	// 创建银两相关的输入框和按钮
@@ -1049,22 +1325,42 @@ func createMainUI() *fyne.Container {
 	moneyInput.SetText(moneyValue)
 	moneyInput.SetPlaceHolder("请输入银两数量")
 
-	// 创建保存修改按钮
-	saveFileButton := widget.NewButton("保存修改", func() {
-		if currentSave == "" || editor == nil {
-			dialog.ShowError(fmt.Errorf("没有加载的存档文件"), characterWindow)
-			return
+	// 创建位置相关的输入框：地图编号是游戏内部的编号，没有对应的名称，直接输入数值
+	mapIDInput := widget.NewEntry()
+	mapIDInput.SetText(strconv.FormatInt(int64(s.editor.PositionInfo.MapID), 10))
+	positionXInput := widget.NewEntry()
+	positionXInput.SetText(strconv.FormatInt(int64(s.editor.PositionInfo.X), 10))
+	positionYInput := widget.NewEntry()
+	positionYInput.SetText(strconv.FormatInt(int64(s.editor.PositionInfo.Y), 10))
+
+	// 进度位置名称（WC.cfg 中的位置编号，即位置名称表的下标），0号存档没有进度
+	var progressLocationSelect *widget.Select
+	var locationIDs []int
+	if s.slot != nil && s.slot.HasProgress {
+		var locationOptions []string
+		locationOptions, locationIDs = buildLocationOptions()
+		progressLocationSelect = widget.NewSelect(locationOptions, nil)
+		if i := slices.Index(locationIDs, s.slot.Progress.LocationID); i >= 0 {
+			progressLocationSelect.SetSelectedIndex(i)
 		}
+	}
 
+	// 将界面上的输入应用到编辑器
+	applyInputs := func() bool {
 		// 修改银两
-		if !updateMoneyValue(moneyInput.Text) {
-			return
+		if !s.updateMoneyValue(moneyInput.Text) {
+			return false
+		}
+
+		// 修改位置
+		if !s.updatePositionValue(mapIDInput.Text, positionXInput.Text, positionYInput.Text) {
+			return false
 		}
 
 		// 保存所有角色的数据
 		savedCount := 0
-		for charIndex, inputs := range characterPropertyInputs {
-			err := saveCharacterChanges(charIndex, inputs)
+		for charIndex, inputs := range s.propertyInputs {
+			err := s.saveCharacterChanges(charIndex, inputs)
 			if err != nil {
 				log.Printf("保存角色%d数据失败: %v", charIndex+1, err)
 				continue
@@ -1072,30 +1368,167 @@ func createMainUI() *fyne.Container {
 			savedCount++
 		}
 		log.Printf("成功保存%d个角色的数据", savedCount)
+		return true
+	}
+	s.applyPendingInputs = applyInputs
+
+	// 创建添加角色按钮
+	addCharacterButton := widget.NewButton("添加角色", func() {
+		if s.editor.GetCharacterCount() >= models.MaxCharacters {
+			dialog.ShowInformation("提示", fmt.Sprintf("队伍已满，最多%d个角色", models.MaxCharacters), s.window)
+			return
+		}
+		if !applyInputs() {
+			return
+		}
+		s.showAddCharacterDialog()
+	})
+
+	// 创建移除角色按钮
+	removeCharacterButton := widget.NewButton("移除角色", func() {
+		index := characterTabs.SelectedIndex()
+		char, ok := s.editor.GetCharacterByIndex(index)
+		if !ok {
+			return
+		}
+		dialog.ShowConfirm("确认移除", fmt.Sprintf("确定要将 '%s' 移出队伍吗？", char.Name), func(confirmed bool) {
+			if !confirmed || !applyInputs() {
+				return
+			}
+			if err := s.editor.RemoveCharacter(index); err != nil {
+				dialog.ShowError(err, s.window)
+				return
+			}
+			s.refreshCharacterWindow()
+		}, s.window)
+	})
+
+	// 创建应用预设按钮
+	presetButton := widget.NewButton("应用预设", func() {
+		if !applyInputs() {
+			return
+		}
+		s.showApplyPresetDialog(characterTabs.SelectedIndex())
+	})
+
+	// 创建运行脚本按钮
+	scriptButton := widget.NewButton("运行脚本", func() {
+		if !applyInputs() {
+			return
+		}
+		s.showRunScriptDialog()
+	})
+
+	// 创建复制到其他存档按钮
+	copyButton := widget.NewButton("复制到其他存档", func() {
+		if !applyInputs() {
+			return
+		}
+		s.showCopyCharacterDialog(characterTabs.SelectedIndex())
+	})
+
+	// 创建保存修改按钮
+	saveFileButton := widget.NewButton("保存修改", func() {
+		if s.path == "" || s.editor == nil {
+			dialog.ShowError(fmt.Errorf("没有加载的存档文件"), s.window)
+			return
+		}
+
+		if !applyInputs() {
+			return
+		}
+
+		// 从其他存档复制过数据时，先备份原文件
+		backupPath := ""
+		if s.copied {
+			var err error
+			if backupPath, err = wcsave.BackupFile(s.path); err != nil {
+				dialog.ShowError(fmt.Errorf("备份存档失败: %v", err), s.window)
+				return
+			}
+			log.Printf("已备份存档: %s", backupPath)
+		}
 
-		// 保存更改（直接修改源文件）
-		var err error
-		err = editor.SaveChanges(currentSave, currentSave)
+		// 保存更改（直接修改源文件），保存成功后编辑器会清空原始字节修改
+		rawEdited := len(s.editor.RawEdits) > 0
+		err := s.editor.SaveChanges(s.path, s.path)
+		if errors.Is(err, wcsave.ErrFileChanged) {
+			// 存档已被游戏修改，直接保存会覆盖更新的存档
+			s.showExternalChangeDialog()
+			return
+		}
 		if err != nil {
-			dialog.ShowError(fmt.Errorf("保存文件失败: %v", err), characterWindow)
+			dialog.ShowError(withAdvice("保存文件失败", err), s.window)
 			return
 		}
 
-		dialog.ShowInformation("成功", "保存修改成功！", characterWindow)
+		// 进度位置名称被修改时写入 WC.cfg（修改前备份），失败时存档仍已保存
+		var progressErr error
+		if progressLocationSelect != nil && progressLocationSelect.SelectedIndex() >= 0 {
+			locationID := locationIDs[progressLocationSelect.SelectedIndex()]
+			if locationID != s.slot.Progress.LocationID {
+				cfgBackupPath, err := s.slot.UpdateProgressLocation(locationID)
+				switch {
+				case errors.Is(err, wcsave.ErrFileChanged):
+					progressErr = fmt.Errorf("存档已保存，但 WC.cfg 已被游戏修改，未修改进度位置名称，请重新打开存档后再试")
+				case err != nil:
+					progressErr = fmt.Errorf("存档已保存，但修改进度位置名称失败: %v", err)
+				default:
+					log.Printf("已修改进度位置名称，WC.cfg 已备份为 %s", cfgBackupPath)
+					s.window.SetTitle(s.windowTitle())
+				}
+			}
+		}
+
+		// 原始字节修改可能改变了已解析的字段，重新加载存档以同步界面
+		if rawEdited {
+			if err := s.loadSaveFile(s.path); err != nil {
+				dialog.ShowError(err, s.window)
+				return
+			}
+			s.refreshCharacterWindow()
+		}
+		s.copied = false
+
+		if progressErr != nil {
+			dialog.ShowError(progressErr, s.window)
+			return
+		}
+		message := "保存修改成功！"
+		if backupPath != "" {
+			message += fmt.Sprintf("\n原存档已备份为 %s", filepath.Base(backupPath))
+		}
+		dialog.ShowInformation("成功", message, s.window)
 	})
 
 	// 创建取消按钮
 	cancelButton := widget.NewButton("取消", func() {
 		log.Println("用户点击了取消按钮")
-		// 直接显示主窗口
-		currentWindow.Show()
 		// 关闭角色属性窗口，不保存任何修改
-		characterWindow.Close()
+		s.window.Close()
 	})
 
 	// 创建银两容器
 	moneyContainer := container.NewGridWithColumns(2, moneyLabel, moneyInput)
 
+	// 创建位置容器
+	positionContainer := container.NewGridWithColumns(2,
+		widget.NewLabel("地图编号:"), mapIDInput,
+		widget.NewLabel("横坐标:"), positionXInput,
+		widget.NewLabel("纵坐标:"), positionYInput,
+	)
+	if len(s.editor.PositionInfo.RawBytes) == 0 {
+		mapIDInput.Disable()
+		positionXInput.Disable()
+		positionYInput.Disable()
+	}
+
+	// WC.cfg 中该存档对应进度的位置名称，与上面的地图编号是两套编号，分别修改
+	if progressLocationSelect != nil {
+		positionContainer.Add(widget.NewLabel("进度位置名称:"))
+		positionContainer.Add(progressLocationSelect)
+	}
+
 	// 创建按钮容器，包含保存和取消按钮
 	buttonContainer := container.NewHBox(
 		layout.NewSpacer(),
@@ -1105,18 +1538,25 @@ func createMainUI() *fyne.Container {
 	)
 
 	// 创建主容器
-	mainContainer := container.NewVBox(
+	propertyContainer := container.NewVBox(
 		// 隐藏标题和状态信息
 		widget.NewSeparator(),
 		widget.NewLabel("角色属性管理:"),
 		// 使用角色标签页替代选择器和属性网格
 		characterTabs,
+		container.NewHBox(layout.NewSpacer(), copyButton, presetButton, scriptButton, addCharacterButton, removeCharacterButton),
 		widget.NewSeparator(),
 		moneyContainer,
-		layout.NewSpacer(),
-		// 添加按钮容器，包含保存和取消按钮
-		buttonContainer,
+		widget.NewSeparator(),
+		positionContainer,
+	)
+
+	// 属性编辑与十六进制视图分为两个标签页，共用底部的保存和取消按钮
+	editorTabs := container.NewAppTabs(
+		container.NewTabItem("属性编辑", propertyContainer),
+		container.NewTabItem("十六进制", s.createHexView()),
 	)
+	mainContainer := container.NewBorder(nil, buttonContainer, nil, nil, editorTabs)
 
 	// 标签页已在createCharacterTabs中初始化了所有角色的数据
 
@@ -1174,8 +1614,8 @@ func main() {
 	// 设置窗口内容
 	window.SetContent(content)
 
-	// 设置窗口大小为适合进度选择界面的尺寸
-	window.Resize(fyne.NewSize(350, 250))
+	// 设置窗口大小为适合进度选择界面的尺寸（包含五个进度的概况卡片）
+	window.Resize(fyne.NewSize(960, 640))
 
 	// 显示窗口
 	log.Println("正在显示进度选择窗口...")
@@ -1288,17 +1728,61 @@ func initConfigFile() {
 }
 
 // 更新银两值
-func updateMoneyValue(valueStr string) bool {
-	if editor == nil {
-		dialog.ShowError(fmt.Errorf("没有加载的存档文件"), characterWindow)
+func (s *saveSession) updateMoneyValue(valueStr string) bool {
+	if s.editor == nil {
+		dialog.ShowError(fmt.Errorf("没有加载的存档文件"), s.window)
 		return false
 	}
 	val, err := strconv.ParseInt(valueStr, 10, 32)
 	if err != nil {
-		dialog.ShowError(fmt.Errorf("银两格式错误: %v", err), characterWindow)
+		dialog.ShowError(fmt.Errorf("银两格式错误: %v", err), s.window)
 		return false
 	}
-	editor.UpdateMoney(int32(val))
+	s.editor.UpdateMoney(int32(val))
+	return true
+}
+
+// 构建进度位置名称选择框的选项（WC.cfg 中的位置编号），跳过名称为空的位置编号
+func buildLocationOptions() ([]string, []int) {
+	options := []string{}
+	ids := []int{}
+	for id, name := range reader.GetLocationNames() {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		options = append(options, fmt.Sprintf("%d. %s", id, name))
+		ids = append(ids, id)
+	}
+	return options, ids
+}
+
+// 更新队伍所在地图编号及坐标
+func (s *saveSession) updatePositionValue(mapIDStr, xStr, yStr string) bool {
+	if s.editor == nil {
+		dialog.ShowError(fmt.Errorf("没有加载的存档文件"), s.window)
+		return false
+	}
+	if len(s.editor.PositionInfo.RawBytes) == 0 {
+		// 未读取到位置数据，不做修改
+		return true
+	}
+	mapID, err := strconv.ParseInt(mapIDStr, 10, 32)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("地图编号格式错误: %v", err), s.window)
+		return false
+	}
+	x, err := strconv.ParseInt(xStr, 10, 32)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("横坐标格式错误: %v", err), s.window)
+		return false
+	}
+	y, err := strconv.ParseInt(yStr, 10, 32)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("纵坐标格式错误: %v", err), s.window)
+		return false
+	}
+	s.editor.UpdatePosition(int32(mapID), int32(x), int32(y))
 	return true
 }
 
@@ -1344,8 +1828,13 @@ func loadFileRecords() {
 
 // saveFileRecords 保存选择记录
 func saveFileRecords() {
-	// 创建新的配置文件
-	cfg := ini.Empty()
+	// 读取现有配置文件以保留其他节（如预设），读取失败时创建新的配置文件
+	cfg, err := ini.Load(configFile)
+	if err != nil {
+		cfg = ini.Empty()
+	}
+	cfg.DeleteSection("default_Records")
+	cfg.DeleteSection("Records")
 
 	// 添加默认记录节
 	defaultSection, err := cfg.NewSection("default_Records")
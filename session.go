@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/watcher"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// saveSession 一个已打开的存档及其角色属性窗口
+// 每个存档有独立的编辑器，可以同时打开多个存档并排编辑
+type saveSession struct {
+	editor *wcsave.SaveEditor
+	path   string       // 存档文件路径
+	slot   *wcsave.Slot // 存档及其在 WC.cfg 中的进度
+	window fyne.Window  // 角色属性编辑窗口
+	// 将窗口中尚未应用的输入写入编辑器，由 createMainUI 设置
+	applyPendingInputs func() bool
+	// 保存每个角色的属性输入框
+	propertyInputs map[int][]*propertyInput
+	// 从其他存档复制过数据，保存前先备份原文件
+	copied bool
+
+	// 存档目录的监视器
+	saveWatcher *watcher.Watcher
+	// 外部修改提示框，避免重复弹出
+	externalChangeDialog dialog.Dialog
+}
+
+// addSession 记录新打开的存档
+func addSession(s *saveSession) {
+	openSessions = append(openSessions, s)
+}
+
+// removeSession 移除已关闭的存档
+func removeSession(s *saveSession) {
+	if i := slices.Index(openSessions, s); i >= 0 {
+		openSessions = slices.Delete(openSessions, i, i+1)
+	}
+}
+
+// findSession 查找已打开的存档，未打开时返回 nil
+func findSession(filePath string) *saveSession {
+	for _, s := range openSessions {
+		if sameFile(s.path, filePath) {
+			return s
+		}
+	}
+	return nil
+}
+
+// sameFile 判断两个路径是否指向同一文件或目录
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// displayName 返回存档的显示名称：进度名称和文件名，用于区分同时打开的多个存档
+func (s *saveSession) displayName() string {
+	name := fmt.Sprintf("存档%d", s.slot.Index)
+	if index := s.slot.ProgressIndex(); index >= 0 {
+		name = formatProgressName(s.slot.Progress, index)
+	}
+	return fmt.Sprintf("%s - %s", name, filepath.Base(s.path))
+}
+
+// windowTitle 返回角色属性窗口的标题
+func (s *saveSession) windowTitle() string {
+	return "角色属性编辑 - " + s.displayName()
+}
+
+// reloadProgress 重新读取 WC.cfg 中该存档对应的进度
+func (s *saveSession) reloadProgress() {
+	gameDir, err := wcsave.OpenGameDir(filepath.Dir(s.path))
+	if err != nil {
+		log.Printf("读取进度信息失败: %v", err)
+		return
+	}
+	dir := s.slot.Set.Dir
+	dir.Progress, dir.ConfigErr = gameDir.Progress, gameDir.ConfigErr
+	s.slot.Progress, s.slot.HasProgress = dir.ProgressFor(s.slot.Index)
+}
+
+// showCopyCharacterDialog 将第 index 个角色的全部或部分属性复制到已打开的存档中的角色
+// 目标可以是其他存档，也可以是本存档的其他角色；复制后在目标窗口保存时写入存档
+func (s *saveSession) showCopyCharacterDialog(index int) {
+	char, ok := s.editor.GetCharacterByIndex(index)
+	if !ok {
+		return
+	}
+
+	// 目标存档，默认选择第一个其他存档
+	targets := make([]*saveSession, 0, len(openSessions))
+	targetOptions := make([]string, 0, len(openSessions))
+	selectedTarget := -1
+	for i, target := range openSessions {
+		option := target.displayName()
+		if target == s {
+			option += "（本存档）"
+		} else if selectedTarget < 0 {
+			selectedTarget = i
+		}
+		targets = append(targets, target)
+		targetOptions = append(targetOptions, option)
+	}
+	if selectedTarget < 0 {
+		selectedTarget = slices.Index(targets, s)
+	}
+
+	// 目标角色随目标存档更新，默认选择相同位置的角色
+	characterSelect := widget.NewSelect(nil, nil)
+	var targetSelect *widget.Select
+	targetSelect = widget.NewSelect(targetOptions, func(string) {
+		target := targets[targetSelect.SelectedIndex()]
+		options := make([]string, 0, target.editor.GetCharacterCount())
+		for i := 0; i < target.editor.GetCharacterCount(); i++ {
+			member, _ := target.editor.GetCharacterByIndex(i)
+			options = append(options, fmt.Sprintf("%d. %s", i+1, member.Name))
+		}
+		characterSelect.Options = options
+		characterSelect.ClearSelected()
+		if len(options) > 0 {
+			characterSelect.SetSelectedIndex(min(index, len(options)-1))
+		}
+	})
+	targetSelect.SetSelectedIndex(selectedTarget)
+
+	// 要复制的属性，默认全部
+	fieldNames := make(map[string]string)
+	fieldOptions := make([]string, 0)
+	for _, field := range wcsave.CopyableFields() {
+		option := field.Label
+		if _, ok := fieldNames[option]; ok {
+			option = fmt.Sprintf("%s（%s）", field.Label, field.Name)
+		}
+		fieldNames[option] = field.Name
+		fieldOptions = append(fieldOptions, option)
+	}
+	fieldChecks := widget.NewCheckGroup(fieldOptions, nil)
+	fieldChecks.SetSelected(fieldOptions)
+	allCheck := widget.NewCheck("全部属性", func(checked bool) {
+		if checked {
+			fieldChecks.SetSelected(fieldOptions)
+		} else {
+			fieldChecks.SetSelected(nil)
+		}
+	})
+	allCheck.SetChecked(true)
+
+	form := widget.NewForm(
+		widget.NewFormItem("复制角色", widget.NewLabel(fmt.Sprintf("%d. %s", index+1, char.Name))),
+		widget.NewFormItem("目标存档", targetSelect),
+		widget.NewFormItem("目标角色", characterSelect),
+	)
+	content := container.NewBorder(container.NewVBox(form, allCheck), nil, nil, nil, container.NewVScroll(fieldChecks))
+
+	copyDialog := dialog.NewCustomConfirm("复制到其他存档", "复制", "取消", content, func(confirmed bool) {
+		if !confirmed || targetSelect.SelectedIndex() < 0 || characterSelect.SelectedIndex() < 0 {
+			return
+		}
+		names := make([]string, 0, len(fieldChecks.Selected))
+		for _, option := range fieldChecks.Selected {
+			names = append(names, fieldNames[option])
+		}
+		if len(names) == 0 {
+			dialog.ShowInformation("提示", "请至少选择一项属性", s.window)
+			return
+		}
+		target := targets[targetSelect.SelectedIndex()]
+		if !slices.Contains(openSessions, target) {
+			dialog.ShowError(fmt.Errorf("目标存档已关闭"), s.window)
+			return
+		}
+
+		// 目标窗口中尚未应用的输入先写入编辑器，避免刷新界面时丢失
+		if target != s && target.applyPendingInputs != nil && !target.applyPendingInputs() {
+			return
+		}
+		changed, err := target.editor.CopyCharacter(s.editor, index, characterSelect.SelectedIndex(), names)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("复制失败: %v", err), s.window)
+			return
+		}
+		if len(changed) == 0 {
+			dialog.ShowInformation("提示", "目标角色的这些属性与源角色相同，无需复制", s.window)
+			return
+		}
+
+		log.Printf("复制角色属性: %s 第%d个角色 -> %s 第%d个角色: %v", s.path, index+1, target.path, characterSelect.SelectedIndex()+1, changed)
+		target.copied = true
+		target.refreshCharacterWindow()
+		dialog.ShowInformation("复制完成", fmt.Sprintf("已将%s的%s复制到“%s”，在该窗口点击“保存修改”后写入存档", char.Name, strings.Join(changed, "、"), target.displayName()), s.window)
+	}, s.window)
+	copyDialog.Resize(fyne.NewSize(520, 600))
+	copyDialog.Show()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/watcher"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// startSaveWatcher 监视存档和 WC.cfg 的外部修改
+func (s *saveSession) startSaveWatcher() {
+	s.stopSaveWatcher()
+	if s.path == "" {
+		return
+	}
+
+	names := []string{filepath.Base(s.path), wcsave.ConfigFileName}
+	w, err := watcher.New(filepath.Dir(s.path), names, watcher.DefaultDelay, func(path string) {
+		fyne.Do(func() {
+			s.handleExternalChange(path)
+		})
+	})
+	if err != nil {
+		log.Printf("监视存档目录失败: %v", err)
+		return
+	}
+	s.saveWatcher = w
+	log.Printf("开始监视存档目录: %s", filepath.Dir(s.path))
+}
+
+// stopSaveWatcher 停止监视
+func (s *saveSession) stopSaveWatcher() {
+	if s.saveWatcher == nil {
+		return
+	}
+	if err := s.saveWatcher.Close(); err != nil {
+		log.Printf("停止监视存档目录失败: %v", err)
+	}
+	s.saveWatcher = nil
+}
+
+// handleExternalChange 处理存档目录中的文件修改
+func (s *saveSession) handleExternalChange(path string) {
+	if s.window == nil || s.editor == nil {
+		return
+	}
+
+	// WC.cfg 只用于显示进度名称，直接重新读取
+	if strings.EqualFold(filepath.Base(path), wcsave.ConfigFileName) {
+		log.Printf("检测到进度文件修改: %s", path)
+		s.reloadProgress()
+		s.window.SetTitle(s.windowTitle())
+		// 与进度选择界面为同一目录时，同时更新其中的进度名称
+		if currentSave != "" && sameFile(filepath.Dir(currentSave), filepath.Dir(s.path)) {
+			updateProgressNames(currentSave, nil)
+		}
+		return
+	}
+
+	// 编辑器自身的保存不会被视为外部修改
+	modified, err := s.editor.ExternallyModified()
+	if err != nil {
+		log.Printf("检查存档修改失败: %v", err)
+		return
+	}
+	if modified {
+		log.Printf("检测到存档被外部修改: %s", path)
+		s.showExternalChangeDialog()
+	}
+}
+
+// showExternalChangeDialog 提示存档已被外部修改，可重新加载或合并
+func (s *saveSession) showExternalChangeDialog() {
+	if s.externalChangeDialog != nil {
+		return
+	}
+
+	message := widget.NewLabel(fmt.Sprintf("存档 %s 已被游戏或其他程序修改。\n"+
+		"重新加载：放弃未保存的修改，显示最新存档。\n"+
+		"合并：在最新存档上保留你的修改，双方都修改的字段以你的修改为准。\n"+
+		"在处理之前不能保存，以免覆盖更新的存档。", filepath.Base(s.path)))
+
+	reloadButton := widget.NewButton("重新加载", func() {
+		s.closeExternalChangeDialog()
+		if err := s.loadSaveFile(s.path); err != nil {
+			dialog.ShowError(err, s.window)
+			return
+		}
+		s.refreshCharacterWindow()
+	})
+
+	mergeButton := widget.NewButton("合并", func() {
+		s.closeExternalChangeDialog()
+		if s.applyPendingInputs != nil && !s.applyPendingInputs() {
+			return
+		}
+		conflicts, err := s.editor.Merge()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("合并失败: %v", err), s.window)
+			return
+		}
+		s.refreshCharacterWindow()
+		if len(conflicts) > 0 {
+			dialog.ShowInformation("合并完成", fmt.Sprintf("以下字段双方都有修改，已保留你的修改:\n%s", strings.Join(conflicts, "、")), s.window)
+		}
+	})
+	mergeButton.Importance = widget.HighImportance
+
+	laterButton := widget.NewButton("稍后处理", func() {
+		s.closeExternalChangeDialog()
+	})
+
+	content := container.NewVBox(message, container.NewHBox(reloadButton, mergeButton, laterButton))
+	s.externalChangeDialog = dialog.NewCustomWithoutButtons("存档已被修改", content, s.window)
+	s.externalChangeDialog.Show()
+}
+
+// closeExternalChangeDialog 关闭外部修改提示框
+func (s *saveSession) closeExternalChangeDialog() {
+	if s.externalChangeDialog != nil {
+		s.externalChangeDialog.Hide()
+		s.externalChangeDialog = nil
+	}
+}
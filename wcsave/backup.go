@@ -0,0 +1,17 @@
+package wcsave
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// BackupFile 将文件复制为 文件名.时间.bak，返回备份文件路径
+func BackupFile(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	backupPath := fmt.Sprintf("%s.%s.bak", filePath, time.Now().Format("20060102-150405.000"))
+	return backupPath, os.WriteFile(backupPath, content, 0644)
+}
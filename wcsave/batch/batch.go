@@ -0,0 +1,200 @@
+package batch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/preset"
+)
+
+// Options 批量处理选项
+type Options struct {
+	Target  string // 作用对象，为空时使用各预设自身的设置
+	DryRun  bool   // 只计算修改，不写入文件
+	Workers int    // 并发数，小于1时使用CPU核数
+}
+
+// Change 单个字段的修改
+type Change struct {
+	Character string // 角色名，银两为空
+	Field     string
+	Old       int64
+	New       int64
+}
+
+// Result 单个存档的处理结果
+type Result struct {
+	File       string
+	Characters []string // 被修改的角色名
+	Changes    []Change
+	Backup     string // 保存前原文件的备份，未保存时为空
+	Err        error
+}
+
+// ResolveFiles 将目录、通配符或文件路径展开为存档文件列表
+// 目录展开为其中所有 .dat 文件（Save/Sald/Sav0 等各类存档），结果去重并排序
+func ResolveFiles(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	files := make([]string, 0)
+	add := func(file string) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+			matches, err := filepath.Glob(filepath.Join(pattern, "*.dat"))
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				add(match)
+			}
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的通配符%s: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("没有匹配的存档: %s", pattern)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				add(match)
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// Run 使用有限大小的工作池并发处理所有存档，结果顺序与 files 一致
+func Run(files []string, presets []preset.Preset, opts Options) []Result {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	results := make([]Result, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = ProcessFile(files[i], presets, opts)
+			}
+		}()
+	}
+
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// ProcessFile 将预设依次应用到单个存档，先备份原文件再原地保存（DryRun 时不保存）
+func ProcessFile(filePath string, presets []preset.Preset, opts Options) Result {
+	result := Result{File: filePath}
+
+	editor := wcsave.NewSaveEditor()
+	if err := editor.ReadSave(filePath); err != nil {
+		result.Err = fmt.Errorf("读取存档失败: %w", err)
+		return result
+	}
+
+	// 记录修改前的状态用于比较
+	before := wcsave.NewSaveEditor()
+	before.Characters = append(before.Characters, editor.Characters...)
+	before.MoneyInfo = editor.MoneyInfo
+
+	for _, p := range presets {
+		if _, err := p.Apply(editor, opts.Target); err != nil {
+			result.Err = fmt.Errorf("应用预设%s失败: %w", p.Name, err)
+			return result
+		}
+	}
+
+	result.Changes, result.Characters = diff(before, editor)
+	if opts.DryRun || len(result.Changes) == 0 {
+		return result
+	}
+
+	backupPath, err := wcsave.BackupFile(filePath)
+	if err != nil {
+		result.Err = fmt.Errorf("备份失败: %w", err)
+		return result
+	}
+	result.Backup = backupPath
+	if err := editor.SaveChanges(filePath, filePath); err != nil {
+		result.Err = fmt.Errorf("保存失败: %w", err)
+	}
+	return result
+}
+
+// diff 比较两个编辑器中的角色属性和银两，返回修改列表和被修改的角色名
+func diff(before, after *wcsave.SaveEditor) ([]Change, []string) {
+	changes := make([]Change, 0)
+	characters := make([]string, 0)
+
+	for i, char := range after.Characters {
+		if i >= len(before.Characters) {
+			break
+		}
+		oldValue := reflect.ValueOf(before.Characters[i].Data)
+		newValue := reflect.ValueOf(char.Data)
+		changed := false
+		for f := 0; f < newValue.NumField(); f++ {
+			if oldValue.Field(f).Int() == newValue.Field(f).Int() {
+				continue
+			}
+			changes = append(changes, Change{
+				Character: char.Name,
+				Field:     newValue.Type().Field(f).Name,
+				Old:       oldValue.Field(f).Int(),
+				New:       newValue.Field(f).Int(),
+			})
+			changed = true
+		}
+		if changed {
+			characters = append(characters, char.Name)
+		}
+	}
+
+	if before.MoneyInfo.Value != after.MoneyInfo.Value {
+		changes = append(changes, Change{
+			Field: preset.MoneyField,
+			Old:   int64(before.MoneyInfo.Value),
+			New:   int64(after.MoneyInfo.Value),
+		})
+	}
+
+	return changes, characters
+}
+
+// String 返回修改的可读描述
+func (c Change) String() string {
+	if c.Character == "" {
+		return fmt.Sprintf("%s: %d -> %d", c.Field, c.Old, c.New)
+	}
+	return fmt.Sprintf("%s.%s: %d -> %d", strings.TrimSpace(c.Character), c.Field, c.Old, c.New)
+}
@@ -0,0 +1,123 @@
+package batch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/preset"
+	"wcediter/wcsave/testsave"
+)
+
+// 在临时目录生成测试存档
+func copyTestSaves(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := testsave.Default().WriteFile(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("生成测试存档失败: %v", err)
+		}
+	}
+	return dir
+}
+
+// 测试展开目录和通配符
+func TestResolveFiles(t *testing.T) {
+	dir := copyTestSaves(t, "Save3.dat", "Sald3.dat", "Sav03.dat")
+	if err := os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	files, err := ResolveFiles([]string{dir})
+	if err != nil {
+		t.Fatalf("ResolveFiles失败: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("目录展开的文件数错误，预期3，实际%d: %v", len(files), files)
+	}
+
+	// 通配符与文件路径重复时应去重
+	files, err = ResolveFiles([]string{filepath.Join(dir, "Sal*.dat"), filepath.Join(dir, "Sald3.dat"), filepath.Join(dir, "Save3.dat")})
+	if err != nil {
+		t.Fatalf("ResolveFiles失败: %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("通配符展开的文件数错误，预期2，实际%d: %v", len(files), files)
+	}
+
+	if _, err := ResolveFiles([]string{filepath.Join(dir, "None*.dat")}); err == nil {
+		t.Error("没有匹配的存档时应该返回错误")
+	}
+}
+
+// 测试批量处理：预览不写入、正式运行写入、损坏的存档报告失败
+func TestRun(t *testing.T) {
+	dir := copyTestSaves(t, "Save3.dat", "Save4.dat")
+	badFile := filepath.Join(dir, "Bad.dat")
+	if err := os.WriteFile(badFile, []byte("bad"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	files := []string{filepath.Join(dir, "Save3.dat"), filepath.Join(dir, "Save4.dat"), badFile}
+	presets := []preset.Preset{{
+		Name:   "测试",
+		Target: "all",
+		Actions: []preset.Action{
+			{Field: "Level", Op: preset.OpSet, Value: 60},
+			{Field: preset.MoneyField, Op: preset.OpSet, Value: 12345},
+		},
+	}}
+
+	original, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+
+	results := Run(files, presets, Options{DryRun: true, Workers: 2})
+	if len(results) != 3 {
+		t.Fatalf("结果数量错误: %d", len(results))
+	}
+	if results[0].Err != nil || len(results[0].Changes) == 0 {
+		t.Errorf("预览结果错误: %+v", results[0])
+	}
+	if !errors.Is(results[2].Err, wcsave.ErrTruncated) {
+		t.Errorf("损坏的存档应该返回 ErrTruncated，实际: %v", results[2].Err)
+	}
+	content, _ := os.ReadFile(files[0])
+	if string(content) != string(original) {
+		t.Error("预览模式不应修改文件")
+	}
+
+	results = Run(files[:2], presets, Options{Workers: 2})
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("处理%s失败: %v", result.File, result.Err)
+		}
+		if result.File != files[i] {
+			t.Errorf("结果顺序错误: %s", result.File)
+		}
+		if backup, err := os.ReadFile(result.Backup); err != nil || string(backup) != string(original) {
+			t.Errorf("%s 保存前应备份原文件: %v", files[i], err)
+		}
+
+		editor := wcsave.NewSaveEditor()
+		if err := editor.ReadSave(files[i]); err != nil {
+			t.Fatalf("重新读取文件失败: %v", err)
+		}
+		if editor.MoneyInfo.Value != 12345 {
+			t.Errorf("%s 银两错误: %d", files[i], editor.MoneyInfo.Value)
+		}
+		for _, char := range editor.Characters {
+			if char.Data.Level != 60 {
+				t.Errorf("%s 角色%s等级错误: %d", files[i], char.Name, char.Data.Level)
+			}
+		}
+	}
+
+	// 再次运行时没有修改
+	results = Run(files[:1], presets, Options{})
+	if len(results[0].Changes) != 0 || results[0].Backup != "" {
+		t.Errorf("重复运行不应产生修改或备份: %+v", results[0])
+	}
+}
@@ -0,0 +1,316 @@
+// Package bundle 将一套存档（同一版本的全部进度文件和 WC.cfg）打包为 zip，
+// 附带记录队伍概况、进度信息和校验和的清单，便于在不同电脑之间迁移
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/models"
+	"wcediter/wcsave/reader"
+)
+
+// Format 清单格式版本
+const Format = 1
+
+// Extension 存档包的扩展名
+const Extension = ".wcbundle"
+
+// ManifestName 存档包中清单文件的名称
+const ManifestName = "manifest.json"
+
+// Manifest 存档包清单
+type Manifest struct {
+	Format   int                   `json:"format"`
+	Variant  string                `json:"variant"`  // 存档版本前缀
+	Created  time.Time             `json:"created"`  // 打包时间
+	Slots    []Slot                `json:"slots"`    // 各存档文件的队伍概况
+	Progress []models.ProgressInfo `json:"progress"` // WC.cfg 中的进度信息，没有 WC.cfg 时为空
+	Files    []File                `json:"files"`    // 包中的全部文件及校验和
+}
+
+// Slot 单个存档文件的概况
+type Slot struct {
+	File  string   `json:"file"`
+	Index int      `json:"index"`           // 文件名末尾的编号
+	Party []Member `json:"party,omitempty"` // 队伍成员
+	Error string   `json:"error,omitempty"` // 读取角色失败的原因
+}
+
+// Member 队伍成员概况
+type Member struct {
+	Name  string `json:"name"`
+	Level int16  `json:"level"`
+}
+
+// File 包中的文件
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Installed 导入时写入的文件
+type Installed struct {
+	Path   string // 写入的文件
+	Backup string // 原文件的备份，原文件不存在或内容相同时为空
+	Same   bool   // 原文件内容相同，未写入
+}
+
+// Export 将目录中指定版本的存档文件和 WC.cfg 打包写入 w，返回写入的清单
+func Export(dir, variant string, w io.Writer) (Manifest, error) {
+	if !knownVariant(variant) {
+		return Manifest{}, fmt.Errorf("未知的存档版本: %s，应为 %s", variant, strings.Join(wcsave.Variants, "、"))
+	}
+	gameDir, err := wcsave.OpenGameDir(dir)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest := Manifest{Format: Format, Variant: variant, Created: time.Now()}
+	contents := make(map[string][]byte)
+	add := func(name string) error {
+		content, err := os.ReadFile(filepath.Join(gameDir.Path, name))
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		manifest.Files = append(manifest.Files, File{Name: name, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])})
+		contents[name] = content
+		return nil
+	}
+
+	for _, i := range gameDir.Set(variant).Indexes() {
+		name := wcsave.SlotFileName(variant, i)
+		if err := add(name); err != nil {
+			return Manifest{}, err
+		}
+
+		slot := Slot{File: name, Index: i}
+		characters, err := reader.ReadCharacters(bytes.NewReader(contents[name]))
+		if err != nil {
+			slot.Error = err.Error()
+		}
+		for _, char := range characters {
+			slot.Party = append(slot.Party, Member{Name: char.Name, Level: char.Data.Level})
+		}
+		manifest.Slots = append(manifest.Slots, slot)
+	}
+	if len(manifest.Slots) == 0 {
+		return Manifest{}, fmt.Errorf("%s中没有%s版本的存档文件", dir, variant)
+	}
+
+	if _, err := os.Stat(gameDir.ConfigPath()); err == nil {
+		if gameDir.ConfigErr != nil {
+			return Manifest{}, fmt.Errorf("读取%s失败: %w", wcsave.ConfigFileName, gameDir.ConfigErr)
+		}
+		if err := add(wcsave.ConfigFileName); err != nil {
+			return Manifest{}, err
+		}
+		manifest.Progress = gameDir.Progress
+	}
+
+	// 清单放在最前面，查看包内容时先看到
+	archive := zip.NewWriter(w)
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := writeEntry(archive, ManifestName, manifestBytes, manifest.Created); err != nil {
+		return Manifest{}, err
+	}
+	for _, file := range manifest.Files {
+		if err := writeEntry(archive, file.Name, contents[file.Name], manifest.Created); err != nil {
+			return Manifest{}, err
+		}
+	}
+	return manifest, archive.Close()
+}
+
+// ExportFile 将目录中指定版本的存档打包为文件
+func ExportFile(dir, variant, bundlePath string) (Manifest, error) {
+	file, err := os.Create(bundlePath)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest, err := Export(dir, variant, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(bundlePath)
+		return Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// Open 读取存档包，校验清单中每个文件的大小和 SHA-256，返回清单和文件内容
+// 任何文件不一致、缺失或包中有清单以外的文件时返回错误
+func Open(bundlePath string) (Manifest, map[string][]byte, error) {
+	archive, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("打开存档包失败: %w", err)
+	}
+	defer archive.Close()
+
+	entries := make(map[string][]byte)
+	for _, entry := range archive.File {
+		content, err := readEntry(entry)
+		if err != nil {
+			return Manifest{}, nil, fmt.Errorf("读取%s失败: %w", entry.Name, err)
+		}
+		entries[entry.Name] = content
+	}
+
+	manifestBytes, ok := entries[ManifestName]
+	if !ok {
+		return Manifest{}, nil, fmt.Errorf("存档包中没有%s", ManifestName)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return Manifest{}, nil, fmt.Errorf("解析%s失败: %v", ManifestName, err)
+	}
+	if manifest.Format != Format {
+		return Manifest{}, nil, fmt.Errorf("不支持的清单版本: %d", manifest.Format)
+	}
+	if !knownVariant(manifest.Variant) {
+		return Manifest{}, nil, fmt.Errorf("清单中的存档版本未知: %q", manifest.Variant)
+	}
+	delete(entries, ManifestName)
+
+	// 只接受 WC.cfg 和该版本的存档文件名，同时防止写到目标目录以外
+	allowed := map[string]bool{wcsave.ConfigFileName: true}
+	for i := 0; i < wcsave.SlotCount; i++ {
+		allowed[wcsave.SlotFileName(manifest.Variant, i)] = true
+	}
+
+	contents := make(map[string][]byte)
+	for _, file := range manifest.Files {
+		if !allowed[file.Name] {
+			return Manifest{}, nil, fmt.Errorf("清单中的文件名无效: %q，应为%s或%s", file.Name, wcsave.ConfigFileName, wcsave.SlotFileName(manifest.Variant, 0))
+		}
+		if _, ok := contents[file.Name]; ok {
+			return Manifest{}, nil, fmt.Errorf("清单中的文件重复: %s", file.Name)
+		}
+		content, ok := entries[file.Name]
+		if !ok {
+			return Manifest{}, nil, fmt.Errorf("存档包中缺少%s", file.Name)
+		}
+		sum := sha256.Sum256(content)
+		if int64(len(content)) != file.Size || hex.EncodeToString(sum[:]) != file.SHA256 {
+			return Manifest{}, nil, fmt.Errorf("%s的校验和不一致，存档包可能已损坏", file.Name)
+		}
+		contents[file.Name] = content
+		delete(entries, file.Name)
+	}
+	if len(entries) > 0 {
+		names := make([]string, 0, len(entries))
+		for name := range entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Manifest{}, nil, fmt.Errorf("存档包中有清单以外的文件: %s", strings.Join(names, ", "))
+	}
+	return manifest, contents, nil
+}
+
+// Import 校验存档包并安装到目录，已有的不同内容的文件先用 wcsave.BackupFile 备份
+// 校验全部通过后才开始写入；中途写入失败时恢复已写入的文件，返回的列表为空
+func Import(bundlePath, dir string) (Manifest, []Installed, error) {
+	manifest, contents, err := Open(bundlePath)
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Manifest{}, nil, err
+	}
+
+	installed := make([]Installed, 0, len(manifest.Files))
+	originals := make(map[string][]byte) // 已写入文件的原内容，原文件不存在时为 nil
+	for _, file := range manifest.Files {
+		item := Installed{Path: filepath.Join(dir, file.Name)}
+		existing, err := os.ReadFile(item.Path)
+		switch {
+		case err == nil && bytes.Equal(existing, contents[file.Name]):
+			item.Same = true
+			installed = append(installed, item)
+			continue
+		case err == nil:
+			item.Backup, err = wcsave.BackupFile(item.Path)
+			if err != nil {
+				return manifest, nil, rollback(originals, fmt.Errorf("备份%s失败: %w", item.Path, err))
+			}
+		case !os.IsNotExist(err):
+			return manifest, nil, rollback(originals, err)
+		}
+
+		originals[item.Path] = existing
+		if err := os.WriteFile(item.Path, contents[file.Name], 0644); err != nil {
+			return manifest, nil, rollback(originals, fmt.Errorf("写入%s失败: %w", item.Path, err))
+		}
+		installed = append(installed, item)
+	}
+	return manifest, installed, nil
+}
+
+// rollback 将已写入的文件恢复为原内容（原来不存在的文件删除），恢复失败时附加到 err 中
+func rollback(originals map[string][]byte, err error) error {
+	for path, content := range originals {
+		var restoreErr error
+		if content == nil {
+			restoreErr = os.Remove(path)
+		} else {
+			restoreErr = os.WriteFile(path, content, 0644)
+		}
+		if restoreErr != nil && !os.IsNotExist(restoreErr) {
+			err = fmt.Errorf("%w；恢复%s失败: %v", err, path, restoreErr)
+		}
+	}
+	return err
+}
+
+// knownVariant 是否为已知的存档版本
+func knownVariant(variant string) bool {
+	for _, v := range wcsave.Variants {
+		if v == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// writeEntry 向 zip 写入一个文件
+func writeEntry(archive *zip.Writer, name string, content []byte, modified time.Time) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// readEntry 读取 zip 中的一个文件，限制大小以防解压炸弹
+func readEntry(entry *zip.File) ([]byte, error) {
+	const maxSize = 16 << 20
+	if entry.UncompressedSize64 > maxSize {
+		return nil, fmt.Errorf("文件过大: %d 字节", entry.UncompressedSize64)
+	}
+	r, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, maxSize))
+}
@@ -0,0 +1,204 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/testsave"
+)
+
+// writeGameDir 在临时目录中生成一套 Sav0 版本的存档和 WC.cfg
+func writeGameDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	saves := make(map[string]testsave.Save)
+	for i := 0; i < wcsave.SlotCount; i++ {
+		save := testsave.Default()
+		save.Money = int32(i * 100)
+		saves[wcsave.SlotFileName("Sav0", i)] = save
+	}
+	if err := testsave.WriteDir(dir, testsave.DefaultConfig(), saves); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestExportImport(t *testing.T) {
+	dir := writeGameDir(t)
+	gameDir, err := wcsave.OpenGameDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variants := gameDir.VariantNames(); len(variants) != 1 || variants[0] != "Sav0" {
+		t.Fatalf("版本识别错误: %v", variants)
+	}
+
+	bundlePath := filepath.Join(t.TempDir(), "game"+Extension)
+	manifest, err := ExportFile(dir, "Sav0", bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Slots) != wcsave.SlotCount || len(manifest.Files) != wcsave.SlotCount+1 || len(manifest.Progress) != 5 {
+		t.Fatalf("清单内容错误: %+v", manifest)
+	}
+	if slot := manifest.Slots[3]; slot.File != "Sav03.dat" || len(slot.Party) != 3 || slot.Party[0].Name != "葉小釵" {
+		t.Errorf("队伍概况错误: %+v", slot)
+	}
+	if _, err := ExportFile(dir, "Save", filepath.Join(t.TempDir(), "x"+Extension)); err == nil {
+		t.Error("没有该版本的存档时应返回错误")
+	}
+
+	// 目标目录中已有不同内容的存档时先备份
+	target := t.TempDir()
+	old := []byte("old")
+	if err := os.WriteFile(filepath.Join(target, "Sav02.dat"), old, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, installed, err := Import(bundlePath, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installed) != wcsave.SlotCount+1 {
+		t.Fatalf("应写入%d个文件，实际%d", wcsave.SlotCount+1, len(installed))
+	}
+	for _, item := range installed {
+		want, _ := os.ReadFile(filepath.Join(dir, filepath.Base(item.Path)))
+		got, _ := os.ReadFile(item.Path)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s的内容不同", item.Path)
+		}
+		if filepath.Base(item.Path) == "Sav02.dat" {
+			backup, err := os.ReadFile(item.Backup)
+			if err != nil || !bytes.Equal(backup, old) {
+				t.Errorf("备份错误: %q, %v", backup, err)
+			}
+		} else if item.Backup != "" {
+			t.Errorf("%s不应备份", item.Path)
+		}
+	}
+
+	// 中途写入失败时恢复已写入的文件（Sav03.dat 是目录，无法读取）
+	failed := t.TempDir()
+	if err := os.WriteFile(filepath.Join(failed, "Sav01.dat"), old, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(failed, "Sav03.dat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, installed, err := Import(bundlePath, failed); err == nil || len(installed) != 0 {
+		t.Fatalf("写入失败时应返回错误且不报告已写入的文件: %v, %v", installed, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(failed, "Sav01.dat")); !bytes.Equal(content, old) {
+		t.Errorf("写入失败后应恢复原文件，实际%q", content)
+	}
+	for _, name := range []string{"Sav00.dat", "Sav02.dat"} {
+		if _, err := os.Stat(filepath.Join(failed, name)); !os.IsNotExist(err) {
+			t.Errorf("写入失败后应删除新写入的%s: %v", name, err)
+		}
+	}
+
+	// 再次导入时内容相同，不写入也不备份
+	_, installed, err = Import(bundlePath, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range installed {
+		if !item.Same || item.Backup != "" {
+			t.Errorf("重复导入结果错误: %+v", item)
+		}
+	}
+}
+
+func TestOpenCorrupted(t *testing.T) {
+	dir := writeGameDir(t)
+	var buffer bytes.Buffer
+	if _, err := Export(dir, "Sav0", &buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	// 替换其中一个存档的内容
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corrupted bytes.Buffer
+	w := zip.NewWriter(&corrupted)
+	for _, entry := range archive.File {
+		content, err := readEntry(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Name == "Sav01.dat" {
+			content[0] ^= 0xFF
+		}
+		if err := writeEntry(w, entry.Name, content, entry.Modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bundlePath := filepath.Join(t.TempDir(), "bad"+Extension)
+	if err := os.WriteFile(bundlePath, corrupted.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := t.TempDir()
+	if _, _, err := Import(bundlePath, target); err == nil || !strings.Contains(err.Error(), "Sav01.dat") {
+		t.Fatalf("应报告校验和不一致: %v", err)
+	}
+	if entries, _ := os.ReadDir(target); len(entries) != 0 {
+		t.Error("校验失败时不应写入任何文件")
+	}
+}
+
+// 测试清单中的文件名必须是 WC.cfg 或该版本的存档文件
+func TestOpenUnexpectedNames(t *testing.T) {
+	dir := writeGameDir(t)
+	var buffer bytes.Buffer
+	if _, err := Export(dir, "Sav0", &buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Save1.dat", "Sav05.dat", "autoexec.bat", "../Sav01.dat"} {
+		// 将清单和包中的 Sav01.dat 改名，校验和仍然一致
+		archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var renamed bytes.Buffer
+		w := zip.NewWriter(&renamed)
+		for _, entry := range archive.File {
+			content, err := readEntry(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entryName := entry.Name
+			switch entry.Name {
+			case ManifestName:
+				content = bytes.Replace(content, []byte(`"Sav01.dat"`), []byte(`"`+name+`"`), -1)
+			case "Sav01.dat":
+				entryName = name
+			}
+			if err := writeEntry(w, entryName, content, entry.Modified); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		bundlePath := filepath.Join(t.TempDir(), "renamed"+Extension)
+		if err := os.WriteFile(bundlePath, renamed.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := Open(bundlePath); err == nil {
+			t.Errorf("清单中的文件名为%s时应返回错误", name)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package check
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// Severity 问题的严重程度
+type Severity int
+
+const (
+	Info    Severity = iota // 提示
+	Warning                 // 数值可疑，但存档仍可使用
+	Error                   // 存档已损坏或无法正确读取
+)
+
+// String 返回严重程度的名称
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// MarshalText 以名称输出到 JSON
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Issue 检查发现的问题
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`             // 问题类型，如 size、terminator、range
+	Offset   int64    `json:"offset,omitempty"` // 问题所在的文件偏移，未知时为0
+	Message  string   `json:"message"`
+}
+
+// Report 单个存档的检查结果
+type Report struct {
+	File       string  `json:"file"`
+	Size       int64   `json:"size"`
+	Characters int     `json:"characters"` // 解析出的角色数量
+	Issues     []Issue `json:"issues"`
+}
+
+// Range 字段的合理取值范围
+type Range struct {
+	Min, Max int64
+}
+
+// Clamp 将数值限制在合理范围内，仅用于修复（修复内容会逐项列出）
+func (r Range) Clamp(value int64) int64 {
+	return max(r.Min, min(r.Max, value))
+}
+
+// FieldRanges 角色字段的合理取值范围，超出时给出警告
+var FieldRanges = map[string]Range{
+	"CurrentExp":   {0, 99999999},
+	"NextLevelExp": {0, 99999999},
+	"MaxHP":        {1, 99999},
+	"MaxMP":        {0, 99999},
+	"CurrentHP":    {0, 99999},
+	"CurrentMP":    {0, 99999},
+	"Strength":     {0, 9999},
+	"Reaction":     {0, 9999},
+	"Constitution": {0, 9999},
+	"Speed":        {0, 9999},
+	"Attack":       {0, 9999},
+	"Defense":      {0, 9999},
+	"Luck":         {0, 9999},
+	"Level":        {1, 99},
+}
+
+// MoneyRange 银两的合理取值范围
+var MoneyRange = Range{0, 99999999}
+
+// CurrentMaxPairs 当前值不应超过最大值的角色字段（当前值字段名, 最大值字段名）
+var CurrentMaxPairs = [][2]string{{"CurrentHP", "MaxHP"}, {"CurrentMP", "MaxMP"}}
+
+// MoneyField、MapField 存档中的银两和地图编号字段
+var (
+	MoneyField = layout.Field{Name: "Money", Label: "银两", Offset: models.MoneyPosition, Size: 4, Type: layout.TypeInt32}
+	MapField   = layout.Field{Name: "MapID", Label: "地图编号", Offset: models.MapPosition, Size: 4, Type: layout.TypeInt32}
+)
+
+// MapRange 地图编号的合理取值范围
+// 地图编号是游戏内部的编号，与位置名称表无关；已知存档中的编号都在100以内
+var MapRange = Range{0, 255}
+
+// add 添加一个问题
+func (r *Report) add(severity Severity, code string, offset int64, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{Severity: severity, Code: code, Offset: offset, Message: fmt.Sprintf(format, args...)})
+}
+
+// Worst 返回最严重的问题等级，没有问题时返回 Info
+func (r Report) Worst() Severity {
+	worst := Info
+	for _, issue := range r.Issues {
+		if issue.Severity > worst {
+			worst = issue.Severity
+		}
+	}
+	return worst
+}
+
+// OK 没有错误级别的问题
+func (r Report) OK() bool {
+	return r.Worst() < Error
+}
+
+// File 检查存档文件，除了按布局检查原始字节，还会用 SaveEditor 读取一次以发现读取时被忽略的错误
+func File(filePath string) Report {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		report := Report{File: filePath}
+		report.add(Error, "read", 0, "无法读取文件: %v", err)
+		return report
+	}
+
+	report := Bytes(filePath, content)
+
+	editor := wcsave.NewSaveEditor()
+	if err := editor.ReadSave(filePath); err != nil {
+		report.add(Error, "reader", 0, "SaveEditor 读取失败: %v", err)
+	}
+	if editor.GetCharacterCount() != report.Characters {
+		report.add(Error, "reader", models.CharacterStartPosition, "SaveEditor 只读取到%d个角色，记录区中有%d个", editor.GetCharacterCount(), report.Characters)
+	}
+	if editor.MoneyInfo.Position == 0 && int64(len(content)) > models.MoneyPosition {
+		report.add(Error, "reader", models.MoneyPosition, "SaveEditor 未能读取银两")
+	}
+	return report
+}
+
+// Bytes 按已知的存档布局检查内容
+func Bytes(name string, content []byte) Report {
+	report := Report{File: name, Size: int64(len(content)), Issues: make([]Issue, 0)}
+
+	// 文件大小
+	switch {
+	case len(content) < models.SaveFileSize:
+		report.add(Error, "size", int64(len(content)), "文件大小为%d字节，少于%d字节，可能写入不完整", len(content), models.SaveFileSize)
+	case len(content) > models.SaveFileSize:
+		report.add(Warning, "size", models.SaveFileSize, "文件大小为%d字节，多于%d字节", len(content), models.SaveFileSize)
+	}
+
+	// 队伍人数
+	partyCount := -1
+	if len(content) >= models.PartyCountPosition+2 {
+		partyCount = int(binary.LittleEndian.Uint16(content[models.PartyCountPosition:]))
+		if partyCount < 1 || partyCount > models.MaxCharacters {
+			report.add(Error, "party", models.PartyCountPosition, "队伍人数为%d，应在1到%d之间", partyCount, models.MaxCharacters)
+		}
+	} else {
+		report.add(Error, "party", models.PartyCountPosition, "文件过短，无法读取队伍人数")
+	}
+
+	checkCharacters(&report, content, partyCount)
+	checkMoney(&report, content)
+	checkPosition(&report, content)
+	return report
+}
+
+// checkCharacters 检查角色记录区
+func checkCharacters(report *Report, content []byte, partyCount int) {
+	terminated := false
+	for i := 0; i < models.MaxCharacters; i++ {
+		start := int64(models.CharacterStartPosition + i*models.CharacterRecordSize)
+		if int64(len(content)) < start+models.CharacterRecordSize {
+			report.add(Error, "record", start, "角色%d的记录不完整", i+1)
+			return
+		}
+		record := content[start : start+models.CharacterRecordSize]
+
+		if record[0] == 0 && record[1] == 0 {
+			terminated = true
+			// 结束标记之后的记录应为空
+			if partyCount >= 0 && i != partyCount {
+				report.add(Error, "terminator", start, "结束标记位于第%d条记录，与队伍人数%d不一致", i+1, partyCount)
+			}
+			break
+		}
+
+		report.Characters++
+		checkName(report, record[:6], start, i+1)
+		for _, field := range layout.CharacterFields {
+			fieldRange, ok := FieldRanges[field.Name]
+			if !ok {
+				continue
+			}
+			value := FieldValue(record, field)
+			if value < fieldRange.Min || value > fieldRange.Max {
+				report.add(Warning, "range", start+field.Offset, "角色%d的%s为%d，超出合理范围%d~%d", i+1, field.Label, value, fieldRange.Min, fieldRange.Max)
+			}
+		}
+
+		for _, pair := range CurrentMaxPairs {
+			current, maximum := CharacterField(pair[0]), CharacterField(pair[1])
+			if value, limit := FieldValue(record, current), FieldValue(record, maximum); value > limit {
+				report.add(Warning, "range", start+current.Offset, "角色%d的%s%d大于%s%d", i+1, current.Label, value, maximum.Label, limit)
+			}
+		}
+	}
+
+	if !terminated && report.Characters < models.MaxCharacters {
+		report.add(Error, "terminator", models.CharacterStartPosition, "角色记录区缺少结束标记")
+	}
+	if report.Characters == models.MaxCharacters && partyCount >= 0 && partyCount != models.MaxCharacters {
+		report.add(Error, "terminator", models.CharacterStartPosition+int64(partyCount*models.CharacterRecordSize), "队伍人数为%d，但记录区中没有结束标记", partyCount)
+	}
+}
+
+// checkName 检查角色名是否为有效的Big5编码
+func checkName(report *Report, nameBytes []byte, offset int64, index int) {
+	if err := ValidateName(nameBytes); err != nil {
+		report.add(Error, "name", offset, "角色%d的%v", index, err)
+	}
+}
+
+// ValidateName 检查6字节的名字字段是否为非空的有效Big5字符串
+func ValidateName(nameBytes []byte) error {
+	trimmed := strings.TrimRight(string(nameBytes), " \x00")
+	if trimmed == "" {
+		return fmt.Errorf("名字为空")
+	}
+
+	decoded, err := traditionalchinese.Big5.NewDecoder().String(trimmed)
+	if err != nil {
+		return fmt.Errorf("名字无法按Big5解码: %v", err)
+	}
+	for _, r := range decoded {
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			return fmt.Errorf("名字包含无效的Big5字符: % X", nameBytes)
+		}
+	}
+	return nil
+}
+
+// checkMoney 检查银两
+func checkMoney(report *Report, content []byte) {
+	if len(content) < models.MoneyPosition+4 {
+		report.add(Error, "money", models.MoneyPosition, "文件过短，无法读取银两")
+		return
+	}
+	money := FieldValue(content, MoneyField)
+	if money < MoneyRange.Min || money > MoneyRange.Max {
+		report.add(Warning, "range", models.MoneyPosition, "银两为%d，超出合理范围%d~%d", money, MoneyRange.Min, MoneyRange.Max)
+	}
+}
+
+// checkPosition 检查队伍位置
+func checkPosition(report *Report, content []byte) {
+	if len(content) < models.MapPosition+models.PositionYOffset+4 {
+		report.add(Error, "position", models.MapPosition, "文件过短，无法读取队伍位置")
+		return
+	}
+	mapID := FieldValue(content, MapField)
+	if mapID < MapRange.Min || mapID > MapRange.Max {
+		report.add(Warning, "position", models.MapPosition, "地图编号%d超出合理范围%d~%d", mapID, MapRange.Min, MapRange.Max)
+	}
+}
+
+// FieldValue 用 layout.Field.Value 解码 data 中 field 处的整数，field.Offset 相对于 data 的起始位置
+// 调用方需先确认 data 足够长，数据不足或不是整数字段时返回0
+func FieldValue(data []byte, field layout.Field) int64 {
+	if field.Offset < 0 || field.Offset > int64(len(data)) {
+		return 0
+	}
+	value, err := field.Value(data[field.Offset:])
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// CharacterField 按字段名查找 layout.CharacterFields 中的角色字段
+func CharacterField(name string) layout.Field {
+	for _, field := range layout.CharacterFields {
+		if field.Name == name {
+			return field
+		}
+	}
+	panic("未知的角色字段: " + name)
+}
+
+// String 返回文本格式的报告
+func (r Report) String() string {
+	var builder strings.Builder
+	status := "正常"
+	switch r.Worst() {
+	case Error:
+		status = "损坏"
+	case Warning:
+		status = "可疑"
+	}
+	fmt.Fprintf(&builder, "%s: %s（%d字节，%d个角色）\n", r.File, status, r.Size, r.Characters)
+	for _, issue := range r.Issues {
+		if issue.Offset != 0 {
+			fmt.Fprintf(&builder, "  [%s] %s @0x%X: %s\n", issue.Severity, issue.Code, issue.Offset, issue.Message)
+		} else {
+			fmt.Fprintf(&builder, "  [%s] %s: %s\n", issue.Severity, issue.Code, issue.Message)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,139 @@
+package check
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+	"wcediter/wcsave/testsave"
+)
+
+// 生成测试存档（三人队伍）
+func readTestSave(t *testing.T) []byte {
+	content, err := testsave.Default().Bytes()
+	if err != nil {
+		t.Fatalf("生成测试存档失败: %v", err)
+	}
+	return content
+}
+
+// 判断报告中是否包含指定等级和类型的问题
+func hasIssue(report Report, severity Severity, code string) bool {
+	for _, issue := range report.Issues {
+		if issue.Severity == severity && issue.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// 测试正常存档没有问题
+func TestBytesValid(t *testing.T) {
+	report := Bytes("Save4.dat", readTestSave(t))
+	if len(report.Issues) != 0 {
+		t.Errorf("正常存档不应有问题: %v", report.Issues)
+	}
+	if report.Characters != 3 {
+		t.Errorf("角色数量错误，预期3，实际%d", report.Characters)
+	}
+}
+
+// 测试各类损坏
+func TestBytesCorrupted(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func([]byte) []byte
+		severity Severity
+		code     string
+	}{
+		{"文件截断", func(b []byte) []byte { return b[:models.MoneyPosition] }, Error, "size"},
+		{"文件过长", func(b []byte) []byte { return append(b, 0) }, Warning, "size"},
+		{"队伍人数无效", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[models.PartyCountPosition:], 9)
+			return b
+		}, Error, "party"},
+		{"结束标记与人数不一致", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[models.PartyCountPosition:], 2)
+			return b
+		}, Error, "terminator"},
+		{"缺少结束标记", func(b []byte) []byte {
+			for i := 3; i < models.MaxCharacters; i++ {
+				copy(b[models.CharacterStartPosition+i*models.CharacterRecordSize:], b[models.CharacterStartPosition:models.CharacterStartPosition+models.CharacterRecordSize])
+			}
+			return b
+		}, Error, "terminator"},
+		{"名字无效", func(b []byte) []byte {
+			copy(b[models.CharacterStartPosition:], []byte{0xFF, 0x01, 0x02, 0x03, 0x04, 0x05})
+			return b
+		}, Error, "name"},
+		{"等级超出范围", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[models.CharacterStartPosition+70:], 500)
+			return b
+		}, Warning, "range"},
+		{"银两为负数", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[models.MoneyPosition:], 0xFFFFFFFF)
+			return b
+		}, Warning, "range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Bytes("test.dat", tt.modify(readTestSave(t)))
+			if !hasIssue(report, tt.severity, tt.code) {
+				t.Errorf("应该报告 %s/%s，实际: %v", tt.severity, tt.code, report.Issues)
+			}
+			if report.Worst() != tt.severity {
+				t.Errorf("最严重等级错误，预期%s，实际%s", tt.severity, report.Worst())
+			}
+		})
+	}
+}
+
+// 测试读取文件时报告 SaveEditor 的读取错误
+func TestFile(t *testing.T) {
+	content := readTestSave(t)
+	filePath := filepath.Join(t.TempDir(), "Save4.dat")
+	if err := os.WriteFile(filePath, content[:models.CharacterStartPosition+100], 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	report := File(filePath)
+	if report.OK() {
+		t.Fatal("截断的存档不应通过检查")
+	}
+	if !hasIssue(report, Error, "reader") {
+		t.Errorf("应该报告 SaveEditor 的读取错误: %v", report.Issues)
+	}
+
+	if report := File(filepath.Join(t.TempDir(), "none.dat")); !hasIssue(report, Error, "read") {
+		t.Errorf("不存在的文件应该报告读取错误: %v", report.Issues)
+	}
+}
+
+// 测试按字段类型解码整数
+func TestFieldValue(t *testing.T) {
+	data := []byte{0xFF, 0xFF, 0xFF, 0xFF}
+	cases := []struct {
+		fieldType layout.FieldType
+		size      int
+		want      int64
+	}{
+		{layout.TypeInt8, 1, -1},
+		{layout.TypeUint8, 1, 255},
+		{layout.TypeInt16, 2, -1},
+		{layout.TypeUint16, 2, 65535},
+		{layout.TypeInt32, 4, -1},
+	}
+	for _, c := range cases {
+		field := layout.Field{Label: "测试", Offset: 0, Size: c.size, Type: c.fieldType}
+		if got := FieldValue(data, field); got != c.want {
+			t.Errorf("类型%v的值应为%d，实际%d", c.fieldType, c.want, got)
+		}
+	}
+	if got := FieldValue(data, layout.Field{Offset: 2, Size: 4, Type: layout.TypeInt32}); got != 0 {
+		t.Errorf("数据不足时应返回0，实际%d", got)
+	}
+}
@@ -0,0 +1,94 @@
+package wcsave
+
+import (
+	"fmt"
+	"reflect"
+
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+)
+
+// CopyableFields 返回可以在存档之间复制的角色字段：内置和自定义的整数字段，不含名字
+func CopyableFields() []layout.Field {
+	var fields []layout.Field
+	for _, field := range layout.AllCharacterFields() {
+		if field.Type.Integer() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// CopyCharacter 将 src 中第 srcIndex 个角色的字段复制到本编辑器的第 dstIndex 个角色
+// names 为 CopyableFields 中的字段名，为空时复制全部字段
+// 先读取并校验所有字段，任一字段无效时不做任何修改；返回值发生变化的字段的显示名称
+func (e *SaveEditor) CopyCharacter(src *SaveEditor, srcIndex, dstIndex int, names []string) ([]string, error) {
+	from, ok := src.GetCharacterByIndex(srcIndex)
+	if !ok {
+		return nil, fmt.Errorf("源存档中没有第%d个角色", srcIndex+1)
+	}
+	to, ok := e.GetCharacterByIndex(dstIndex)
+	if !ok {
+		return nil, fmt.Errorf("目标存档中没有第%d个角色", dstIndex+1)
+	}
+
+	fields := CopyableFields()
+	if len(names) > 0 {
+		byName := make(map[string]layout.Field, len(fields))
+		for _, field := range fields {
+			byName[field.Name] = field
+		}
+		fields = fields[:0:0]
+		for _, name := range names {
+			field, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("不能复制的角色字段: %s", name)
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	type change struct {
+		field layout.Field
+		value int64
+	}
+	var changes []change
+	for _, field := range fields {
+		value, err := characterValue(src, from, field)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := field.Encode(value); err != nil {
+			return nil, err
+		}
+		current, err := characterValue(e, to, field)
+		if err != nil {
+			return nil, err
+		}
+		if current != value {
+			changes = append(changes, change{field, value})
+		}
+	}
+
+	// 内置字段写入角色数据，自定义字段以原始字节修改的方式写入
+	data := to.Data
+	changed := make([]string, 0, len(changes))
+	for _, c := range changes {
+		if target := reflect.ValueOf(&data).Elem().FieldByName(c.field.Name); target.IsValid() {
+			target.SetInt(c.value)
+		} else if err := e.SetCustomValue(c.field.At(to.Position), c.value); err != nil {
+			return changed, err
+		}
+		changed = append(changed, c.field.Label)
+	}
+	e.UpdateCharacter(dstIndex, data)
+	return changed, nil
+}
+
+// characterValue 读取角色的整数字段，内置字段取编辑器中尚未保存的角色数据
+func characterValue(e *SaveEditor, char models.CharacterInfo, field layout.Field) (int64, error) {
+	if value := reflect.ValueOf(char.Data).FieldByName(field.Name); value.IsValid() {
+		return value.Int(), nil
+	}
+	return e.CustomValue(field.At(char.Position))
+}
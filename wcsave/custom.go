@@ -0,0 +1,79 @@
+package wcsave
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+)
+
+// CurrentBytes 返回存档中 offset 处 size 字节的当前内容
+// 以读取时的内容为基础，叠加编辑器中的角色记录和队伍成员编号表（队伍成员可能已调整）以及尚未保存的原始字节修改
+// 角色属性（Data）的修改在保存时才写入，不包含在内
+func (e *SaveEditor) CurrentBytes(offset int64, size int) ([]byte, error) {
+	end := offset + int64(size)
+	if offset < 0 || size < 0 || end > int64(len(e.content)) {
+		return nil, fmt.Errorf("位置超出存档范围: 0x%X+%d", offset, size)
+	}
+
+	data := make([]byte, size)
+	copy(data, e.content[offset:end])
+	members := make([]byte, 2*models.MaxCharacters)
+	for i, char := range e.Characters {
+		overlay(data, offset, char.RecordBytes, char.Position)
+		binary.LittleEndian.PutUint16(members[i*2:], uint16(char.MemberID))
+	}
+	if e.partyChanged {
+		overlay(data, offset, members, models.PartyMemberPosition)
+	}
+	for _, edit := range e.RawEdits {
+		overlay(data, offset, edit.Data, edit.Position)
+	}
+	return data, nil
+}
+
+// overlay 将位于 srcOffset 的 src 中与 dst（位于 dstOffset）重叠的部分复制到 dst
+func overlay(dst []byte, dstOffset int64, src []byte, srcOffset int64) {
+	start := max(dstOffset, srcOffset)
+	end := min(dstOffset+int64(len(dst)), srcOffset+int64(len(src)))
+	if start < end {
+		copy(dst[start-dstOffset:end-dstOffset], src[start-srcOffset:end-srcOffset])
+	}
+}
+
+// CustomValue 读取整数类型的自定义字段
+// field.Offset 为文件内绝对位置，角色字段使用 field.At(角色记录位置)
+func (e *SaveEditor) CustomValue(field layout.Field) (int64, error) {
+	data, err := e.CurrentBytes(field.Offset, field.Size)
+	if err != nil {
+		return 0, err
+	}
+	return field.Value(data)
+}
+
+// CustomText 返回自定义字段的显示文本，整数字段有枚举名称时附带名称
+func (e *SaveEditor) CustomText(field layout.Field) string {
+	data, err := e.CurrentBytes(field.Offset, field.Size)
+	if err != nil {
+		return "越界"
+	}
+	return field.Text(data)
+}
+
+// SetCustomValue 以原始字节修改的方式写入整数类型的自定义字段，值未改变时不记录修改
+func (e *SaveEditor) SetCustomValue(field layout.Field, value int64) error {
+	encoded, err := field.Encode(value)
+	if err != nil {
+		return err
+	}
+	current, err := e.CurrentBytes(field.Offset, field.Size)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, encoded) {
+		return nil
+	}
+	return e.UpdateRawBytes(field.Offset, encoded)
+}
@@ -0,0 +1,182 @@
+// Package discover 在磁盘上查找游戏存档目录（包含 WC.cfg 和成组的存档文件），
+// 用于自动生成存档选择记录
+package discover
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"wcediter/wcsave"
+)
+
+// DefaultMaxDepth 默认的最大搜索深度（相对于搜索目录）
+const DefaultMaxDepth = 6
+
+// Options 搜索选项
+type Options struct {
+	MaxDepth int              // 最大搜索深度，小于1时使用 DefaultMaxDepth
+	Visit    func(dir string) // 进入每个目录时调用，用于显示进度，可为空
+}
+
+// Result 找到的游戏目录
+type Result struct {
+	Dir      string
+	Variants []string // 目录中存在的存档版本前缀
+}
+
+// Record 可添加为选择记录的存档
+type Record struct {
+	Tag  string // 自动生成的标签，例如“无名原版-风云”
+	Path string // 该版本的 0 号存档文件路径
+}
+
+// skipDirs 不进入的目录（小写），多为系统目录或体积很大的开发目录
+var skipDirs = map[string]bool{
+	"$recycle.bin":              true,
+	"system volume information": true,
+	"windows":                   true,
+	"node_modules":              true,
+}
+
+// DefaultRoots 返回默认的搜索目录：用户主目录、Wine 的 C 盘，以及已挂载的磁盘
+func DefaultRoots() []string {
+	var roots []string
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, home, filepath.Join(home, ".wine", "drive_c"))
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		for letter := 'C'; letter <= 'Z'; letter++ {
+			roots = append(roots, string(letter)+`:\`)
+		}
+	case "darwin":
+		roots = append(roots, mounted("/Volumes")...)
+	default:
+		roots = append(roots, mounted("/mnt")...)
+		roots = append(roots, mounted("/media")...)
+		roots = append(roots, mounted("/run/media")...)
+		if user := os.Getenv("USER"); user != "" {
+			roots = append(roots, mounted(filepath.Join("/media", user))...)
+			roots = append(roots, mounted(filepath.Join("/run/media", user))...)
+		}
+	}
+
+	existing := roots[:0]
+	for _, root := range roots {
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			existing = append(existing, root)
+		}
+	}
+	return existing
+}
+
+// mounted 返回挂载点目录下的子目录
+func mounted(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	dirs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return dirs
+}
+
+// Scan 在 roots 中查找游戏存档目录，每找到一个即调用 found
+// ctx 取消时停止搜索并返回 ctx.Err()；无法读取的目录直接跳过
+func Scan(ctx context.Context, roots []string, opts Options, found func(Result)) error {
+	maxDepth := opts.MaxDepth
+	if maxDepth < 1 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	// 搜索目录可能相互包含，同一目录只检查一次
+	visited := make(map[string]bool)
+	for _, root := range roots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil || !entry.IsDir() {
+				return nil
+			}
+			if path != root && skip(entry.Name()) {
+				return filepath.SkipDir
+			}
+			if visited[path] {
+				return filepath.SkipDir
+			}
+			visited[path] = true
+
+			if opts.Visit != nil {
+				opts.Visit(path)
+			}
+			if result, ok := Check(path); ok {
+				found(result)
+			}
+			if depth(root, path) >= maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check 检查目录是否为游戏存档目录：包含 WC.cfg，且至少有一个版本的存档文件
+func Check(dir string) (Result, bool) {
+	if info, err := os.Stat(filepath.Join(dir, wcsave.ConfigFileName)); err != nil || info.IsDir() {
+		return Result{}, false
+	}
+	gameDir, err := wcsave.OpenGameDir(dir)
+	if err != nil || len(gameDir.Sets) == 0 {
+		return Result{}, false
+	}
+	return Result{Dir: dir, Variants: gameDir.VariantNames()}, true
+}
+
+// Records 为目录中的每个版本生成一条选择记录
+func (r Result) Records() []Record {
+	records := make([]Record, 0, len(r.Variants))
+	for _, variant := range r.Variants {
+		label := wcsave.VariantLabels[variant]
+		if label == "" {
+			label = variant
+		}
+		records = append(records, Record{
+			Tag:  fmt.Sprintf("%s-%s", label, filepath.Base(r.Dir)),
+			Path: filepath.Join(r.Dir, wcsave.SlotFileName(variant, 0)),
+		})
+	}
+	return records
+}
+
+// depth 返回 path 相对于 root 的层数
+func depth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// skip 是否跳过该目录：隐藏目录和系统目录
+func skip(name string) bool {
+	return strings.HasPrefix(name, ".") || skipDirs[strings.ToLower(name)]
+}
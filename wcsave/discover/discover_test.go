@@ -0,0 +1,71 @@
+package discover
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"wcediter/wcsave/testsave"
+)
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	writeDir := func(dir string, names ...string) {
+		t.Helper()
+		saves := make(map[string]testsave.Save)
+		for _, name := range names {
+			saves[name] = testsave.Default()
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := testsave.WriteDir(dir, testsave.DefaultConfig(), saves); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	game := filepath.Join(root, "Games", "風雲")
+	writeDir(game, "Save0.dat", "Save1.dat", "Sav00.dat")
+	writeDir(filepath.Join(root, "empty"))                            // 只有 WC.cfg
+	writeDir(filepath.Join(root, ".hidden", "game"), "Save0.dat")     // 隐藏目录
+	writeDir(filepath.Join(root, "a", "b", "c", "deep"), "Sald0.dat") // 超过深度
+
+	var results []Result
+	visited := 0
+	opts := Options{MaxDepth: 3, Visit: func(string) { visited++ }}
+	// 搜索目录重复时只检查一次
+	err := Scan(context.Background(), []string{root, game}, opts, func(result Result) {
+		results = append(results, result)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Dir != game {
+		t.Fatalf("应只找到%s，实际: %+v", game, results)
+	}
+	if visited == 0 {
+		t.Error("应调用 Visit")
+	}
+
+	records := results[0].Records()
+	if len(records) != 2 || records[0].Tag != "原版-風雲" || records[0].Path != filepath.Join(game, "Save0.dat") || records[1].Path != filepath.Join(game, "Sav00.dat") {
+		t.Errorf("选择记录错误: %+v", records)
+	}
+
+	// 默认深度可以找到更深的目录
+	results = nil
+	if err := Scan(context.Background(), []string{root}, Options{}, func(result Result) { results = append(results, result) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("默认深度应找到2个目录，实际: %+v", results)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Scan(ctx, []string{root}, Options{}, func(Result) {}); !errors.Is(err, context.Canceled) {
+		t.Errorf("取消后应返回 context.Canceled，实际: %v", err)
+	}
+}
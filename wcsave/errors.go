@@ -0,0 +1,35 @@
+package wcsave
+
+import (
+	"errors"
+	"io/fs"
+
+	"wcediter/wcsave/models"
+)
+
+// ErrTruncated 存档内容不完整（文件被截断或写入中断）
+var ErrTruncated = models.ErrTruncated
+
+// ErrBadLayout 存档内容与已知布局不一致
+var ErrBadLayout = models.ErrBadLayout
+
+// FieldError 读取字段失败时的错误，包含字段名称和文件偏移
+// 可用 errors.As 取出，再用 errors.Is 判断底层原因
+type FieldError = models.FieldError
+
+// Advice 根据错误类型给出处理建议，无法判断时返回空字符串
+func Advice(err error) string {
+	switch {
+	case errors.Is(err, ErrFileChanged):
+		return "存档已被游戏修改，请重新加载或合并后再保存"
+	case errors.Is(err, ErrTruncated):
+		return "存档不完整，可能是游戏保存时被中断，可使用 repair 命令根据同目录中完好的存档修复"
+	case errors.Is(err, ErrBadLayout):
+		return "存档内容与预期不符，可能已损坏或不是本游戏的存档，可先使用 check 命令检查"
+	case errors.Is(err, fs.ErrNotExist):
+		return "文件不存在，请确认文件路径是否正确"
+	case errors.Is(err, fs.ErrPermission):
+		return "没有访问权限，请确认文件未被设为只读或被其他程序占用"
+	}
+	return ""
+}
@@ -0,0 +1,159 @@
+package wcsave
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"wcediter/wcsave/models"
+)
+
+// ErrFileChanged 存档在读取之后被其他程序（如游戏本身）修改
+var ErrFileChanged = errors.New("存档已被其他程序修改，请重新加载或合并后再保存")
+
+// snapshot 读取存档时的状态，用于检测外部修改和合并
+type snapshot struct {
+	path       string
+	sum        [sha256.Size]byte
+	characters []models.CharacterData
+	money      int32
+	position   [3]int32
+}
+
+// takeSnapshot 记录文件内容的摘要和编辑器中的数据
+func (e *SaveEditor) takeSnapshot(filePath string, content []byte) {
+	characters := make([]models.CharacterData, len(e.Characters))
+	for i, char := range e.Characters {
+		characters[i] = char.Data
+	}
+	e.loaded = &snapshot{
+		path:       filePath,
+		sum:        sha256.Sum256(content),
+		characters: characters,
+		money:      e.MoneyInfo.Value,
+		position:   [3]int32{e.PositionInfo.MapID, e.PositionInfo.X, e.PositionInfo.Y},
+	}
+}
+
+// fileSum 计算文件内容的摘要
+func fileSum(filePath string) ([sha256.Size]byte, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(content), nil
+}
+
+// samePath 判断两个路径是否指向同一文件
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// LoadedPath 返回编辑器读取的存档路径，未读取时为空
+func (e *SaveEditor) LoadedPath() string {
+	if e.loaded == nil {
+		return ""
+	}
+	return e.loaded.path
+}
+
+// ExternallyModified 判断读取后存档文件是否被其他程序修改
+// 编辑器自身保存后会更新记录，因此自己的写入不会被视为外部修改
+func (e *SaveEditor) ExternallyModified() (bool, error) {
+	if e.loaded == nil {
+		return false, nil
+	}
+	sum, err := fileSum(e.loaded.path)
+	if err != nil {
+		return false, err
+	}
+	return sum != e.loaded.sum, nil
+}
+
+// checkNotModified 在写入读取过的存档前确认其未被外部修改
+func (e *SaveEditor) checkNotModified(paths ...string) error {
+	if e.loaded == nil {
+		return nil
+	}
+	for _, path := range paths {
+		if !samePath(path, e.loaded.path) {
+			continue
+		}
+		modified, err := e.ExternallyModified()
+		if err != nil {
+			return err
+		}
+		if modified {
+			return ErrFileChanged
+		}
+		return nil
+	}
+	return nil
+}
+
+// Merge 重新读取存档，并将读取后在编辑器中做出的修改重新应用到最新数据上
+// 返回双方都修改过且值不同的字段（以编辑器中的值为准）
+// 调整过队伍成员或有原始字节修改时无法合并，需要重新加载
+func (e *SaveEditor) Merge() ([]string, error) {
+	if e.loaded == nil {
+		return nil, fmt.Errorf("编辑器没有读取过存档")
+	}
+	if e.partyChanged {
+		return nil, fmt.Errorf("队伍成员已调整，无法合并，请重新加载")
+	}
+	if len(e.RawEdits) > 0 {
+		return nil, fmt.Errorf("存在原始字节修改，无法合并，请重新加载")
+	}
+
+	latest := NewSaveEditor()
+	if err := latest.ReadSave(e.loaded.path); err != nil {
+		return nil, err
+	}
+	base := e.loaded
+	conflicts := make([]string, 0)
+
+	// 角色按名字和序号对应，队伍成员被游戏改变时只合并仍存在的角色
+	for i, char := range e.Characters {
+		if i >= len(base.characters) || i >= len(latest.Characters) || latest.Characters[i].Name != char.Name {
+			continue
+		}
+		baseValue := reflect.ValueOf(base.characters[i])
+		editValue := reflect.ValueOf(char.Data)
+		latestValue := reflect.ValueOf(&latest.Characters[i].Data).Elem()
+		for f := 0; f < editValue.NumField(); f++ {
+			if editValue.Field(f).Int() == baseValue.Field(f).Int() {
+				continue
+			}
+			if latestValue.Field(f).Int() != baseValue.Field(f).Int() && latestValue.Field(f).Int() != editValue.Field(f).Int() {
+				conflicts = append(conflicts, fmt.Sprintf("%s.%s", char.Name, editValue.Type().Field(f).Name))
+			}
+			latestValue.Field(f).SetInt(editValue.Field(f).Int())
+		}
+	}
+
+	if e.MoneyInfo.Value != base.money {
+		if latest.MoneyInfo.Value != base.money && latest.MoneyInfo.Value != e.MoneyInfo.Value {
+			conflicts = append(conflicts, "银两")
+		}
+		latest.MoneyInfo.Value = e.MoneyInfo.Value
+	}
+
+	position := [3]int32{e.PositionInfo.MapID, e.PositionInfo.X, e.PositionInfo.Y}
+	if position != base.position {
+		latestPosition := [3]int32{latest.PositionInfo.MapID, latest.PositionInfo.X, latest.PositionInfo.Y}
+		if latestPosition != base.position && latestPosition != position {
+			conflicts = append(conflicts, "位置")
+		}
+		latest.UpdatePosition(position[0], position[1], position[2])
+	}
+
+	// 进度信息不属于存档文件，保留编辑器中已读取的内容
+	latest.ProgressInfos = e.ProgressInfos
+	*e = *latest
+	return conflicts, nil
+}
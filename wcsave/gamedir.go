@@ -0,0 +1,276 @@
+package wcsave
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wcediter/wcsave/models"
+	"wcediter/wcsave/reader"
+)
+
+// ConfigFileName 进度配置文件名，与存档文件位于同一目录
+const ConfigFileName = "WC.cfg"
+
+// SlotCount 每个版本的存档文件数（xxx0.dat 到 xxx5.dat），1~5 号对应 WC.cfg 中的五个进度
+const SlotCount = 6
+
+// Variants 已知的存档版本前缀：Save（原版、无名简单版）、Sald（无名原版）、Sav0（无名困难版）
+var Variants = []string{"Save", "Sald", "Sav0"}
+
+// VariantLabels 各版本前缀的显示名称
+var VariantLabels = map[string]string{
+	"Save": "原版",
+	"Sald": "无名原版",
+	"Sav0": "无名困难版",
+}
+
+// GameDir 游戏目录，将 WC.cfg 与各版本的存档文件关联起来
+type GameDir struct {
+	Path      string
+	Progress  []models.ProgressInfo // WC.cfg 中的进度信息，读取失败时为空
+	ConfigErr error                 // 读取 WC.cfg 的错误，文件不存在时为 os.ErrNotExist
+	Sets      []*SlotSet            // 目录中存在存档文件的已知版本，按 Variants 的顺序排列
+}
+
+// SlotSet 同一版本的一组存档文件
+type SlotSet struct {
+	Dir     *GameDir
+	Variant string // 文件名前缀，例如 Sav0
+}
+
+// Slot 单个存档文件及其对应的进度
+type Slot struct {
+	Set         *SlotSet
+	Index       int    // 文件名末尾的编号（0~5）
+	Path        string // 存档文件路径
+	Progress    models.ProgressInfo
+	HasProgress bool        // 是否有对应的进度（1~5 号且 WC.cfg 读取成功）
+	Editor      *SaveEditor // 已读取的存档
+}
+
+// SlotSummary 存档概况，用于不打开编辑器时显示各存档的内容
+type SlotSummary struct {
+	Index       int
+	Path        string
+	ModTime     time.Time // 文件修改时间
+	Characters  []models.CharacterInfo
+	Money       int32
+	Position    models.PositionInfo
+	Progress    models.ProgressInfo
+	HasProgress bool
+	Err         error // 文件不存在或读取失败的原因
+}
+
+// SlotFileName 返回版本中指定编号的存档文件名，例如 Sav0 的 3 号为 Sav03.dat
+func SlotFileName(variant string, index int) string {
+	return fmt.Sprintf("%s%d.dat", variant, index)
+}
+
+// ParseSlotFile 从存档文件名中解析版本前缀和编号，例如 Sav03.dat 为 Sav0 和 3
+// 前缀不限于已知版本，以便支持改名的存档
+func ParseSlotFile(filePath string) (string, int, bool) {
+	name := filepath.Base(filePath)
+	ext := filepath.Ext(name)
+	if !strings.EqualFold(ext, ".dat") {
+		return "", 0, false
+	}
+	stem := strings.TrimSuffix(name, ext)
+	if len(stem) < 2 {
+		return "", 0, false
+	}
+	index, err := strconv.Atoi(stem[len(stem)-1:])
+	if err != nil || index >= SlotCount {
+		return "", 0, false
+	}
+	return stem[:len(stem)-1], index, true
+}
+
+// OpenGameDir 打开游戏目录，读取 WC.cfg 并查找各版本的存档文件
+// WC.cfg 不存在或读取失败时不返回错误，记录在 ConfigErr 中
+func OpenGameDir(dir string) (*GameDir, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s不是目录", dir)
+	}
+
+	g := &GameDir{Path: dir}
+	g.Progress, g.ConfigErr = NewSaveEditor().ReadProgress(g.ConfigPath())
+
+	for _, variant := range Variants {
+		set := g.Set(variant)
+		if len(set.Indexes()) > 0 {
+			g.Sets = append(g.Sets, set)
+		}
+	}
+	return g, nil
+}
+
+// OpenSlotFile 打开存档文件所在的游戏目录，返回目录和该文件所属的版本
+func OpenSlotFile(filePath string) (*GameDir, *SlotSet, int, error) {
+	variant, index, ok := ParseSlotFile(filePath)
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("无法识别的存档文件名: %s，应为 前缀+编号(0~%d).dat", filepath.Base(filePath), SlotCount-1)
+	}
+	g, err := OpenGameDir(filepath.Dir(filePath))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return g, g.Set(variant), index, nil
+}
+
+// ConfigPath 返回 WC.cfg 的路径
+func (g *GameDir) ConfigPath() string {
+	return filepath.Join(g.Path, ConfigFileName)
+}
+
+// VariantNames 返回目录中存在的版本前缀
+func (g *GameDir) VariantNames() []string {
+	names := make([]string, 0, len(g.Sets))
+	for _, set := range g.Sets {
+		names = append(names, set.Variant)
+	}
+	return names
+}
+
+// Set 返回指定前缀的存档组，前缀不必是已知版本，对应文件也不必存在
+func (g *GameDir) Set(variant string) *SlotSet {
+	for _, set := range g.Sets {
+		if set.Variant == variant {
+			return set
+		}
+	}
+	return &SlotSet{Dir: g, Variant: variant}
+}
+
+// ProgressFor 返回存档编号对应的进度，0 号存档没有进度
+func (g *GameDir) ProgressFor(index int) (models.ProgressInfo, bool) {
+	if index < 1 || index > len(g.Progress) {
+		return models.ProgressInfo{}, false
+	}
+	return g.Progress[index-1], true
+}
+
+// SlotPath 返回指定编号的存档文件路径
+func (s *SlotSet) SlotPath(index int) string {
+	return filepath.Join(s.Dir.Path, SlotFileName(s.Variant, index))
+}
+
+// Indexes 返回存在的存档文件编号
+func (s *SlotSet) Indexes() []int {
+	indexes := make([]int, 0, SlotCount)
+	for i := 0; i < SlotCount; i++ {
+		if info, err := os.Stat(s.SlotPath(i)); err == nil && !info.IsDir() {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// Slot 读取指定编号的存档，并关联 WC.cfg 中对应的进度
+func (s *SlotSet) Slot(index int) (*Slot, error) {
+	if index < 0 || index >= SlotCount {
+		return nil, fmt.Errorf("无效的存档编号: %d", index)
+	}
+
+	slot := &Slot{Set: s, Index: index, Path: s.SlotPath(index)}
+	slot.Progress, slot.HasProgress = s.Dir.ProgressFor(index)
+
+	slot.Editor = NewSaveEditor()
+	if err := slot.Editor.ReadSave(slot.Path); err != nil {
+		return slot, err
+	}
+	if s.Dir.ConfigErr == nil {
+		slot.Editor.ProgressInfos = append([]models.ProgressInfo(nil), s.Dir.Progress...)
+	}
+	return slot, nil
+}
+
+// Summaries 并行读取指定编号的存档概况，结果与 indexes 的顺序一致
+func (s *SlotSet) Summaries(indexes []int) []SlotSummary {
+	summaries := make([]SlotSummary, len(indexes))
+	var wg sync.WaitGroup
+	for i, index := range indexes {
+		wg.Add(1)
+		go func(i, index int) {
+			defer wg.Done()
+			summaries[i] = s.summary(index)
+		}(i, index)
+	}
+	wg.Wait()
+	return summaries
+}
+
+// summary 读取单个存档的概况，只读取队伍、银两和位置，不检查整体结构
+func (s *SlotSet) summary(index int) SlotSummary {
+	summary := SlotSummary{Index: index, Path: s.SlotPath(index)}
+	summary.Progress, summary.HasProgress = s.Dir.ProgressFor(index)
+
+	info, err := os.Stat(summary.Path)
+	if err != nil {
+		summary.Err = err
+		return summary
+	}
+	summary.ModTime = info.ModTime()
+
+	content, err := os.ReadFile(summary.Path)
+	if err != nil {
+		summary.Err = err
+		return summary
+	}
+	r := bytes.NewReader(content)
+	if summary.Characters, err = reader.ReadCharacters(r); err != nil {
+		summary.Err = err
+		return summary
+	}
+	moneyInfo, err := reader.ReadMoneyData(r, models.MoneyPosition)
+	if err != nil {
+		summary.Err = err
+		return summary
+	}
+	summary.Money = moneyInfo.Value
+	if summary.Position, err = reader.ReadPosition(r, models.MapPosition); err != nil {
+		summary.Err = err
+	}
+	return summary
+}
+
+// ProgressIndex 返回对应的进度索引（0~4），0 号存档返回 -1
+func (s *Slot) ProgressIndex() int {
+	return s.Index - 1
+}
+
+// UpdateProgressLocation 修改 WC.cfg 中该存档对应进度的位置编号（进度选择界面显示的位置名称），返回备份文件路径
+// locationID 为位置名称表的下标，与存档中的地图编号无关；WC.cfg 中该进度与读取时不同时返回 ErrFileChanged
+func (s *Slot) UpdateProgressLocation(locationID int) (string, error) {
+	if !s.HasProgress {
+		return "", fmt.Errorf("%d号存档没有对应的进度", s.Index)
+	}
+	index := s.ProgressIndex()
+	cfgPath := s.Set.Dir.ConfigPath()
+	progressInfos, err := s.Editor.ReadProgress(cfgPath)
+	if err != nil {
+		return "", err
+	}
+	if progressInfos[index] != s.Progress {
+		return "", ErrFileChanged
+	}
+
+	backupPath, err := s.Editor.UpdateProgressLocation(cfgPath, index, locationID)
+	if err != nil {
+		return backupPath, err
+	}
+	s.Progress = s.Editor.ProgressInfos[index]
+	if index < len(s.Set.Dir.Progress) {
+		s.Set.Dir.Progress[index] = s.Progress
+	}
+	return backupPath, nil
+}
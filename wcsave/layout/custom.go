@@ -0,0 +1,350 @@
+package layout
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"wcediter/wcsave/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefinitionFiles 在配置文件所在目录中查找的自定义布局文件，按顺序使用第一个存在的文件
+var DefinitionFiles = []string{"wcediter.layout.yaml", "wcediter.layout.yml", "wcediter.layout.json"}
+
+// Definition 自定义布局文件中的一个字段
+//
+// 示例（YAML，JSON 格式的字段名相同）:
+//
+//	fields:
+//	  - name: Kungfu
+//	    label: 武功
+//	    scope: character   # character: 偏移相对于角色记录; file: 文件内绝对偏移
+//	    offset: 44
+//	    type: uint8        # int8 uint8 int16 uint16 int32 bytes big5
+//	    enum: {0: 无, 1: 降龙十八掌}
+//	  - name: Flags
+//	    scope: file
+//	    offset: 0x100
+//	    type: bytes
+//	    size: 16
+type Definition struct {
+	Name   string            `yaml:"name" json:"name"`
+	Label  string            `yaml:"label" json:"label"`
+	Scope  string            `yaml:"scope" json:"scope"`
+	Offset int64             `yaml:"offset" json:"offset"`
+	Size   int               `yaml:"size" json:"size"` // 整数类型可省略
+	Type   string            `yaml:"type" json:"type"`
+	Enum   map[string]string `yaml:"enum" json:"enum"` // 键为数值，JSON 中需写成字符串
+}
+
+// Custom 用户定义的附加字段
+type Custom struct {
+	Character []Field // 偏移相对于角色记录起始位置
+	File      []Field // 偏移为文件内绝对位置
+}
+
+// Extra 当前生效的自定义字段，由 Register 设置
+var Extra Custom
+
+// typeNames 定义文件中的类型名称及对应的字节数（0 表示由 size 指定）
+var typeNames = map[string]struct {
+	fieldType FieldType
+	size      int
+}{
+	"int8":   {TypeInt8, 1},
+	"uint8":  {TypeUint8, 1},
+	"int16":  {TypeInt16, 2},
+	"uint16": {TypeUint16, 2},
+	"int32":  {TypeInt32, 4},
+	"bytes":  {TypeBytes, 0},
+	"big5":   {TypeBig5, 0},
+}
+
+// Register 设置当前生效的自定义字段
+func Register(custom Custom) {
+	Extra = custom
+}
+
+// AllCharacterFields 返回内置和自定义的角色字段
+func AllCharacterFields() []Field {
+	fields := make([]Field, 0, len(CharacterFields)+len(Extra.Character))
+	fields = append(fields, CharacterFields...)
+	return append(fields, Extra.Character...)
+}
+
+// LoadBeside 在配置文件所在目录中查找并注册自定义布局，返回读取的文件路径
+// 没有自定义布局文件时返回空字符串并清空已注册的字段
+func LoadBeside(configFile string) (string, error) {
+	dir := filepath.Dir(configFile)
+	for _, name := range DefinitionFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		custom, err := LoadDefinitions(path)
+		if err != nil {
+			return path, err
+		}
+		Register(custom)
+		return path, nil
+	}
+	Register(Custom{})
+	return "", nil
+}
+
+// LoadDefinitions 读取自定义布局文件（YAML 或 JSON）
+func LoadDefinitions(path string) (Custom, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Custom{}, err
+	}
+	custom, err := ParseDefinitions(content)
+	if err != nil {
+		return Custom{}, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return custom, nil
+}
+
+// ParseDefinitions 解析并检查自定义布局内容
+// 字段不能与内置字段或彼此重叠，文件级字段不能位于角色记录区内
+func ParseDefinitions(content []byte) (Custom, error) {
+	var file struct {
+		Fields []Definition `yaml:"fields"`
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return Custom{}, fmt.Errorf("格式错误: %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, field := range CharacterFields {
+		names[field.Name] = true
+	}
+	for _, name := range []string{"PartyCount", "Money", "MapID", "X", "Y"} {
+		names[name] = true
+	}
+
+	var custom Custom
+	for i, def := range file.Fields {
+		field, err := def.field()
+		if err != nil {
+			return Custom{}, fmt.Errorf("第%d个字段 %s: %v", i+1, def.Name, err)
+		}
+		if names[field.Name] {
+			return Custom{}, fmt.Errorf("第%d个字段: 名称 %s 已被使用", i+1, field.Name)
+		}
+		names[field.Name] = true
+
+		switch def.Scope {
+		case "character", "":
+			if field.Offset < 0 || field.Offset+int64(field.Size) > models.CharacterRecordSize {
+				return Custom{}, fmt.Errorf("字段 %s 超出角色记录范围（0~%d）", field.Name, models.CharacterRecordSize)
+			}
+			if other, ok := overlapping(field, append(append([]Field(nil), CharacterFields...), custom.Character...)); ok {
+				return Custom{}, fmt.Errorf("字段 %s 与 %s 重叠", field.Name, other.Name)
+			}
+			custom.Character = append(custom.Character, field)
+		case "file":
+			if field.Offset < 0 || field.Offset+int64(field.Size) > models.SaveFileSize {
+				return Custom{}, fmt.Errorf("字段 %s 超出存档范围（0~%d）", field.Name, models.SaveFileSize)
+			}
+			if other, ok := overlapping(field, append(builtinFileFields(), custom.File...)); ok {
+				return Custom{}, fmt.Errorf("字段 %s 与 %s 重叠", field.Name, other.Name)
+			}
+			custom.File = append(custom.File, field)
+		default:
+			return Custom{}, fmt.Errorf("字段 %s 的 scope 应为 character 或 file: %s", field.Name, def.Scope)
+		}
+	}
+	return custom, nil
+}
+
+// field 将定义转换为字段
+func (d Definition) field() (Field, error) {
+	if d.Name == "" {
+		return Field{}, fmt.Errorf("缺少 name")
+	}
+	typeInfo, ok := typeNames[strings.ToLower(d.Type)]
+	if !ok {
+		return Field{}, fmt.Errorf("未知的类型: %q", d.Type)
+	}
+	size := typeInfo.size
+	if size == 0 {
+		if d.Size <= 0 {
+			return Field{}, fmt.Errorf("类型 %s 需要指定 size", d.Type)
+		}
+		size = d.Size
+	} else if d.Size != 0 && d.Size != size {
+		return Field{}, fmt.Errorf("类型 %s 的 size 应为%d", d.Type, size)
+	}
+	if len(d.Enum) > 0 && !typeInfo.fieldType.Integer() {
+		return Field{}, fmt.Errorf("只有整数类型可以定义 enum")
+	}
+
+	var enum map[int64]string
+	if len(d.Enum) > 0 {
+		enum = make(map[int64]string, len(d.Enum))
+		for key, name := range d.Enum {
+			value, err := strconv.ParseInt(key, 0, 64)
+			if err != nil {
+				return Field{}, fmt.Errorf("enum 的键应为数值: %q", key)
+			}
+			enum[value] = name
+		}
+	}
+
+	label := d.Label
+	if label == "" {
+		label = d.Name
+	}
+	return Field{Name: d.Name, Label: label, Offset: d.Offset, Size: size, Type: typeInfo.fieldType, Enum: enum}, nil
+}
+
+// builtinFileFields 内置的文件级字段，角色记录区整体视为一个字段
+func builtinFileFields() []Field {
+	return []Field{
+		{Name: "PartyCount", Offset: models.PartyCountPosition, Size: 2},
+		{Name: "Characters", Offset: models.CharacterStartPosition, Size: models.MaxCharacters * models.CharacterRecordSize},
+		{Name: "Money", Offset: models.MoneyPosition, Size: 4},
+		{Name: "MapID", Offset: models.MapPosition, Size: 4},
+		{Name: "X", Offset: models.MapPosition + models.PositionXOffset, Size: 4},
+		{Name: "Y", Offset: models.MapPosition + models.PositionYOffset, Size: 4},
+	}
+}
+
+// overlapping 查找与 field 重叠的字段
+func overlapping(field Field, fields []Field) (Field, bool) {
+	for _, other := range fields {
+		if field.Offset < other.Offset+int64(other.Size) && other.Offset < field.Offset+int64(field.Size) {
+			return other, true
+		}
+	}
+	return Field{}, false
+}
+
+// Integer 是否为整数类型
+func (t FieldType) Integer() bool {
+	switch t {
+	case TypeInt8, TypeUint8, TypeInt16, TypeUint16, TypeInt32:
+		return true
+	}
+	return false
+}
+
+// At 返回偏移加上 base 后的字段，用于将角色字段转换为文件内的绝对位置
+func (f Field) At(base int64) Field {
+	f.Offset += base
+	return f
+}
+
+// Value 将字段的原始字节（长度为 Size）解码为整数
+func (f Field) Value(b []byte) (int64, error) {
+	if len(b) < f.Size {
+		return 0, fmt.Errorf("%s的数据不足%d字节", f.Label, f.Size)
+	}
+	switch f.Type {
+	case TypeInt8:
+		return int64(int8(b[0])), nil
+	case TypeUint8:
+		return int64(b[0]), nil
+	case TypeInt16:
+		return int64(int16(binary.LittleEndian.Uint16(b))), nil
+	case TypeUint16:
+		return int64(binary.LittleEndian.Uint16(b)), nil
+	case TypeInt32:
+		return int64(int32(binary.LittleEndian.Uint32(b))), nil
+	}
+	return 0, fmt.Errorf("%s不是整数字段", f.Label)
+}
+
+// Bounds 返回整数字段可以保存的取值范围
+func (f Field) Bounds() (int64, int64) {
+	switch f.Type {
+	case TypeInt8:
+		return -1 << 7, 1<<7 - 1
+	case TypeUint8:
+		return 0, 1<<8 - 1
+	case TypeInt16:
+		return -1 << 15, 1<<15 - 1
+	case TypeUint16:
+		return 0, 1<<16 - 1
+	default:
+		return -1 << 31, 1<<31 - 1
+	}
+}
+
+// IntValue 将数值四舍五入为 bits 位有符号整数，不是数值或超出范围时返回错误
+// 脚本、预设和接口修改 models.CharacterData、银两等字段时统一使用，不做截断
+func IntValue(number float64, bits int) (int64, error) {
+	if math.IsNaN(number) {
+		return 0, fmt.Errorf("不是数值")
+	}
+	min, max := -int64(1)<<(bits-1), int64(1)<<(bits-1)-1
+	rounded := math.Round(number)
+	if rounded < float64(min) || rounded > float64(max) {
+		return 0, fmt.Errorf("值%g超出范围%d~%d", number, min, max)
+	}
+	return int64(rounded), nil
+}
+
+// Encode 将整数编码为字段的原始字节，超出范围时返回错误
+func (f Field) Encode(value int64) ([]byte, error) {
+	if !f.Type.Integer() {
+		return nil, fmt.Errorf("%s不是整数字段", f.Label)
+	}
+	min, max := f.Bounds()
+	if value < min || value > max {
+		return nil, fmt.Errorf("%s的值%d超出范围%d~%d", f.Label, value, min, max)
+	}
+	b := make([]byte, f.Size)
+	switch f.Size {
+	case 1:
+		b[0] = byte(value)
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(value))
+	case 4:
+		binary.LittleEndian.PutUint32(b, uint32(value))
+	}
+	return b, nil
+}
+
+// Format 返回整数值的显示文本，有枚举名称时附加在后面
+func (f Field) Format(value int64) string {
+	if name, ok := f.Enum[value]; ok {
+		return fmt.Sprintf("%d（%s）", value, name)
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// Parse 解析输入的整数值，接受数字、枚举名称或 Format 的输出
+func (f Field) Parse(text string) (int64, error) {
+	text = strings.TrimSpace(text)
+	if i := strings.Index(text, "（"); i > 0 {
+		text = text[:i]
+	}
+	if value, err := strconv.ParseInt(text, 0, 64); err == nil {
+		return value, nil
+	}
+	for value, name := range f.Enum {
+		if name == text {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("%s的值无效: %q", f.Label, text)
+}
+
+// EnumValues 返回按数值排序的枚举值
+func (f Field) EnumValues() []int64 {
+	values := make([]int64, 0, len(f.Enum))
+	for value := range f.Enum {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values
+}
@@ -0,0 +1,337 @@
+package layout
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"wcediter/wcsave/models"
+)
+
+// Formats 支持导出的模板格式
+var Formats = []string{"imhex", "010", "kaitai"}
+
+// 导出的目标文件
+const (
+	TargetSave   = "save" // SaveN.dat 存档
+	TargetConfig = "cfg"  // WC.cfg 进度文件
+)
+
+// Export 将已知的文件布局导出为十六进制编辑器的模板
+// format 为 imhex（ImHex pattern）、010（010 Editor 模板）或 kaitai（Kaitai Struct .ksy）
+// 已注册的自定义字段（Extra）一并导出
+func Export(w io.Writer, format, target string) error {
+	var fields []Region
+	switch target {
+	case TargetSave:
+		fields = saveTemplateFields()
+	case TargetConfig:
+		fields = configTemplateFields()
+	default:
+		return fmt.Errorf("未知的导出目标: %s（可选 %s、%s）", target, TargetSave, TargetConfig)
+	}
+
+	var b strings.Builder
+	switch format {
+	case "imhex":
+		exportImHex(&b, target, fields)
+	case "010":
+		export010(&b, target, fields)
+	case "kaitai":
+		exportKaitai(&b, target, fields)
+	default:
+		return fmt.Errorf("未知的模板格式: %s（可选 %s）", format, strings.Join(Formats, "、"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// FileExtension 返回模板格式通常使用的扩展名
+func FileExtension(format string) string {
+	switch format {
+	case "imhex":
+		return ".hexpat"
+	case "010":
+		return ".bt"
+	case "kaitai":
+		return ".ksy"
+	}
+	return ".txt"
+}
+
+// saveTemplateFields 存档中的文件级字段，按偏移排序
+// 角色记录区以 KindCharacter 区域表示（Size 为单条记录的字节数），结束标记的偏移取决于队伍人数
+func saveTemplateFields() []Region {
+	fields := []Region{
+		{Kind: KindParty, Field: Field{Name: "PartyCount", Label: "队伍人数", Offset: models.PartyCountPosition, Size: 2, Type: TypeUint16}},
+		{Kind: KindCharacter, Field: Field{Name: "Characters", Label: "角色记录", Offset: models.CharacterStartPosition, Size: models.CharacterRecordSize, Type: TypeBytes}},
+		{Kind: KindTerminator, Field: Field{Name: "Terminator", Label: "结束标记（队伍未满时为0）", Offset: models.CharacterStartPosition, Size: 2, Type: TypeUint16}},
+		{Kind: KindMoney, Field: Field{Name: "Money", Label: "银两", Offset: models.MoneyPosition, Size: 4, Type: TypeInt32}},
+		{Kind: KindPosition, Field: Field{Name: "MapID", Label: "地图编号", Offset: models.MapPosition, Size: 4, Type: TypeInt32}},
+		{Kind: KindPosition, Field: Field{Name: "X", Label: "横坐标", Offset: models.MapPosition + models.PositionXOffset, Size: 4, Type: TypeInt32}},
+		{Kind: KindPosition, Field: Field{Name: "Y", Label: "纵坐标", Offset: models.MapPosition + models.PositionYOffset, Size: 4, Type: TypeInt32}},
+	}
+	for _, field := range Extra.File {
+		fields = append(fields, Region{Kind: KindCustom, Field: field})
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Field.Offset < fields[j].Field.Offset
+	})
+	return fields
+}
+
+// configTemplateFields WC.cfg 中的字段，整数数组的 Size 为整个数组的字节数
+func configTemplateFields() []Region {
+	return []Region{
+		{Kind: KindUnknown, Field: Field{Name: "Settings", Label: "游戏设置标记", Offset: 0, Size: models.ProgressIDPosition, Type: TypeBytes}},
+		{Kind: KindPosition, Field: Field{Name: "ProgressIDs", Label: "各进度对应的存档编号", Offset: models.ProgressIDPosition, Size: models.MaxProgress * 4, Type: TypeInt32}},
+		{Kind: KindPosition, Field: Field{Name: "LocationIDs", Label: "各进度的位置编号", Offset: models.ProgressLocationPosition, Size: models.MaxProgress * 4, Type: TypeInt32}},
+	}
+}
+
+// templateTitle 模板开头的说明
+func templateTitle(target string) string {
+	if target == TargetConfig {
+		return fmt.Sprintf("风云之天下会 WC.cfg（%d字节）", models.ConfigFileSize)
+	}
+	return fmt.Sprintf("风云之天下会存档 SaveN.dat（%d字节）", models.SaveFileSize)
+}
+
+// elementSize 返回字段单个元素的字节数，字节串和字符串按1字节计
+func elementSize(t FieldType) int {
+	switch t {
+	case TypeInt16, TypeUint16:
+		return 2
+	case TypeInt32:
+		return 4
+	}
+	return 1
+}
+
+// count 返回字段包含的元素个数，大于1时导出为数组
+func (f Field) count() int {
+	return f.Size / elementSize(f.Type)
+}
+
+// comment 返回字段的注释文本，包括显示名称和枚举值
+func (f Field) comment() string {
+	text := f.Label
+	if f.Type == TypeBig5 {
+		text += "（Big5）"
+	}
+	if len(f.Enum) > 0 {
+		names := make([]string, 0, len(f.Enum))
+		for _, value := range f.EnumValues() {
+			names = append(names, fmt.Sprintf("%d=%s", value, f.Enum[value]))
+		}
+		text += " [" + strings.Join(names, ", ") + "]"
+	}
+	return text
+}
+
+// identifier 将字段名转换为模板中可用的标识符
+func identifier(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		id = "_" + id
+	}
+	return id
+}
+
+// snakeCase 将字段名转换为 Kaitai Struct 要求的小写下划线标识符，如 MaxHP -> max_hp
+func snakeCase(name string) string {
+	runes := []rune(identifier(name))
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			// 缩写后的复数 s 不拆开，如 ProgressIDs -> progress_ids
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !pluralSuffix(runes, i+1)
+			if prev != '_' && (unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower)) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	id := strings.Trim(b.String(), "_")
+	if id == "" || !unicode.IsLetter(rune(id[0])) {
+		id = "f_" + id
+	}
+	return id
+}
+
+// pluralSuffix 判断 runes[i] 是否为紧跟在大写缩写后的复数 s（其后为单词结尾）
+func pluralSuffix(runes []rune, i int) bool {
+	return runes[i] == 's' && (i+1 == len(runes) || !unicode.IsLower(runes[i+1]))
+}
+
+// imhexTypes ImHex 中各字段类型的名称
+var imhexTypes = map[FieldType]string{
+	TypeInt8: "s8", TypeUint8: "u8", TypeInt16: "s16", TypeUint16: "u16", TypeInt32: "s32",
+	TypeBytes: "u8", TypeBig5: "char",
+}
+
+// imhexDeclaration 返回 ImHex 中的字段声明（不含位置和分号）
+func imhexDeclaration(f Field) string {
+	if f.count() > 1 {
+		return fmt.Sprintf("%s %s[%d]", imhexTypes[f.Type], identifier(f.Name), f.count())
+	}
+	return fmt.Sprintf("%s %s", imhexTypes[f.Type], identifier(f.Name))
+}
+
+// exportImHex 生成 ImHex pattern
+func exportImHex(b *strings.Builder, target string, fields []Region) {
+	fmt.Fprintf(b, "// %s\n// 由 wcediter layout export 生成\n\n#pragma endian little\n\n", templateTitle(target))
+
+	if target == TargetSave {
+		b.WriteString("struct Character {\n")
+		for _, region := range RecordLayout() {
+			fmt.Fprintf(b, "    %s; // +%d %s\n", imhexDeclaration(region.Field), region.Field.Offset, region.Field.comment())
+		}
+		b.WriteString("};\n\n")
+	}
+
+	for _, region := range fields {
+		field := region.Field
+		switch region.Kind {
+		case KindCharacter:
+			fmt.Fprintf(b, "Character %s[PartyCount] @ 0x%X; // %s\n", field.Name, field.Offset, field.comment())
+		case KindTerminator:
+			fmt.Fprintf(b, "if (PartyCount < %d)\n    %s @ 0x%X + PartyCount * %d; // %s\n",
+				models.MaxCharacters, imhexDeclaration(field), field.Offset, models.CharacterRecordSize, field.comment())
+		default:
+			fmt.Fprintf(b, "%s @ 0x%X; // %s\n", imhexDeclaration(field), field.Offset, field.comment())
+		}
+	}
+}
+
+// bt010Types 010 Editor 中各字段类型的名称
+var bt010Types = map[FieldType]string{
+	TypeInt8: "char", TypeUint8: "uchar", TypeInt16: "short", TypeUint16: "ushort", TypeInt32: "int",
+	TypeBytes: "uchar", TypeBig5: "char",
+}
+
+// bt010Declaration 返回 010 Editor 中的字段声明（含注释属性和分号）
+func bt010Declaration(f Field) string {
+	declaration := fmt.Sprintf("%s %s", bt010Types[f.Type], identifier(f.Name))
+	if f.count() > 1 {
+		declaration += fmt.Sprintf("[%d]", f.count())
+	}
+	if f.Label != "" {
+		declaration += fmt.Sprintf(" <comment=%s>", strconv.Quote(f.comment()))
+	}
+	return declaration + ";"
+}
+
+// export010 生成 010 Editor 模板（.bt）
+func export010(b *strings.Builder, target string, fields []Region) {
+	fmt.Fprintf(b, "// %s\n// 由 wcediter layout export 生成\n\nLittleEndian();\n\n", templateTitle(target))
+
+	if target == TargetSave {
+		b.WriteString("typedef struct {\n")
+		for _, region := range RecordLayout() {
+			fmt.Fprintf(b, "    %s\n", bt010Declaration(region.Field))
+		}
+		b.WriteString("} Character;\n\n")
+	}
+
+	for _, region := range fields {
+		field := region.Field
+		switch region.Kind {
+		case KindCharacter:
+			fmt.Fprintf(b, "FSeek(0x%X);\nif (PartyCount > 0)\n    Character %s[PartyCount] <comment=%s>;\n", field.Offset, field.Name, strconv.Quote(field.comment()))
+		case KindTerminator:
+			// 紧接在最后一条角色记录之后
+			fmt.Fprintf(b, "FSeek(0x%X + PartyCount * %d);\nif (PartyCount < %d)\n    %s\n",
+				field.Offset, models.CharacterRecordSize, models.MaxCharacters, bt010Declaration(field))
+		default:
+			fmt.Fprintf(b, "FSeek(0x%X);\n%s\n", field.Offset, bt010Declaration(field))
+		}
+	}
+}
+
+// kaitaiTypes Kaitai Struct 中各整数类型的名称
+var kaitaiTypes = map[FieldType]string{
+	TypeInt8: "s1", TypeUint8: "u1", TypeInt16: "s2", TypeUint16: "u2", TypeInt32: "s4",
+}
+
+// kaitaiAttributes 返回 Kaitai Struct 中描述字段类型的属性
+func kaitaiAttributes(f Field) []string {
+	switch f.Type {
+	case TypeBytes:
+		return []string{fmt.Sprintf("size: %d", f.Size)}
+	case TypeBig5:
+		return []string{"type: str", fmt.Sprintf("size: %d", f.Size)}
+	}
+	attributes := []string{"type: " + kaitaiTypes[f.Type]}
+	if f.count() > 1 {
+		attributes = append(attributes, "repeat: expr", fmt.Sprintf("repeat-expr: %d", f.count()))
+	}
+	return attributes
+}
+
+// writeKaitaiAttributes 按缩进写入属性列表，first 为第一行的前缀（seq 中为 "- "）
+func writeKaitaiAttributes(b *strings.Builder, indent, first string, attributes []string) {
+	for i, attribute := range attributes {
+		if i == 0 {
+			fmt.Fprintf(b, "%s%s%s\n", indent, first, attribute)
+		} else {
+			fmt.Fprintf(b, "%s%s%s\n", indent, strings.Repeat(" ", len(first)), attribute)
+		}
+	}
+}
+
+// exportKaitai 生成 Kaitai Struct 描述（.ksy）
+// 字段均位于固定位置，因此文件级字段以 instances 描述
+func exportKaitai(b *strings.Builder, target string, fields []Region) {
+	id := "wc_save"
+	extension := "dat"
+	if target == TargetConfig {
+		id, extension = "wc_cfg", "cfg"
+	}
+	fmt.Fprintf(b, "# %s\n# 由 wcediter layout export 生成\nmeta:\n  id: %s\n  file-extension: %s\n  endian: le\n  encoding: Big5\n", templateTitle(target), id, extension)
+
+	b.WriteString("instances:\n")
+	for _, region := range fields {
+		field := region.Field
+		fmt.Fprintf(b, "  %s:\n", snakeCase(field.Name))
+		var attributes []string
+		switch region.Kind {
+		case KindCharacter:
+			attributes = []string{
+				fmt.Sprintf("pos: 0x%X", field.Offset),
+				"type: character",
+				"repeat: expr",
+				"repeat-expr: party_count",
+			}
+		case KindTerminator:
+			attributes = append([]string{fmt.Sprintf("pos: 0x%X + party_count * %d", field.Offset, models.CharacterRecordSize)}, kaitaiAttributes(field)...)
+			attributes = append(attributes, fmt.Sprintf("if: party_count < %d", models.MaxCharacters))
+		default:
+			attributes = append([]string{fmt.Sprintf("pos: 0x%X", field.Offset)}, kaitaiAttributes(field)...)
+		}
+		attributes = append(attributes, "doc: "+strconv.Quote(field.comment()))
+		writeKaitaiAttributes(b, "    ", "", attributes)
+	}
+
+	if target == TargetSave {
+		b.WriteString("types:\n  character:\n    seq:\n")
+		for _, region := range RecordLayout() {
+			field := region.Field
+			attributes := append([]string{"id: " + snakeCase(field.Name)}, kaitaiAttributes(field)...)
+			attributes = append(attributes, "doc: "+strconv.Quote(fmt.Sprintf("+%d %s", field.Offset, field.comment())))
+			writeKaitaiAttributes(b, "      ", "- ", attributes)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package layout
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"wcediter/wcsave/models"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// FieldType 字段类型
+type FieldType int
+
+const (
+	TypeBytes  FieldType = iota // 原始字节
+	TypeInt16                   // 2字节小端整数
+	TypeInt32                   // 4字节小端整数
+	TypeBig5                    // Big5编码字符串
+	TypeInt8                    // 1字节有符号整数
+	TypeUint8                   // 1字节无符号整数
+	TypeUint16                  // 2字节小端无符号整数
+)
+
+// Field 字段定义
+type Field struct {
+	Name   string           // 字段名（与 models.CharacterData 的字段名一致）
+	Label  string           // 显示名称
+	Offset int64            // 偏移（在字段表中为相对结构起始位置，在区域中为文件内绝对位置）
+	Size   int              // 字节数
+	Type   FieldType        // 字段类型
+	Enum   map[int64]string // 整数值对应的名称，仅自定义字段使用
+}
+
+// CharacterFields 角色记录中已知的字段，偏移相对于角色记录起始位置
+var CharacterFields = []Field{
+	{Name: "Name", Label: "名字", Offset: 0, Size: 6, Type: TypeBig5},
+	{Name: "CurrentExp", Label: "当前经验值", Offset: 8, Size: 4, Type: TypeInt32},
+	{Name: "NextLevelExp", Label: "升级经验值", Offset: 12, Size: 4, Type: TypeInt32},
+	{Name: "MaxHP", Label: "最大生命值", Offset: 16, Size: 4, Type: TypeInt32},
+	{Name: "MaxMP", Label: "最大内力值", Offset: 20, Size: 4, Type: TypeInt32},
+	{Name: "CurrentHP", Label: "当前生命值", Offset: 24, Size: 4, Type: TypeInt32},
+	{Name: "CurrentMP", Label: "当前内力值", Offset: 28, Size: 4, Type: TypeInt32},
+	{Name: "Strength", Label: "力量", Offset: 32, Size: 2, Type: TypeInt16},
+	{Name: "Reaction", Label: "反应", Offset: 34, Size: 2, Type: TypeInt16},
+	{Name: "Constitution", Label: "体质", Offset: 36, Size: 2, Type: TypeInt16},
+	{Name: "Speed", Label: "速度", Offset: 38, Size: 2, Type: TypeInt16},
+	{Name: "Attack", Label: "攻击", Offset: 40, Size: 2, Type: TypeInt16},
+	{Name: "Defense", Label: "防御", Offset: 42, Size: 2, Type: TypeInt16},
+	{Name: "Luck", Label: "运气", Offset: 52, Size: 2, Type: TypeInt16},
+	{Name: "Level", Label: "等级", Offset: 70, Size: 2, Type: TypeInt16},
+}
+
+// Kind 区域类别
+type Kind int
+
+const (
+	KindUnknown    Kind = iota // 未知数据
+	KindCharacter              // 角色记录中的已知字段
+	KindGap                    // 角色记录中尚未解析的字节
+	KindTerminator             // 角色记录结束标记
+	KindParty                  // 队伍人数
+	KindMoney                  // 银两
+	KindPosition               // 队伍位置
+	KindCustom                 // 自定义布局文件中的字段
+)
+
+// Region 文件中已标注的区域
+type Region struct {
+	Kind  Kind
+	Group string // 所属结构，例如“角色1 步驚雲”
+	Field Field  // Offset 为文件内绝对位置
+}
+
+// Regions 根据已读取的存档数据生成按位置排序的标注区域
+func Regions(characters []models.CharacterInfo, moneyInfo models.MoneyInfo, positionInfo models.PositionInfo) []Region {
+	regions := []Region{
+		{Kind: KindParty, Group: "队伍", Field: Field{Name: "PartyCount", Label: "队伍人数", Offset: models.PartyCountPosition, Size: 2, Type: TypeInt16}},
+	}
+
+	record := RecordLayout()
+	for i, char := range characters {
+		group := fmt.Sprintf("角色%d %s", i+1, char.Name)
+		for _, region := range record {
+			region.Group = group
+			region.Field = region.Field.At(char.Position)
+			regions = append(regions, region)
+		}
+	}
+
+	// 队伍未满时，下一个记录的首2字节为结束标记
+	if len(characters) < models.MaxCharacters {
+		regions = append(regions, Region{Kind: KindTerminator, Group: "队伍", Field: Field{
+			Name:   "Terminator",
+			Label:  "结束标记",
+			Offset: models.CharacterStartPosition + int64(len(characters)*models.CharacterRecordSize),
+			Size:   2,
+			Type:   TypeBytes,
+		}})
+	}
+
+	if moneyInfo.Position != 0 {
+		regions = append(regions, Region{Kind: KindMoney, Group: "银两", Field: Field{Name: "Money", Label: "银两", Offset: moneyInfo.Position, Size: 4, Type: TypeInt32}})
+	}
+
+	if positionInfo.Position != 0 {
+		regions = append(regions,
+			Region{Kind: KindPosition, Group: "位置", Field: Field{Name: "MapID", Label: "地图编号", Offset: positionInfo.Position, Size: 4, Type: TypeInt32}},
+			Region{Kind: KindPosition, Group: "位置", Field: Field{Name: "X", Label: "横坐标", Offset: positionInfo.Position + models.PositionXOffset, Size: 4, Type: TypeInt32}},
+			Region{Kind: KindPosition, Group: "位置", Field: Field{Name: "Y", Label: "纵坐标", Offset: positionInfo.Position + models.PositionYOffset, Size: 4, Type: TypeInt32}},
+		)
+	}
+
+	for _, field := range Extra.File {
+		regions = append(regions, Region{Kind: KindCustom, Group: "自定义", Field: field})
+	}
+
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].Field.Offset < regions[j].Field.Offset
+	})
+	return regions
+}
+
+// RecordLayout 返回一条角色记录中按偏移排序的全部区域（偏移相对于记录起始位置），
+// 包括内置字段、自定义字段，以及合并为连续空隙的未解析字节
+func RecordLayout() []Region {
+	var regions []Region
+	covered := make([]bool, models.CharacterRecordSize)
+	for i, field := range AllCharacterFields() {
+		kind := KindCharacter
+		if i >= len(CharacterFields) {
+			kind = KindCustom
+		}
+		regions = append(regions, Region{Kind: kind, Field: field})
+		for j := 0; j < field.Size; j++ {
+			covered[int(field.Offset)+j] = true
+		}
+	}
+
+	// 未解析的字节合并为连续的空隙区域
+	for start := 0; start < len(covered); {
+		if covered[start] {
+			start++
+			continue
+		}
+		end := start
+		for end < len(covered) && !covered[end] {
+			end++
+		}
+		regions = append(regions, Region{Kind: KindGap, Field: Field{
+			Name:   fmt.Sprintf("Unknown%d", start),
+			Label:  fmt.Sprintf("未知(+%d)", start),
+			Offset: int64(start),
+			Size:   end - start,
+			Type:   TypeBytes,
+		}})
+		start = end
+	}
+
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].Field.Offset < regions[j].Field.Offset
+	})
+	return regions
+}
+
+// Find 查找包含指定位置的区域，regions 需按位置排序
+func Find(regions []Region, offset int64) (Region, bool) {
+	i := sort.Search(len(regions), func(i int) bool {
+		return regions[i].Field.Offset+int64(regions[i].Field.Size) > offset
+	})
+	if i < len(regions) && regions[i].Field.Offset <= offset {
+		return regions[i], true
+	}
+	return Region{}, false
+}
+
+// Decode 按字段类型解码文件内容中的字段值
+func Decode(field Field, data []byte) string {
+	if field.Offset < 0 || field.Offset+int64(field.Size) > int64(len(data)) {
+		return "越界"
+	}
+	return field.Text(data[field.Offset : field.Offset+int64(field.Size)])
+}
+
+// Text 按字段类型将字段的原始字节转换为显示文本
+func (f Field) Text(b []byte) string {
+	if f.Type.Integer() {
+		value, err := f.Value(b)
+		if err != nil {
+			return fmt.Sprintf("% X", b)
+		}
+		return f.Format(value)
+	}
+
+	switch f.Type {
+	case TypeBig5:
+		utf8Bytes, err := traditionalchinese.Big5.NewDecoder().Bytes(b)
+		if err != nil {
+			return fmt.Sprintf("% X", b)
+		}
+		return strings.TrimRight(string(utf8Bytes), "\x00")
+	default:
+		return fmt.Sprintf("% X", b)
+	}
+}
@@ -0,0 +1,252 @@
+package layout
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"wcediter/wcsave/models"
+)
+
+// 测试Regions和Find函数
+func TestRegionsAndFind(t *testing.T) {
+	characters := []models.CharacterInfo{
+		{Name: "步驚雲", Position: models.CharacterStartPosition},
+	}
+	moneyInfo := models.MoneyInfo{Position: models.MoneyPosition}
+	regions := Regions(characters, moneyInfo, models.PositionInfo{})
+
+	// 角色记录的每个字节都应被标注
+	for offset := int64(models.CharacterStartPosition); offset < models.CharacterStartPosition+models.CharacterRecordSize; offset++ {
+		if _, ok := Find(regions, offset); !ok {
+			t.Fatalf("位置%d未被标注", offset)
+		}
+	}
+
+	region, ok := Find(regions, models.CharacterStartPosition+71)
+	if !ok || region.Field.Name != "Level" || region.Kind != KindCharacter {
+		t.Errorf("位置%d应属于等级字段，实际%+v", models.CharacterStartPosition+71, region)
+	}
+
+	region, ok = Find(regions, models.CharacterStartPosition+45)
+	if !ok || region.Kind != KindGap {
+		t.Errorf("位置%d应属于未知区域，实际%+v", models.CharacterStartPosition+45, region)
+	}
+
+	region, ok = Find(regions, models.CharacterStartPosition+models.CharacterRecordSize)
+	if !ok || region.Kind != KindTerminator {
+		t.Errorf("第二个记录的起始位置应为结束标记，实际%+v", region)
+	}
+
+	region, ok = Find(regions, models.MoneyPosition+3)
+	if !ok || region.Kind != KindMoney {
+		t.Errorf("银两区域查找失败，实际%+v", region)
+	}
+
+	if _, ok := Find(regions, 100); ok {
+		t.Error("位置100不应被标注")
+	}
+}
+
+// 测试Decode函数
+func TestDecode(t *testing.T) {
+	data := make([]byte, 16)
+	binary.LittleEndian.PutUint32(data[0:], 1000)
+	binary.LittleEndian.PutUint16(data[4:], uint16(0xFFFF))
+	copy(data[6:], []byte{0xA8, 0x42, 0xC5, 0xE5, 0xB6, 0xB3})
+
+	if v := Decode(Field{Offset: 0, Size: 4, Type: TypeInt32}, data); v != "1000" {
+		t.Errorf("Int32解码错误，实际%s", v)
+	}
+	if v := Decode(Field{Offset: 4, Size: 2, Type: TypeInt16}, data); v != "-1" {
+		t.Errorf("Int16解码错误，实际%s", v)
+	}
+	if v := Decode(Field{Offset: 6, Size: 6, Type: TypeBig5}, data); v != "步驚雲" {
+		t.Errorf("Big5解码错误，实际%s", v)
+	}
+	if v := Decode(Field{Offset: 14, Size: 4, Type: TypeInt32}, data); v != "越界" {
+		t.Errorf("越界时应返回提示，实际%s", v)
+	}
+}
+
+// 测试解析自定义布局
+func TestParseDefinitions(t *testing.T) {
+	yamlContent := []byte(`
+fields:
+  - name: Kungfu
+    label: 武功
+    scope: character
+    offset: 44
+    type: uint8
+    enum: {0: 无, 1: 降龙十八掌}
+  - name: Flags
+    scope: file
+    offset: 0x100
+    type: bytes
+    size: 16
+`)
+	custom, err := ParseDefinitions(yamlContent)
+	if err != nil {
+		t.Fatalf("ParseDefinitions失败: %v", err)
+	}
+	if len(custom.Character) != 1 || len(custom.File) != 1 {
+		t.Fatalf("字段数量错误: %+v", custom)
+	}
+	kungfu := custom.Character[0]
+	if kungfu.Offset != 44 || kungfu.Size != 1 || kungfu.Type != TypeUint8 || kungfu.Enum[1] != "降龙十八掌" {
+		t.Errorf("角色字段解析错误: %+v", kungfu)
+	}
+	if custom.File[0].Offset != 0x100 || custom.File[0].Label != "Flags" {
+		t.Errorf("文件字段解析错误: %+v", custom.File[0])
+	}
+
+	jsonContent := []byte(`{"fields": [{"name": "Title", "offset": 46, "type": "int16", "enum": {"-1": "无"}}]}`)
+	custom, err = ParseDefinitions(jsonContent)
+	if err != nil {
+		t.Fatalf("解析JSON失败: %v", err)
+	}
+	if len(custom.Character) != 1 || custom.Character[0].Enum[-1] != "无" {
+		t.Errorf("JSON字段解析错误: %+v", custom)
+	}
+
+	invalid := map[string]string{
+		"与内置字段重叠": `{"fields": [{"name": "A", "offset": 33, "type": "uint8"}]}`,
+		"超出记录":    `{"fields": [{"name": "A", "offset": 83, "type": "int16"}]}`,
+		"名称重复":    `{"fields": [{"name": "Level", "offset": 44, "type": "uint8"}]}`,
+		"未知类型":    `{"fields": [{"name": "A", "offset": 44, "type": "float"}]}`,
+		"缺少大小":    `{"fields": [{"name": "A", "offset": 44, "type": "bytes"}]}`,
+		"位于记录区":   `{"fields": [{"name": "A", "scope": "file", "offset": 202620, "type": "uint8"}]}`,
+		"未知范围":    `{"fields": [{"name": "A", "scope": "party", "offset": 44, "type": "uint8"}]}`,
+	}
+	for name, content := range invalid {
+		if _, err := ParseDefinitions([]byte(content)); err == nil {
+			t.Errorf("%s: 预期返回错误", name)
+		}
+	}
+}
+
+// 测试自定义字段出现在标注区域中
+func TestRegionsCustom(t *testing.T) {
+	Register(Custom{
+		Character: []Field{{Name: "Kungfu", Label: "武功", Offset: 44, Size: 1, Type: TypeUint8, Enum: map[int64]string{2: "剑法"}}},
+		File:      []Field{{Name: "Flags", Label: "标记", Offset: 100, Size: 4, Type: TypeBytes}},
+	})
+	defer Register(Custom{})
+
+	characters := []models.CharacterInfo{{Name: "步驚雲", Position: models.CharacterStartPosition}}
+	regions := Regions(characters, models.MoneyInfo{}, models.PositionInfo{})
+
+	region, ok := Find(regions, models.CharacterStartPosition+44)
+	if !ok || region.Kind != KindCustom || region.Field.Name != "Kungfu" {
+		t.Errorf("自定义角色字段未被标注: %+v", region)
+	}
+	if region, ok := Find(regions, models.CharacterStartPosition+45); !ok || region.Kind != KindGap {
+		t.Errorf("自定义字段之后应为未知区域: %+v", region)
+	}
+	if region, ok := Find(regions, 102); !ok || region.Kind != KindCustom {
+		t.Errorf("自定义文件字段未被标注: %+v", region)
+	}
+
+	data := make([]byte, models.CharacterStartPosition+models.CharacterRecordSize)
+	data[models.CharacterStartPosition+44] = 2
+	if v := Decode(region.Field, data); v != "2（剑法）" {
+		t.Errorf("枚举解码错误，实际%s", v)
+	}
+}
+
+// 测试整数字段的编码和解析
+func TestFieldEncodeParse(t *testing.T) {
+	field := Field{Label: "武功", Size: 1, Type: TypeUint8, Enum: map[int64]string{3: "剑法"}}
+	if _, err := field.Encode(256); err == nil {
+		t.Error("超出范围的值应返回错误")
+	}
+	b, err := field.Encode(200)
+	if err != nil || b[0] != 200 {
+		t.Errorf("编码错误: %v %v", b, err)
+	}
+	for _, text := range []string{"3", "剑法", "3（剑法）", "0x3"} {
+		if v, err := field.Parse(text); err != nil || v != 3 {
+			t.Errorf("解析%q错误: %d %v", text, v, err)
+		}
+	}
+	if _, err := field.Parse("拳法"); err == nil {
+		t.Error("未知的枚举名称应返回错误")
+	}
+}
+
+func TestExport(t *testing.T) {
+	Register(Custom{
+		Character: []Field{{Name: "Kungfu", Label: "武功", Offset: 44, Size: 1, Type: TypeUint8, Enum: map[int64]string{1: "降龙十八掌"}}},
+	})
+	defer Register(Custom{})
+
+	start := fmt.Sprintf("0x%X", models.CharacterStartPosition)
+	money := fmt.Sprintf("0x%X", models.MoneyPosition)
+	tests := []struct {
+		format, target string
+		want           []string
+	}{
+		{"imhex", TargetSave, []string{
+			"#pragma endian little",
+			"struct Character {",
+			"u8 Kungfu; // +44 武功 [1=降龙十八掌]",
+			"u8 Unknown72[12];",
+			"Character Characters[PartyCount] @ " + start,
+			"u16 Terminator @ " + start + " + PartyCount * 84",
+			"s32 Money @ " + money,
+		}},
+		{"imhex", TargetConfig, []string{"s32 ProgressIDs[5] @ 0x24;", "s32 LocationIDs[5] @ 0x38;"}},
+		{"010", TargetSave, []string{
+			"LittleEndian();",
+			"char Name[6] <comment=\"名字（Big5）\">;",
+			"} Character;",
+			"FSeek(" + start + " + PartyCount * 84);",
+			"FSeek(" + money + ");\nint Money",
+		}},
+		{"010", TargetConfig, []string{"FSeek(0x38);\nint LocationIDs[5]"}},
+		{"kaitai", TargetSave, []string{
+			"  encoding: Big5",
+			"  party_count:\n    pos: 0x3174E\n    type: u2",
+			"    repeat-expr: party_count",
+			"    if: party_count < 5",
+			"      - id: max_hp\n        type: s4",
+			"      - id: kungfu\n        type: u1",
+		}},
+		{"kaitai", TargetConfig, []string{"  progress_ids:\n    pos: 0x24\n    type: s4\n    repeat: expr\n    repeat-expr: 5"}},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := Export(&b, tt.format, tt.target); err != nil {
+			t.Fatalf("%s/%s: %v", tt.format, tt.target, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s/%s 的输出缺少 %q:\n%s", tt.format, tt.target, want, b.String())
+			}
+		}
+	}
+
+	if err := Export(&strings.Builder{}, "hexfiend", TargetSave); err == nil {
+		t.Error("未知格式应返回错误")
+	}
+	if err := Export(&strings.Builder{}, "imhex", "inventory"); err == nil {
+		t.Error("未知目标应返回错误")
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"MaxHP":        "max_hp",
+		"NextLevelExp": "next_level_exp",
+		"MapID":        "map_id",
+		"ProgressIDs":  "progress_ids",
+		"Unknown44":    "unknown44",
+		"X":            "x",
+		"武功":           "f_",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q，应为 %q", name, got, want)
+		}
+	}
+}
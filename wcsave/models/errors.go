@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTruncated 存档内容不完整，要读取的位置超出了数据末尾
+var ErrTruncated = errors.New("存档数据不完整")
+
+// ErrBadLayout 存档内容与已知的布局不一致（如队伍人数与角色记录不符）
+var ErrBadLayout = errors.New("存档布局与预期不符")
+
+// FieldError 读取某个字段时发生的错误，记录字段名称和所在的文件偏移
+type FieldError struct {
+	Field  string // 字段名称，如 "力量"、"银两"
+	Offset int64  // 字段在文件中的偏移
+	Err    error  // 底层错误，数据不足时为 ErrTruncated
+}
+
+// Error 返回错误描述
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("读取%s失败（偏移0x%X）: %v", e.Field, e.Offset, e.Err)
+}
+
+// Unwrap 返回底层错误，便于 errors.Is 判断错误类型
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
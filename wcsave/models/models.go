@@ -1,5 +1,27 @@
 package models
 
+// 存档布局常量
+const (
+	SaveFileSize           = 205014 // 存档文件大小
+	PartyCountPosition     = 202574 // 队伍人数（2字节）所在位置
+	CharacterStartPosition = 202618 // 第一个角色记录的起始位置
+	CharacterRecordSize    = 84     // 每个角色记录的字节数
+	MaxCharacters          = 5      // 队伍最多角色数
+	PartyMemberPosition    = 203038 // 队伍成员编号表（每人2字节，共5项），与角色记录一一对应
+	MoneyPosition          = 203054 // 银两所在位置
+	MapPosition            = 204658 // 队伍所在地图编号的位置（游戏内部的地图编号，与 WC.cfg 的位置编号不同）
+	PositionXOffset        = 36     // 横坐标相对地图编号的偏移
+	PositionYOffset        = 40     // 纵坐标相对地图编号的偏移
+)
+
+// WC.cfg 布局常量
+const (
+	ConfigFileSize           = 76 // WC.cfg 文件大小
+	ProgressIDPosition       = 36 // 各进度对应的存档编号（int数组）
+	ProgressLocationPosition = 56 // 各进度的位置编号（int数组，位置名称表的下标）
+	MaxProgress              = 5  // 进度数量
+)
+
 // CharacterData 角色属性数据结构
 type CharacterData struct {
 	CurrentExp   int32 // 当前经验值
@@ -38,10 +60,12 @@ type RawByteData struct {
 
 // CharacterInfo 角色信息结构体
 type CharacterInfo struct {
-	Name     string
-	Data     CharacterData
-	RawBytes RawByteData
-	Position int64 // 记录角色数据在文件中的起始位置
+	Name        string
+	Data        CharacterData
+	RawBytes    RawByteData
+	RecordBytes []byte // 完整的角色记录原始字节（84字节）
+	Position    int64  // 记录角色数据在文件中的起始位置
+	MemberID    int16  // 队伍成员编号表中的编号，调整队伍时随角色记录移动
 }
 
 // MoneyInfo 银两信息结构体
@@ -57,3 +81,18 @@ type ProgressInfo struct {
 	LocationID   int    // 位置编号
 	LocationName string // 位置名称
 }
+
+// PositionInfo 队伍当前位置信息结构体
+type PositionInfo struct {
+	MapID    int32  // 地图编号（游戏内部编号，不是位置名称表的下标，没有对应的名称）
+	X        int32  // 横坐标
+	Y        int32  // 纵坐标
+	RawBytes []byte // 原始字节（地图编号4字节 + 坐标8字节）
+	Position int64  // 地图编号在文件中的位置
+}
+
+// RawEdit 原始字节修改
+type RawEdit struct {
+	Position int64  // 修改的起始位置
+	Data     []byte // 写入的字节
+}
@@ -0,0 +1,332 @@
+// Package patch 比较两个存档生成补丁，并将补丁应用到其他存档
+// 布局中已知的字段记录为字段修改，其余位置记录为原始字节修改；
+// 应用前检查目标存档的原内容与补丁记录的一致
+package patch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+)
+
+// Version 补丁格式版本
+const Version = 1
+
+// Extension 补丁文件的扩展名
+const Extension = ".wcpatch"
+
+// ErrConflict 目标存档的原内容与补丁不一致
+var ErrConflict = errors.New("目标存档的原内容与补丁不一致")
+
+// Op 单个修改操作
+type Op struct {
+	Character int    `json:"character,omitempty"` // 角色序号（从1开始），0 表示不属于角色记录
+	Field     string `json:"field,omitempty"`     // 字段名，为空时为原始字节修改
+	Label     string `json:"label,omitempty"`     // 字段的显示名称，仅供阅读
+	Offset    int64  `json:"offset"`              // 文件内位置
+	Old       string `json:"old"`                 // 原内容：整数字段为十进制数值，其余为十六进制字节
+	New       string `json:"new"`                 // 新内容，格式同 Old
+}
+
+// Patch 补丁文件内容
+type Patch struct {
+	Version int  `json:"version"`
+	Ops     []Op `json:"ops"`
+}
+
+// Conflict 原内容不一致的操作
+type Conflict struct {
+	Op      Op
+	Current string // 目标存档中的当前内容，格式同 Op.Old
+}
+
+// Result 应用补丁的结果
+type Result struct {
+	Applied   int        // 应用的操作数
+	Conflicts []Conflict // 原内容不一致的操作，强制应用时仍会写入
+}
+
+// String 返回操作的说明文本，例如“角色1 最大生命值: 100 -> 200”
+func (op Op) String() string {
+	name := fmt.Sprintf("0x%06X", op.Offset)
+	if op.Field != "" {
+		name = op.Label
+		if name == "" {
+			name = op.Field
+		}
+		if op.Character > 0 {
+			name = fmt.Sprintf("角色%d %s", op.Character, name)
+		}
+	}
+	return fmt.Sprintf("%s: %s -> %s", name, op.Old, op.New)
+}
+
+// Create 比较原存档和修改后的存档，生成补丁
+func Create(base, edited []byte) (Patch, error) {
+	if len(base) != len(edited) {
+		return Patch{}, fmt.Errorf("两个存档的大小不同: %d 和 %d 字节", len(base), len(edited))
+	}
+
+	editor := wcsave.NewSaveEditor()
+	if err := editor.ReadSaveFrom(bytes.NewReader(base)); err != nil {
+		return Patch{}, fmt.Errorf("读取原存档失败: %w", err)
+	}
+	regions := layout.Regions(editor.Characters, editor.MoneyInfo, editor.PositionInfo)
+
+	p := Patch{Version: Version, Ops: make([]Op, 0)}
+	for i := int64(0); i < int64(len(base)); {
+		if base[i] == edited[i] {
+			i++
+			continue
+		}
+
+		// 已知字段整体记录为一个字段修改
+		if region, ok := layout.Find(regions, i); ok && fieldRegion(region) {
+			field := region.Field
+			end := field.Offset + int64(field.Size)
+			if field.Offset >= 0 && end <= int64(len(base)) {
+				p.Ops = append(p.Ops, Op{
+					Character: characterIndex(region),
+					Field:     field.Name,
+					Label:     field.Label,
+					Offset:    field.Offset,
+					Old:       encodeValue(field, base[field.Offset:end]),
+					New:       encodeValue(field, edited[field.Offset:end]),
+				})
+				i = end
+				continue
+			}
+		}
+
+		// 其余连续变化的字节记录为一个原始字节修改，遇到已知字段时截断
+		end := i + 1
+		for end < int64(len(base)) && base[end] != edited[end] {
+			if region, ok := layout.Find(regions, end); ok && fieldRegion(region) {
+				break
+			}
+			end++
+		}
+		p.Ops = append(p.Ops, Op{
+			Offset: i,
+			Old:    hex.EncodeToString(base[i:end]),
+			New:    hex.EncodeToString(edited[i:end]),
+		})
+		i = end
+	}
+	return p, nil
+}
+
+// CreateFiles 比较两个存档文件，生成补丁
+func CreateFiles(baseFilePath, editedFilePath string) (Patch, error) {
+	base, err := os.ReadFile(baseFilePath)
+	if err != nil {
+		return Patch{}, err
+	}
+	edited, err := os.ReadFile(editedFilePath)
+	if err != nil {
+		return Patch{}, err
+	}
+	return Create(base, edited)
+}
+
+// Write 以 JSON 格式写出补丁
+func Write(w io.Writer, p Patch) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(p)
+}
+
+// Read 读取 JSON 格式的补丁
+func Read(r io.Reader) (Patch, error) {
+	var p Patch
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return Patch{}, fmt.Errorf("解析补丁失败: %v", err)
+	}
+	if p.Version != Version {
+		return Patch{}, fmt.Errorf("不支持的补丁版本: %d", p.Version)
+	}
+	return p, nil
+}
+
+// Load 读取补丁文件
+func Load(filePath string) (Patch, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Patch{}, err
+	}
+	defer file.Close()
+
+	p, err := Read(file)
+	if err != nil {
+		return Patch{}, fmt.Errorf("%s: %v", filePath, err)
+	}
+	return p, nil
+}
+
+// Apply 检查并将补丁应用到编辑器，保存仍通过 editor.SaveChanges 完成
+// 有原内容不一致的操作时，force 为 false 则不做任何修改并返回 ErrConflict
+func (p Patch) Apply(editor *wcsave.SaveEditor, force bool) (Result, error) {
+	var result Result
+	regions := layout.Regions(editor.Characters, editor.MoneyInfo, editor.PositionInfo)
+
+	// 先检查全部操作，避免只应用了一部分
+	targets := make([]layout.Region, len(p.Ops))
+	for i, op := range p.Ops {
+		region, err := resolve(editor, regions, op)
+		if err != nil {
+			return result, fmt.Errorf("第%d项修改（%s）: %v", i+1, op, err)
+		}
+		targets[i] = region
+
+		current, err := editor.CurrentBytes(region.Field.Offset, region.Field.Size)
+		if err != nil {
+			return result, fmt.Errorf("第%d项修改（%s）: %v", i+1, op, err)
+		}
+		// 已经是新内容的不视为不一致，重复应用同一补丁不会报错
+		if text := encodeValue(region.Field, current); text != op.Old && text != op.New {
+			result.Conflicts = append(result.Conflicts, Conflict{Op: op, Current: text})
+		}
+	}
+	if len(result.Conflicts) > 0 && !force {
+		return result, fmt.Errorf("%w: %d项修改的原内容不同，可使用 --force 强制应用", ErrConflict, len(result.Conflicts))
+	}
+
+	for i, op := range p.Ops {
+		if err := apply(editor, targets[i], op); err != nil {
+			return result, fmt.Errorf("第%d项修改（%s）: %v", i+1, op, err)
+		}
+		result.Applied++
+	}
+	return result, nil
+}
+
+// resolve 在目标存档中找到操作对应的区域，原始字节修改返回只有位置和长度的区域
+func resolve(editor *wcsave.SaveEditor, regions []layout.Region, op Op) (layout.Region, error) {
+	if op.Field == "" {
+		old, err := hex.DecodeString(op.Old)
+		if err != nil {
+			return layout.Region{}, fmt.Errorf("原内容格式错误: %v", err)
+		}
+		data, err := hex.DecodeString(op.New)
+		if err != nil {
+			return layout.Region{}, fmt.Errorf("新内容格式错误: %v", err)
+		}
+		if len(old) != len(data) {
+			return layout.Region{}, fmt.Errorf("原内容与新内容长度不同")
+		}
+		return layout.Region{Field: layout.Field{Offset: op.Offset, Size: len(data), Type: layout.TypeBytes}}, nil
+	}
+
+	if op.Character > len(editor.Characters) {
+		return layout.Region{}, fmt.Errorf("目标存档只有%d个角色", len(editor.Characters))
+	}
+	for _, region := range regions {
+		if region.Field.Name == op.Field && region.Field.Offset == op.Offset && characterIndex(region) == op.Character {
+			return region, nil
+		}
+	}
+	return layout.Region{}, fmt.Errorf("目标存档中没有字段%s，自定义字段需要加载相同的布局文件", op.Field)
+}
+
+// apply 按字段类别通过编辑器写入一个操作
+func apply(editor *wcsave.SaveEditor, region layout.Region, op Op) error {
+	field := region.Field
+	data, err := decodeValue(field, op.New)
+	if err != nil {
+		return err
+	}
+	if !field.Type.Integer() {
+		return editor.UpdateRawBytes(field.Offset, data)
+	}
+	value, err := field.Value(data)
+	if err != nil {
+		return err
+	}
+
+	switch region.Kind {
+	case layout.KindCharacter:
+		// 内置的角色属性通过角色数据写入
+		index := op.Character - 1
+		char := editor.Characters[index]
+		target := reflect.ValueOf(&char.Data).Elem().FieldByName(field.Name)
+		if !target.IsValid() {
+			return editor.UpdateRawBytes(field.Offset, data)
+		}
+		target.SetInt(value)
+		editor.UpdateCharacter(index, char.Data)
+	case layout.KindMoney:
+		editor.UpdateMoney(int32(value))
+	case layout.KindPosition:
+		position := editor.PositionInfo
+		switch field.Name {
+		case "MapID":
+			position.MapID = int32(value)
+		case "X":
+			position.X = int32(value)
+		case "Y":
+			position.Y = int32(value)
+		}
+		editor.UpdatePosition(position.MapID, position.X, position.Y)
+	default:
+		return editor.UpdateRawBytes(field.Offset, data)
+	}
+	return nil
+}
+
+// fieldRegion 是否为以字段修改记录的区域，未解析的空隙和结束标记按原始字节记录
+func fieldRegion(region layout.Region) bool {
+	return region.Kind != layout.KindGap && region.Kind != layout.KindTerminator && region.Kind != layout.KindUnknown
+}
+
+// characterIndex 返回区域所属角色的序号（从1开始），不属于角色记录时返回0
+func characterIndex(region layout.Region) int {
+	if region.Kind != layout.KindCharacter && !(region.Kind == layout.KindCustom && strings.HasPrefix(region.Group, "角色")) {
+		return 0
+	}
+	offset := region.Field.Offset - models.CharacterStartPosition
+	if offset < 0 {
+		return 0
+	}
+	return int(offset/models.CharacterRecordSize) + 1
+}
+
+// encodeValue 将字段的原始字节转换为补丁中的文本
+func encodeValue(field layout.Field, b []byte) string {
+	if field.Type.Integer() {
+		if value, err := field.Value(b); err == nil {
+			return strconv.FormatInt(value, 10)
+		}
+	}
+	return hex.EncodeToString(b)
+}
+
+// decodeValue 将补丁中的文本转换为字段的原始字节
+func decodeValue(field layout.Field, text string) ([]byte, error) {
+	if field.Type.Integer() {
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s的值格式错误: %q", field.Label, text)
+		}
+		return field.Encode(value)
+	}
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s的内容格式错误: %v", field.Label, err)
+	}
+	if len(data) != field.Size {
+		return nil, fmt.Errorf("%s的内容长度应为%d字节", field.Label, field.Size)
+	}
+	return data, nil
+}
@@ -0,0 +1,149 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/models"
+	"wcediter/wcsave/testsave"
+)
+
+// edit 在存档副本的指定位置写入4字节整数
+func edit(content []byte, offset int64, value int32) []byte {
+	result := bytes.Clone(content)
+	binary.LittleEndian.PutUint32(result[offset:], uint32(value))
+	return result
+}
+
+func TestCreate(t *testing.T) {
+	base, err := testsave.Default().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxHP := int64(models.CharacterStartPosition + models.CharacterRecordSize + 16) // 角色2的最大生命值
+	edited := edit(edit(base, maxHP, 999), models.MoneyPosition, 54321)
+	edited[100] ^= 0xFF
+
+	p, err := Create(base, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Ops) != 3 {
+		t.Fatalf("应有3项修改，实际%d: %+v", len(p.Ops), p.Ops)
+	}
+	if op := p.Ops[0]; op.Field != "" || op.Offset != 100 || len(op.New) != 2 {
+		t.Errorf("原始字节修改错误: %+v", op)
+	}
+	if op := p.Ops[1]; op.Character != 2 || op.Field != "MaxHP" || op.Offset != maxHP || op.New != "999" {
+		t.Errorf("角色字段修改错误: %+v", op)
+	}
+	if op := p.Ops[2]; op.Character != 0 || op.Field != "Money" || op.New != "54321" {
+		t.Errorf("银两修改错误: %+v", op)
+	}
+
+	if _, err := Create(base, base[:100]); err == nil {
+		t.Error("大小不同的存档应返回错误")
+	}
+
+	var buffer bytes.Buffer
+	if err := Write(&buffer, p); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Read(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Ops) != len(p.Ops) || loaded.Ops[1] != p.Ops[1] {
+		t.Errorf("读写补丁后内容不同: %+v", loaded)
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	base, err := testsave.Default().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	level := models.CharacterStartPosition + 70
+	edited := edit(edit(base, models.MoneyPosition, 77777), models.MapPosition+models.PositionXOffset, 12)
+	binary.LittleEndian.PutUint16(edited[level:], 99)
+	edited[100] = 0x42
+
+	p, err := Create(base, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 与原存档相同的存档应用后与修改后的存档完全一致，重复应用不报错
+	target := filepath.Join(dir, "Save1.dat")
+	if err := os.WriteFile(target, base, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		editor := wcsave.NewSaveEditor()
+		if err := editor.ReadSave(target); err != nil {
+			t.Fatal(err)
+		}
+		result, err := p.Apply(editor, false)
+		if err != nil || result.Applied != len(p.Ops) || len(result.Conflicts) != 0 {
+			t.Fatalf("应用补丁失败: %+v, %v", result, err)
+		}
+		if err := editor.SaveChanges(target, target); err != nil {
+			t.Fatal(err)
+		}
+		content, _ := os.ReadFile(target)
+		if !bytes.Equal(content, edited) {
+			t.Fatalf("第%d次应用后的存档与修改后的存档不同", i+1)
+		}
+	}
+
+	// 原内容不同的存档：不强制时不做修改，强制时仍写入
+	other := testsave.Default()
+	other.Money = 500
+	otherBytes, err := other.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor := wcsave.NewSaveEditor()
+	if err := editor.ReadSaveFrom(bytes.NewReader(otherBytes)); err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.Apply(editor, false)
+	if !errors.Is(err, ErrConflict) || len(result.Conflicts) != 1 || result.Conflicts[0].Current != "500" || result.Applied != 0 {
+		t.Fatalf("应报告银两不一致: %+v, %v", result, err)
+	}
+	if editor.MoneyInfo.Value != 500 || len(editor.RawEdits) != 0 {
+		t.Error("不一致时不应修改编辑器")
+	}
+	if _, err := p.Apply(editor, true); err != nil {
+		t.Fatal(err)
+	}
+	if editor.MoneyInfo.Value != 77777 || editor.PositionInfo.X != 12 || editor.Characters[0].Data.Level != 99 {
+		t.Errorf("强制应用后的值错误: %+v %+v", editor.MoneyInfo, editor.PositionInfo)
+	}
+
+	// 目标存档缺少补丁修改的角色
+	small := testsave.Default()
+	small.Party = small.Party[:1]
+	smallBytes, err := small.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxHP := int64(models.CharacterStartPosition + 2*models.CharacterRecordSize + 16)
+	p, err = Create(base, edit(base, maxHP, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor = wcsave.NewSaveEditor()
+	if err := editor.ReadSaveFrom(bytes.NewReader(smallBytes)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Apply(editor, true); err == nil {
+		t.Error("目标存档没有角色3时应返回错误")
+	}
+}
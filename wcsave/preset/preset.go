@@ -0,0 +1,332 @@
+package preset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+
+	"gopkg.in/ini.v1"
+)
+
+// SectionPrefix 配置文件中预设节的前缀，例如 [preset.满银两]
+const SectionPrefix = "preset."
+
+// MoneyField 银两字段名
+const MoneyField = "Money"
+
+// Operation 预设操作类型
+type Operation string
+
+const (
+	OpSet Operation = "set" // 设置为固定值
+	OpAdd Operation = "add" // 增加（可为负数）
+	OpMul Operation = "mul" // 乘以倍数
+)
+
+// Action 单个字段的修改操作
+type Action struct {
+	Field string    `json:"field"` // models.CharacterData 的字段名，或 Money
+	Op    Operation `json:"op"`    // 操作类型
+	Value float64   `json:"value"` // 操作数
+}
+
+// Preset 命名的修改预设
+type Preset struct {
+	Name    string   `json:"name"`
+	Target  string   `json:"target"` // 默认作用对象：all、index:N（从1开始）或 name:名字1,名字2
+	Actions []Action `json:"actions"`
+}
+
+// Result 应用预设的结果
+type Result struct {
+	Characters []string // 被修改的角色名
+	Money      bool     // 是否修改了银两
+}
+
+// characterFieldNames 可修改的角色字段名
+var characterFieldNames = func() map[string]bool {
+	names := make(map[string]bool)
+	t := reflect.TypeOf(models.CharacterData{})
+	for i := 0; i < t.NumField(); i++ {
+		names[t.Field(i).Name] = true
+	}
+	return names
+}()
+
+// ParseAction 解析形如 "set 9999"、"add -10"、"mul 1.5" 的操作
+func ParseAction(field, text string) (Action, error) {
+	if field != MoneyField && !characterFieldNames[field] {
+		return Action{}, fmt.Errorf("未知的字段: %s", field)
+	}
+
+	parts := strings.Fields(text)
+	if len(parts) != 2 {
+		return Action{}, fmt.Errorf("字段%s的操作格式错误: %q，应为 \"set|add|mul 数值\"", field, text)
+	}
+
+	op := Operation(strings.ToLower(parts[0]))
+	if op != OpSet && op != OpAdd && op != OpMul {
+		return Action{}, fmt.Errorf("字段%s的操作类型未知: %s", field, parts[0])
+	}
+
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return Action{}, fmt.Errorf("字段%s的数值格式错误: %v", field, err)
+	}
+
+	return Action{Field: field, Op: op, Value: value}, nil
+}
+
+// Load 从 ini 文件中读取所有预设（节名以 preset. 开头）
+func Load(filePath string) ([]Preset, error) {
+	cfg, err := ini.Load(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	presets := make([]Preset, 0)
+	for _, section := range cfg.Sections() {
+		if !strings.HasPrefix(section.Name(), SectionPrefix) {
+			continue
+		}
+
+		p := Preset{
+			Name:   strings.TrimPrefix(section.Name(), SectionPrefix),
+			Target: "all",
+		}
+		for _, key := range section.Keys() {
+			if key.Name() == "target" {
+				p.Target = strings.TrimSpace(key.String())
+				continue
+			}
+			action, err := ParseAction(key.Name(), key.String())
+			if err != nil {
+				return nil, fmt.Errorf("预设%s: %v", p.Name, err)
+			}
+			p.Actions = append(p.Actions, action)
+		}
+		presets = append(presets, p)
+	}
+
+	return presets, nil
+}
+
+// LoadJSON 从 JSON 文件中读取单个预设，格式为
+// {"name": "名称", "target": "all", "actions": [{"field": "Money", "op": "set", "value": 99999}]}
+func LoadJSON(filePath string) (Preset, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return Preset{}, err
+	}
+
+	var p Preset
+	if err := json.Unmarshal(content, &p); err != nil {
+		return Preset{}, fmt.Errorf("解析%s失败: %v", filePath, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	}
+	if p.Target == "" {
+		p.Target = "all"
+	}
+	if len(p.Actions) == 0 {
+		return Preset{}, fmt.Errorf("%s中没有任何修改操作", filePath)
+	}
+
+	// 复用文本格式的校验逻辑
+	for i, action := range p.Actions {
+		checked, err := ParseAction(action.Field, fmt.Sprintf("%s %g", action.Op, action.Value))
+		if err != nil {
+			return Preset{}, fmt.Errorf("%s: %v", filePath, err)
+		}
+		p.Actions[i] = checked
+	}
+	return p, nil
+}
+
+// LoadDir 读取目录下所有 .ini 文件中的预设，目录不存在时返回空列表
+func LoadDir(dir string) ([]Preset, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.ini"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	presets := make([]Preset, 0)
+	for _, file := range files {
+		filePresets, err := Load(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取预设文件%s失败: %v", file, err)
+		}
+		presets = append(presets, filePresets...)
+	}
+	return presets, nil
+}
+
+// LoadAll 依次读取配置文件和预设目录中的预设，忽略不存在的文件
+func LoadAll(configFile, presetDir string) ([]Preset, error) {
+	presets := make([]Preset, 0)
+	if _, err := os.Stat(configFile); err == nil {
+		configPresets, err := Load(configFile)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, configPresets...)
+	}
+
+	dirPresets, err := LoadDir(presetDir)
+	if err != nil {
+		return nil, err
+	}
+	return append(presets, dirPresets...), nil
+}
+
+// Find 按名称查找预设
+func Find(presets []Preset, name string) (Preset, bool) {
+	for _, p := range presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// MatchCharacters 解析作用对象，返回匹配的角色索引
+func MatchCharacters(characters []models.CharacterInfo, target string) ([]int, error) {
+	target = strings.TrimSpace(target)
+	indexes := make([]int, 0)
+
+	switch {
+	case target == "" || target == "all":
+		for i := range characters {
+			indexes = append(indexes, i)
+		}
+	case strings.HasPrefix(target, "index:"):
+		index, err := strconv.Atoi(strings.TrimPrefix(target, "index:"))
+		if err != nil || index < 1 || index > len(characters) {
+			return nil, fmt.Errorf("无效的角色序号: %s", target)
+		}
+		indexes = append(indexes, index-1)
+	case strings.HasPrefix(target, "name:"):
+		names := make([]string, 0)
+		for _, name := range strings.Split(strings.TrimPrefix(target, "name:"), ",") {
+			if name = normalizeName(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("无效的作用对象: %s，没有指定名字", target)
+		}
+		matched := make(map[string]bool)
+		for i, char := range characters {
+			charName := normalizeName(char.Name)
+			found := false
+			for _, name := range names {
+				if strings.Contains(charName, name) {
+					matched[name] = true
+					found = true
+				}
+			}
+			if found {
+				indexes = append(indexes, i)
+			}
+		}
+		for _, name := range names {
+			if !matched[name] {
+				return nil, fmt.Errorf("没有名字包含%s的角色", name)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("无效的作用对象: %s，应为 all、index:N 或 name:名字", target)
+	}
+
+	return indexes, nil
+}
+
+// normalizeName 去掉名字中的空白，存档中的名字以空格补足6字节（如 "聶  風"）
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), "")
+}
+
+// Apply 将预设应用到编辑器，target 为空时使用预设自身的作用对象
+func (p Preset) Apply(editor *wcsave.SaveEditor, target string) (Result, error) {
+	var result Result
+	if target == "" {
+		target = p.Target
+	}
+
+	indexes, err := MatchCharacters(editor.Characters, target)
+	if err != nil {
+		return result, err
+	}
+
+	// 先计算全部结果，全部有效后再写入编辑器，避免只应用了一部分（如超出范围时）
+	updated := make([]models.CharacterData, len(indexes))
+	changed := false
+	for i, index := range indexes {
+		char, _ := editor.GetCharacterByIndex(index)
+		updated[i] = char.Data
+		for _, action := range p.Actions {
+			if action.Field == MoneyField {
+				continue
+			}
+			field := reflect.ValueOf(&updated[i]).Elem().FieldByName(action.Field)
+			value, err := applyOperation(field.Int(), action, field.Type().Bits())
+			if err != nil {
+				return result, fmt.Errorf("角色%s的%s: %v", char.Name, action.Field, err)
+			}
+			field.SetInt(value)
+			changed = true
+		}
+	}
+
+	// 银两与角色无关，只应用一次
+	money := int64(editor.MoneyInfo.Value)
+	for _, action := range p.Actions {
+		if action.Field != MoneyField {
+			continue
+		}
+		if editor.MoneyInfo.Position == 0 {
+			return result, fmt.Errorf("存档中没有银两数据")
+		}
+		money, err = applyOperation(money, action, 32)
+		if err != nil {
+			return result, fmt.Errorf("银两: %v", err)
+		}
+		result.Money = true
+	}
+
+	if changed {
+		for i, index := range indexes {
+			editor.UpdateCharacter(index, updated[i])
+			result.Characters = append(result.Characters, editor.Characters[index].Name)
+		}
+	}
+	if result.Money {
+		editor.UpdateMoney(int32(money))
+	}
+	return result, nil
+}
+
+// applyOperation 计算操作结果，超出字段位数可表示的范围时返回错误
+func applyOperation(current int64, action Action, bits int) (int64, error) {
+	var value float64
+	switch action.Op {
+	case OpSet:
+		value = action.Value
+	case OpAdd:
+		value = float64(current) + action.Value
+	case OpMul:
+		value = float64(current) * action.Value
+	}
+	return layout.IntValue(value, bits)
+}
@@ -0,0 +1,206 @@
+package preset
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/models"
+)
+
+// 创建包含两个角色的测试编辑器
+func newTestEditor() *wcsave.SaveEditor {
+	editor := wcsave.NewSaveEditor()
+	editor.Characters = []models.CharacterInfo{
+		{Name: "步驚雲", Data: models.CharacterData{Level: 10, Attack: 50, MaxHP: 500, CurrentHP: 100}},
+		{Name: "聶  風", Data: models.CharacterData{Level: 8, Attack: 40, MaxHP: 400, CurrentHP: 50}},
+	}
+	editor.MoneyInfo = models.MoneyInfo{Value: 1000, RawBytes: make([]byte, 4), Position: models.MoneyPosition}
+	return editor
+}
+
+// 测试从ini文件读取预设
+func TestLoad(t *testing.T) {
+	content := `[default_Records]
+原版 = ./Save0.dat
+
+[preset.满银两]
+Money = set 99999999
+
+[preset.强化]
+target = name:步驚雲
+Attack = mul 2
+Level = add 5
+`
+	filePath := filepath.Join(t.TempDir(), "wcediter.ini")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	presets, err := Load(filePath)
+	if err != nil {
+		t.Fatalf("Load失败: %v", err)
+	}
+	if len(presets) != 2 {
+		t.Fatalf("预设数量错误，预期2，实际%d", len(presets))
+	}
+
+	p, ok := Find(presets, "强化")
+	if !ok {
+		t.Fatal("未找到预设“强化”")
+	}
+	if p.Target != "name:步驚雲" || len(p.Actions) != 2 || p.Actions[0].Op != OpMul {
+		t.Errorf("预设内容错误: %+v", p)
+	}
+
+	// 未知字段应报错
+	if err := os.WriteFile(filePath, []byte("[preset.错误]\nFoo = set 1\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	if _, err := Load(filePath); err == nil {
+		t.Error("未知字段应该返回错误")
+	}
+}
+
+// 测试ParseAction函数
+func TestParseAction(t *testing.T) {
+	action, err := ParseAction("Level", "add -3")
+	if err != nil || action.Op != OpAdd || action.Value != -3 {
+		t.Errorf("解析结果错误: %+v, %v", action, err)
+	}
+
+	for _, text := range []string{"set", "pow 2", "set abc"} {
+		if _, err := ParseAction("Level", text); err == nil {
+			t.Errorf("%q应该返回错误", text)
+		}
+	}
+}
+
+// 测试Apply函数
+func TestApply(t *testing.T) {
+	editor := newTestEditor()
+	p := Preset{
+		Name:   "测试",
+		Target: "all",
+		Actions: []Action{
+			{Field: "Attack", Op: OpMul, Value: 2},
+			{Field: "CurrentHP", Op: OpSet, Value: 999},
+			{Field: "Level", Op: OpAdd, Value: 32757}, // 恰好为int16上限
+			{Field: MoneyField, Op: OpAdd, Value: 500},
+		},
+	}
+
+	result, err := p.Apply(editor, "")
+	if err != nil {
+		t.Fatalf("Apply失败: %v", err)
+	}
+	if len(result.Characters) != 2 || !result.Money {
+		t.Errorf("应用结果错误: %+v", result)
+	}
+	if editor.Characters[0].Data.Attack != 100 || editor.Characters[1].Data.Attack != 80 {
+		t.Errorf("攻击倍乘错误: %d, %d", editor.Characters[0].Data.Attack, editor.Characters[1].Data.Attack)
+	}
+	if editor.Characters[1].Data.CurrentHP != 999 {
+		t.Errorf("生命值设置错误: %d", editor.Characters[1].Data.CurrentHP)
+	}
+	if editor.Characters[0].Data.Level != 32767 {
+		t.Errorf("等级应为int16上限: %d", editor.Characters[0].Data.Level)
+	}
+	if editor.MoneyInfo.Value != 1500 {
+		t.Errorf("银两应为1500，实际%d", editor.MoneyInfo.Value)
+	}
+
+	// 超出字段范围时返回错误，不修改任何角色
+	editor = newTestEditor()
+	overflow := Preset{Name: "溢出", Target: "all", Actions: []Action{
+		{Field: "Attack", Op: OpSet, Value: 1},
+		{Field: "Level", Op: OpAdd, Value: 40000},
+	}}
+	if _, err := overflow.Apply(editor, ""); err == nil {
+		t.Error("超出int16范围时应该返回错误")
+	}
+	if editor.Characters[0].Data.Attack != 50 || editor.Characters[0].Data.Level != 10 {
+		t.Errorf("应用失败时角色数据不应改变: %+v", editor.Characters[0].Data)
+	}
+
+	// 银两操作无法应用时，角色也不应被修改
+	editor = newTestEditor()
+	editor.MoneyInfo = models.MoneyInfo{}
+	if _, err := p.Apply(editor, ""); err == nil {
+		t.Error("没有银两数据时应该返回错误")
+	}
+	if editor.Characters[0].Data.Attack != 50 || editor.Characters[1].Data.CurrentHP != 50 {
+		t.Errorf("应用失败时角色数据不应改变: %+v", editor.Characters)
+	}
+}
+
+// 测试按序号和名字匹配角色
+func TestMatchCharacters(t *testing.T) {
+	editor := newTestEditor()
+
+	indexes, err := MatchCharacters(editor.Characters, "index:2")
+	if err != nil || len(indexes) != 1 || indexes[0] != 1 {
+		t.Errorf("按序号匹配错误: %v, %v", indexes, err)
+	}
+
+	indexes, err = MatchCharacters(editor.Characters, "name:風")
+	if err != nil || len(indexes) != 1 || indexes[0] != 1 {
+		t.Errorf("按名字匹配错误: %v, %v", indexes, err)
+	}
+
+	// 存档中的名字以空格补足，匹配时忽略
+	indexes, err = MatchCharacters(editor.Characters, "name:聶風, 步驚雲")
+	if err != nil || len(indexes) != 2 {
+		t.Errorf("忽略空格按名字匹配错误: %v, %v", indexes, err)
+	}
+
+	// 没有匹配任何角色的名字视为错误
+	if _, err := MatchCharacters(editor.Characters, "name:雄霸"); err == nil {
+		t.Error("没有匹配的名字应该返回错误")
+	}
+	if _, err := MatchCharacters(editor.Characters, "name:聶風,雄霸"); err == nil {
+		t.Error("部分名字没有匹配时应该返回错误")
+	}
+	if _, err := MatchCharacters(editor.Characters, "name: , "); err == nil {
+		t.Error("没有指定名字时应该返回错误")
+	}
+
+	if _, err := MatchCharacters(editor.Characters, "index:3"); err == nil {
+		t.Error("超出范围的序号应该返回错误")
+	}
+	if _, err := MatchCharacters(editor.Characters, "foo"); err == nil {
+		t.Error("无效的作用对象应该返回错误")
+	}
+}
+
+// 测试从JSON文件读取预设
+func TestLoadJSON(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "满级.json")
+	content := `{"target": "index:1", "actions": [{"field": "Level", "op": "set", "value": 99}, {"field": "Money", "op": "add", "value": 100}]}`
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	p, err := LoadJSON(filePath)
+	if err != nil {
+		t.Fatalf("LoadJSON失败: %v", err)
+	}
+	if p.Name != "满级" || p.Target != "index:1" || len(p.Actions) != 2 || p.Actions[1].Op != OpAdd {
+		t.Errorf("预设内容错误: %+v", p)
+	}
+
+	// 未知字段和未知操作应报错
+	for _, content := range []string{
+		`{"actions": [{"field": "Foo", "op": "set", "value": 1}]}`,
+		`{"actions": [{"field": "Level", "op": "pow", "value": 1}]}`,
+		`{"actions": []}`,
+	} {
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+		if _, err := LoadJSON(filePath); err == nil {
+			t.Errorf("%s 应该返回错误", content)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package reader
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"reflect"
 	"strings"
 
 	"wcediter/assets"
+	"wcediter/wcsave/layout"
 	"wcediter/wcsave/models"
 	"wcediter/wcsave/utils"
 
@@ -31,34 +33,78 @@ func GetLocationNameByID(locationID int) string {
 	return "未知位置"
 }
 
-// readMoneyData 读取银两数据的函数
-func ReadMoneyData(file *os.File, position int64) (models.MoneyInfo, error) {
-	var moneyInfo models.MoneyInfo
-	moneyInfo.Position = position
+// GetLocationNames 获取完整的位置名称表（下标即位置编号）
+func GetLocationNames() []string {
+	names := make([]string, len(locationNames))
+	copy(names, locationNames)
+	return names
+}
+
+// fieldError 包装字段读取错误，数据不足时统一为 models.ErrTruncated
+func fieldError(field string, offset int64, err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = models.ErrTruncated
+	}
+	return &models.FieldError{Field: field, Offset: offset, Err: err}
+}
+
+// ReadPosition 读取队伍当前所在地图及坐标
+// position 为地图编号所在位置，坐标位于其后第36字节起的两个int
+// 地图编号与 WC.cfg 中的位置编号不是同一套编号，不能用 GetLocationNameByID 取名称
+func ReadPosition(r io.ReaderAt, position int64) (models.PositionInfo, error) {
+	var positionInfo models.PositionInfo
+	positionInfo.Position = position
 
-	// 检查文件大小是否足够
-	fileInfo, err := file.Stat()
+	// 读取地图编号（4字节）
+	mapID, mapRawBytes, err := utils.ReadAndConvert(r, position, 4, utils.Int32Converter)
 	if err != nil {
-		return moneyInfo, fmt.Errorf("无法获取文件信息: %v", err)
+		return positionInfo, fieldError("地图编号", position, err)
 	}
 
-	if position >= fileInfo.Size() {
-		return moneyInfo, fmt.Errorf("文件大小不足以读取银两数据位置")
+	// 地图编号后32字节为坐标（各4字节）
+	x, xRawBytes, err := utils.ReadAndConvert(r, position+models.PositionXOffset, 4, utils.Int32Converter)
+	if err != nil {
+		return positionInfo, fieldError("横坐标", position+models.PositionXOffset, err)
+	}
+	y, yRawBytes, err := utils.ReadAndConvert(r, position+models.PositionYOffset, 4, utils.Int32Converter)
+	if err != nil {
+		return positionInfo, fieldError("纵坐标", position+models.PositionYOffset, err)
+	}
+
+	rawBytes := make([]byte, 0, 12)
+	rawBytes = append(rawBytes, mapRawBytes...)
+	rawBytes = append(rawBytes, xRawBytes...)
+	rawBytes = append(rawBytes, yRawBytes...)
+
+	positionInfo = models.PositionInfo{
+		MapID:    mapID,
+		X:        x,
+		Y:        y,
+		RawBytes: rawBytes,
+		Position: position,
 	}
 
-	// 定位到银两数据位置
-	_, err = file.Seek(position, 0)
+	return positionInfo, nil
+}
+
+// ReadPartyCount 读取存档中记录的队伍人数
+func ReadPartyCount(r io.ReaderAt) (int, error) {
+	count, _, err := utils.ReadAndConvert(r, models.PartyCountPosition, 2, utils.Int16Converter)
 	if err != nil {
-		return moneyInfo, fmt.Errorf("无法定位到银两数据位置: %v", err)
+		return 0, fieldError("队伍人数", models.PartyCountPosition, err)
 	}
+	return int(uint16(count)), nil
+}
 
-	// 读取4字节银两数据
-	money, moneyRawBytes, err := utils.ReadAndConvert(file, 4, func(b []byte) (int32, error) {
-		return int32(binary.LittleEndian.Uint32(b)), nil
-	})
+// ReadMoneyData 读取银两数据的函数
+func ReadMoneyData(r io.ReaderAt, position int64) (models.MoneyInfo, error) {
+	var moneyInfo models.MoneyInfo
+	moneyInfo.Position = position
 
+	// 读取4字节银两数据
+	money, moneyRawBytes, err := utils.ReadAndConvert(r, position, 4, utils.Int32Converter)
 	if err != nil {
-		return moneyInfo, fmt.Errorf("读取银两数据失败: %v", err)
+		return moneyInfo, fieldError("银两", position, err)
 	}
 
 	moneyInfo = models.MoneyInfo{
@@ -70,33 +116,22 @@ func ReadMoneyData(file *os.File, position int64) (models.MoneyInfo, error) {
 	return moneyInfo, nil
 }
 
-// readCharacterProperties 读取角色属性的函数
-func readCharacterProperties(file *os.File) (models.CharacterData, models.RawByteData, []byte, int64, bool, error) {
-	// 记录当前位置
-	position, err := file.Seek(0, 1)
-	if err != nil {
-		// 如果获取位置失败，使用-1作为默认值
-		position = -1
-	}
+// readCharacterProperties 读取 position 处的角色记录
+// 返回的 bool 为 false 表示遇到结束标记（起始2字节为0）
+func readCharacterProperties(r io.ReaderAt, position int64) (models.CharacterData, models.RawByteData, []byte, bool, error) {
 	var data models.CharacterData
 	var rawBytes models.RawByteData
 	var utf8Name []byte
 
 	// 检查起始2字节是否全为0
-	_, startCheckBuffer, err := utils.ReadAndConvert[struct{}](file, 2, nil)
+	_, startCheckBuffer, err := utils.ReadAndConvert[struct{}](r, position, 2, nil)
 	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取起始2字节失败: %v", err)
+		return data, rawBytes, utf8Name, false, fieldError("角色记录起始2字节", position, err)
 	}
 
 	// 如果起始2字节全为0，表示遇到结束条件
-	if len(startCheckBuffer) >= 2 && startCheckBuffer[0] == 0 && startCheckBuffer[1] == 0 {
-		return data, rawBytes, utf8Name, position, false, nil
-	}
-
-	// 如果不是结束条件，需要将文件指针回退2字节
-	_, err = file.Seek(-2, 1)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("文件指针回退失败: %v", err)
+	if startCheckBuffer[0] == 0 && startCheckBuffer[1] == 0 {
+		return data, rawBytes, utf8Name, false, nil
 	}
 
 	// 定义名字编码转换器
@@ -110,179 +145,46 @@ func readCharacterProperties(file *os.File) (models.CharacterData, models.RawByt
 		return utf8Bytes, nil
 	}
 
-	// 使用泛型方法读取并转换名字
-	utf8Name, _, err = utils.ReadAndConvert(file, 6, nameConverter)
+	// 使用泛型方法读取并转换名字（名字后2字节未知）
+	utf8Name, _, err = utils.ReadAndConvert(r, position, 6, nameConverter)
 	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取名字失败: %v", err)
+		return data, rawBytes, utf8Name, false, fieldError("名字", position, err)
 	}
 
-	// 在名字后跳过2个字节
-	err = utils.SkipBytes(file, 2)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("跳过名字后字节失败: %v", err)
-	}
-
-	// 使用泛型方法读取4字节字段
-	uint32Converter := func(b []byte) (int32, error) {
-		return int32(binary.LittleEndian.Uint32(b)), nil
-	}
-
-	// 读取当前经验值（4字节）
-	val, bytes, err := utils.ReadAndConvert(file, 4, uint32Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取当前经验值失败: %v", err)
-	}
-	data.CurrentExp = val
-	rawBytes.CurrentExp = bytes
-
-	// 读取升级经验值（4字节）
-	val, bytes, err = utils.ReadAndConvert(file, 4, uint32Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取升级经验值失败: %v", err)
-	}
-	data.NextLevelExp = val
-	rawBytes.NextLevelExp = bytes
-
-	// 读取最大生命值（4字节）
-	val, bytes, err = utils.ReadAndConvert(file, 4, uint32Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取最大生命值失败: %v", err)
-	}
-	data.MaxHP = val
-	rawBytes.MaxHP = bytes
-
-	// 读取最大内力值（4字节）
-	val, bytes, err = utils.ReadAndConvert(file, 4, uint32Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取最大内力值失败: %v", err)
-	}
-	data.MaxMP = val
-	rawBytes.MaxMP = bytes
-
-	// 读取当前生命值（4字节）
-	val, bytes, err = utils.ReadAndConvert(file, 4, uint32Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取当前生命值失败: %v", err)
-	}
-	data.CurrentHP = val
-	rawBytes.CurrentHP = bytes
-
-	// 读取当前内力值（4字节）
-	val, bytes, err = utils.ReadAndConvert(file, 4, uint32Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取当前内力值失败: %v", err)
-	}
-	data.CurrentMP = val
-	rawBytes.CurrentMP = bytes
-
-	// 使用泛型方法读取2字节字段
-	uint16Converter := func(b []byte) (int16, error) {
-		return int16(binary.LittleEndian.Uint16(b)), nil
-	}
-
-	// 读取力量（2字节）
-	val16, bytes, err := utils.ReadAndConvert(file, 2, uint16Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取力量失败: %v", err)
-	}
-	data.Strength = val16
-	rawBytes.Strength = bytes
-
-	// 读取反应（2字节）
-	val16, bytes, err = utils.ReadAndConvert(file, 2, uint16Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取反应失败: %v", err)
-	}
-	data.Reaction = val16
-	rawBytes.Reaction = bytes
-
-	// 读取体质（2字节）
-	val16, bytes, err = utils.ReadAndConvert(file, 2, uint16Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取体质失败: %v", err)
-	}
-	data.Constitution = val16
-	rawBytes.Constitution = bytes
-
-	// 读取速度（2字节）
-	val16, bytes, err = utils.ReadAndConvert(file, 2, uint16Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取速度失败: %v", err)
-	}
-	data.Speed = val16
-	rawBytes.Speed = bytes
-
-	// 读取攻击（2字节）
-	val16, bytes, err = utils.ReadAndConvert(file, 2, uint16Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取攻击失败: %v", err)
-	}
-	data.Attack = val16
-	rawBytes.Attack = bytes
-
-	// 读取防御（2字节）
-	val16, bytes, err = utils.ReadAndConvert(file, 2, uint16Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取防御失败: %v", err)
-	}
-	data.Defense = val16
-	rawBytes.Defense = bytes
-
-	// 跳过8字节
-	err = utils.SkipBytes(file, 8)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("跳过8字节失败: %v", err)
-	}
-
-	// 读取运气（2字节）
-	val16, bytes, err = utils.ReadAndConvert(file, 2, uint16Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取运气失败: %v", err)
-	}
-	data.Luck = val16
-	rawBytes.Luck = bytes
-
-	// 跳过16字节
-	err = utils.SkipBytes(file, 16)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("跳过16字节失败: %v", err)
-	}
-
-	// 读取等级（2字节）
-	val16, bytes, err = utils.ReadAndConvert(file, 2, uint16Converter)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("读取等级失败: %v", err)
-	}
-	data.Level = val16
-	rawBytes.Level = bytes
-
-	// 跳过12字节
-	err = utils.SkipBytes(file, 12)
-	if err != nil {
-		return data, rawBytes, utf8Name, position, false, fmt.Errorf("跳过12字节失败: %v", err)
+	// 读取整数字段，偏移和长度取自 layout.CharacterFields，按字段名写入角色数据及原始字节
+	dataValue := reflect.ValueOf(&data).Elem()
+	rawValue := reflect.ValueOf(&rawBytes).Elem()
+	for _, field := range layout.CharacterFields {
+		target := dataValue.FieldByName(field.Name)
+		if !field.Type.Integer() || !target.IsValid() {
+			continue
+		}
+		val, bytes, err := utils.ReadAndConvert(r, position+field.Offset, field.Size, field.Value)
+		if err != nil {
+			return data, rawBytes, utf8Name, false, fieldError(field.Label, position+field.Offset, err)
+		}
+		target.SetInt(val)
+		if raw := rawValue.FieldByName(field.Name); raw.IsValid() {
+			raw.SetBytes(bytes)
+		}
 	}
 
-	return data, rawBytes, utf8Name, position, true, nil
+	return data, rawBytes, utf8Name, true, nil
 }
 
 // ReadCharacters 读取所有角色数据
-func ReadCharacters(file *os.File) ([]models.CharacterInfo, error) {
-	// 定位到指定位置
-	targetPosition := int64(202618)
-	_, err := file.Seek(targetPosition, 0)
-	if err != nil {
-		return nil, fmt.Errorf("无法定位到指定位置: %v", err)
-	}
-
+func ReadCharacters(r io.ReaderAt) ([]models.CharacterInfo, error) {
 	// 存储所有角色信息
 	characters := make([]models.CharacterInfo, 0)
 
 	// 循环读取多个角色数据，最多读取5个角色
-	for i := 0; i < 5; i++ {
+	for i := 0; i < models.MaxCharacters; i++ {
+		position := int64(models.CharacterStartPosition + i*models.CharacterRecordSize)
+
 		// 调用函数读取角色属性
-		characterData, rawBytes, utf8Name, position, continueReading, err := readCharacterProperties(file)
+		characterData, rawBytes, utf8Name, continueReading, err := readCharacterProperties(r, position)
 		if err != nil {
-			return characters, fmt.Errorf("读取角色属性时出错: %v", err)
+			return characters, fmt.Errorf("读取角色%d的属性时出错: %w", i+1, err)
 		}
 
 		// 检查是否遇到结束条件
@@ -296,68 +198,57 @@ func ReadCharacters(file *os.File) ([]models.CharacterInfo, error) {
 			characterName = string(utf8Name)
 		}
 
+		// 保留完整的角色记录，用于调整队伍成员时整体搬移
+		_, recordBytes, err := utils.ReadAndConvert[struct{}](r, position, models.CharacterRecordSize, nil)
+		if err != nil {
+			return characters, fmt.Errorf("读取角色%d时出错: %w", i+1, fieldError("角色记录", position, err))
+		}
+
+		// 队伍成员编号表中对应的编号
+		memberPosition := int64(models.PartyMemberPosition + i*2)
+		memberID, _, err := utils.ReadAndConvert(r, memberPosition, 2, utils.Int16Converter)
+		if err != nil {
+			return characters, fmt.Errorf("读取角色%d时出错: %w", i+1, fieldError("队伍成员编号", memberPosition, err))
+		}
+
 		characters = append(characters, models.CharacterInfo{
-			Name:     characterName,
-			Data:     characterData,
-			RawBytes: rawBytes,
-			Position: position,
+			Name:        characterName,
+			Data:        characterData,
+			RawBytes:    rawBytes,
+			RecordBytes: recordBytes,
+			Position:    position,
+			MemberID:    memberID,
 		})
 	}
 
 	return characters, nil
 }
 
-// ReadProgress 从 WC.cfg 文件中读取进度信息
-func ReadProgress(file *os.File) ([]models.ProgressInfo, error) {
-	// 检查文件大小
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("无法获取文件信息: %v", err)
-	}
-
-	// 需要读取到至少第56+20=76字节（56字节起始位置 + 5个int*4字节）
-	minSize := int64(76)
-	if fileInfo.Size() < minSize {
-		return nil, fmt.Errorf("文件大小不足以读取进度数据")
-	}
-
-	// 读取进度编号：从第36个字节开始，读取5个int（每个4字节）
-	progressIDs := make([]int, 5)
-	_, err = file.Seek(36, 0)
-	if err != nil {
-		return nil, fmt.Errorf("无法定位到进度编号位置: %v", err)
-	}
+// ReadProgress 从 WC.cfg 内容中读取进度信息
+// 进度编号从第36字节开始，位置编号从第56字节开始，各5个int
+func ReadProgress(r io.ReaderAt) ([]models.ProgressInfo, error) {
+	progressIDs := make([]int, models.MaxProgress)
+	locationIDs := make([]int, models.MaxProgress)
 
-	uint32Converter := func(b []byte) (int32, error) {
-		return int32(binary.LittleEndian.Uint32(b)), nil
-	}
-
-	for i := 0; i < 5; i++ {
-		val, _, err := utils.ReadAndConvert(file, 4, uint32Converter)
+	for i := 0; i < models.MaxProgress; i++ {
+		offset := models.ProgressIDPosition + int64(i)*4
+		val, _, err := utils.ReadAndConvert(r, offset, 4, utils.Int32Converter)
 		if err != nil {
-			return nil, fmt.Errorf("读取进度编号[%d]失败: %v", i, err)
+			return nil, fieldError(fmt.Sprintf("进度编号[%d]", i), offset, err)
 		}
 		progressIDs[i] = int(val)
-	}
-
-	// 读取位置编号：从第56个字节开始，读取5个int（每个4字节）
-	locationIDs := make([]int, 5)
-	_, err = file.Seek(56, 0)
-	if err != nil {
-		return nil, fmt.Errorf("无法定位到位置编号位置: %v", err)
-	}
 
-	for i := 0; i < 5; i++ {
-		val, _, err := utils.ReadAndConvert(file, 4, uint32Converter)
+		offset = models.ProgressLocationPosition + int64(i)*4
+		val, _, err = utils.ReadAndConvert(r, offset, 4, utils.Int32Converter)
 		if err != nil {
-			return nil, fmt.Errorf("读取位置编号[%d]失败: %v", i, err)
+			return nil, fieldError(fmt.Sprintf("位置编号[%d]", i), offset, err)
 		}
 		locationIDs[i] = int(val)
 	}
 
 	// 构建结果数组
-	result := make([]models.ProgressInfo, 5)
-	for i := 0; i < 5; i++ {
+	result := make([]models.ProgressInfo, models.MaxProgress)
+	for i := 0; i < models.MaxProgress; i++ {
 		// 通过位置ID获取位置名称
 		locationName := GetLocationNameByID(locationIDs[i])
 
@@ -1,9 +1,19 @@
 package reader
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"wcediter/wcsave/models"
 )
 
 // 测试ReadMoneyData函数
@@ -115,4 +125,289 @@ func TestReadMoneyData_OutOfBounds(t *testing.T) {
 	if err == nil {
 		t.Fatal("预期应该返回错误，但没有")
 	}
-}
\ No newline at end of file
+}
+
+// 测试ReadPosition函数
+func TestReadPosition(t *testing.T) {
+	// 构造数据：位置10处写入地图编号，其后第36字节起写入坐标
+	data := make([]byte, 60)
+	binary.LittleEndian.PutUint32(data[10:], 11)
+	binary.LittleEndian.PutUint32(data[46:], 21)
+	binary.LittleEndian.PutUint32(data[50:], 84)
+
+	tempFile := filepath.Join(t.TempDir(), "test_position.dat")
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	file, err := os.Open(tempFile)
+	if err != nil {
+		t.Fatalf("打开测试文件失败: %v", err)
+	}
+	defer file.Close()
+
+	positionInfo, err := ReadPosition(file, 10)
+	if err != nil {
+		t.Fatalf("ReadPosition失败: %v", err)
+	}
+
+	if positionInfo.MapID != 11 || positionInfo.X != 21 || positionInfo.Y != 84 {
+		t.Errorf("位置数据错误，预期(11, 21, 84)，实际(%d, %d, %d)", positionInfo.MapID, positionInfo.X, positionInfo.Y)
+	}
+	if len(positionInfo.RawBytes) != 12 {
+		t.Errorf("原始字节长度错误，预期12，实际%d", len(positionInfo.RawBytes))
+	}
+
+	// 文件过小时应返回错误
+	_, err = ReadPosition(file, 20)
+	if err == nil {
+		t.Fatal("预期应该返回错误，但没有")
+	}
+}
+
+// 测试从内存中的存档读取角色
+func TestReadCharacters_InMemory(t *testing.T) {
+	content := make([]byte, 205014)
+	start := 202618
+	copy(content[start:], []byte{0xA4, 0xFD, 0xA4, 0x51, 0x20, 0x20}) // "小一"
+	binary.LittleEndian.PutUint32(content[start+16:], 500)
+	binary.LittleEndian.PutUint16(content[start+32:], 42)
+	binary.LittleEndian.PutUint32(content[start+70:], 7)
+
+	characters, err := ReadCharacters(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("ReadCharacters失败: %v", err)
+	}
+	if len(characters) != 1 {
+		t.Fatalf("预期读取到1个角色，实际%d", len(characters))
+	}
+	char := characters[0]
+	if char.Position != int64(start) {
+		t.Errorf("角色位置预期%d，实际%d", start, char.Position)
+	}
+	if char.Data.MaxHP != 500 || char.Data.Strength != 42 || char.Data.Level != 7 {
+		t.Errorf("角色数据读取错误: %+v", char.Data)
+	}
+	if len(char.RecordBytes) != 84 {
+		t.Errorf("记录字节长度预期84，实际%d", len(char.RecordBytes))
+	}
+}
+
+// 测试截断的内容不会导致越界
+func TestReadCharacters_Truncated(t *testing.T) {
+	content := make([]byte, 202618+40)
+	content[202618] = 0xA4
+	content[202619] = 0xFD
+
+	_, err := ReadCharacters(bytes.NewReader(content))
+	if !errors.Is(err, models.ErrTruncated) {
+		t.Fatalf("预期返回 ErrTruncated，实际: %v", err)
+	}
+	var fieldErr *models.FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("预期返回 FieldError，实际: %T", err)
+	}
+	// 记录中前40字节完整，第一个不完整的字段是从第40字节开始的攻击
+	if fieldErr.Offset != 202618+40 || fieldErr.Field != "攻击" {
+		t.Errorf("错误位置预期 攻击@%d，实际 %s@%d", 202618+40, fieldErr.Field, fieldErr.Offset)
+	}
+}
+
+// fuzzSeeds 使用 data 目录中的文件作为模糊测试的初始语料
+func fuzzSeeds(f *testing.F, pattern string) {
+	f.Add([]byte{})
+	f.Add([]byte{0xA4, 0xFD})
+	paths, _ := filepath.Glob(filepath.Join("../../data", pattern))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err == nil {
+			f.Add(content)
+		}
+	}
+}
+
+// 模糊测试ReadCharacters：任意输入都不应panic，且最多读取5个角色
+func FuzzReadCharacters(f *testing.F) {
+	fuzzSeeds(f, "*.dat")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		characters, err := ReadCharacters(bytes.NewReader(data))
+		if len(characters) > models.MaxCharacters {
+			t.Fatalf("读取到%d个角色，超过上限", len(characters))
+		}
+		if err != nil {
+			if !errors.Is(err, models.ErrTruncated) {
+				t.Fatalf("内存数据只应出现截断错误，实际: %v", err)
+			}
+			return
+		}
+		for i, char := range characters {
+			if len(char.RecordBytes) != models.CharacterRecordSize {
+				t.Fatalf("角色%d的记录长度为%d", i, len(char.RecordBytes))
+			}
+		}
+	})
+}
+
+// 模糊测试ReadMoneyData：任意输入和位置都不应panic
+func FuzzReadMoneyData(f *testing.F) {
+	fuzzSeeds(f, "*.dat")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, position := range []int64{0, models.MoneyPosition, int64(len(data)) - 4, int64(len(data)) - 1, -1} {
+			moneyInfo, err := ReadMoneyData(bytes.NewReader(data), position)
+			if err == nil && len(moneyInfo.RawBytes) != 4 {
+				t.Fatalf("位置%d: 原始字节长度为%d", position, len(moneyInfo.RawBytes))
+			}
+		}
+	})
+}
+
+// 模糊测试ReadProgress：任意输入都不应panic，成功时返回5条进度
+func FuzzReadProgress(f *testing.F) {
+	fuzzSeeds(f, "WC.cfg")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		progressInfos, err := ReadProgress(bytes.NewReader(data))
+		if err != nil {
+			if !errors.Is(err, models.ErrTruncated) {
+				t.Fatalf("内存数据只应出现截断错误，实际: %v", err)
+			}
+			return
+		}
+		if len(progressInfos) != 5 {
+			t.Fatalf("预期5条进度，实际%d", len(progressInfos))
+		}
+	})
+}
+
+var update = flag.Bool("update", false, "根据当前的解析结果重新生成 testdata/golden 中的快照")
+
+// goldenCharacter 快照中的角色
+type goldenCharacter struct {
+	Name     string               `json:"name"`
+	Position int64                `json:"position"`
+	MemberID int16                `json:"memberID"` // 队伍成员编号表中的编号
+	Data     models.CharacterData `json:"data"`
+	Record   string               `json:"record"` // 完整记录的十六进制
+}
+
+// goldenSave 存档解析结果的快照
+type goldenSave struct {
+	PartyCount  int               `json:"partyCount"`
+	Characters  []goldenCharacter `json:"characters"`
+	Money       int32             `json:"money"`
+	MapID       int32             `json:"mapID"`
+	X           int32             `json:"x"`
+	Y           int32             `json:"y"`
+	PositionRaw string            `json:"positionRaw"`
+}
+
+// parseGolden 解析 data 目录中的文件，返回用于快照的结构
+func parseGolden(t *testing.T, content []byte, name string) interface{} {
+	r := bytes.NewReader(content)
+	if name == "WC.cfg" {
+		progressInfos, err := ReadProgress(r)
+		if err != nil {
+			t.Fatalf("ReadProgress失败: %v", err)
+		}
+		return progressInfos
+	}
+
+	characters, err := ReadCharacters(r)
+	if err != nil {
+		t.Fatalf("ReadCharacters失败: %v", err)
+	}
+	partyCount, err := ReadPartyCount(r)
+	if err != nil {
+		t.Fatalf("ReadPartyCount失败: %v", err)
+	}
+	moneyInfo, err := ReadMoneyData(r, models.MoneyPosition)
+	if err != nil {
+		t.Fatalf("ReadMoneyData失败: %v", err)
+	}
+	positionInfo, err := ReadPosition(r, models.MapPosition)
+	if err != nil {
+		t.Fatalf("ReadPosition失败: %v", err)
+	}
+
+	save := goldenSave{
+		PartyCount:  partyCount,
+		Characters:  make([]goldenCharacter, 0, len(characters)),
+		Money:       moneyInfo.Value,
+		MapID:       positionInfo.MapID,
+		X:           positionInfo.X,
+		Y:           positionInfo.Y,
+		PositionRaw: hex.EncodeToString(positionInfo.RawBytes),
+	}
+	for _, char := range characters {
+		save.Characters = append(save.Characters, goldenCharacter{
+			Name:     char.Name,
+			Position: char.Position,
+			MemberID: char.MemberID,
+			Data:     char.Data,
+			Record:   hex.EncodeToString(char.RecordBytes),
+		})
+	}
+	return save
+}
+
+// 回归测试：解析 data 目录中的所有文件，与 testdata/golden 中的快照比较
+// 修改解析逻辑后使用 go test ./wcsave/reader -run TestGolden -update 重新生成快照
+func TestGolden(t *testing.T) {
+	paths, _ := filepath.Glob("../../data/*")
+	if len(paths) == 0 {
+		t.Skip("测试数据文件不存在，跳过回归测试")
+	}
+
+	for _, path := range paths {
+		name := filepath.Base(path)
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("读取测试文件失败: %v", err)
+			}
+			got, err := json.MarshalIndent(parseGolden(t, content, name), "", "  ")
+			if err != nil {
+				t.Fatalf("生成快照失败: %v", err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := filepath.Join("testdata", "golden", name+".json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+					t.Fatalf("创建目录失败: %v", err)
+				}
+				if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+					t.Fatalf("写入快照失败: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("读取快照失败（可使用 -update 生成）: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("解析结果与快照 %s 不一致，确认修改无误后使用 -update 更新\n%s", goldenPath, lineDiff(string(want), string(got)))
+			}
+		})
+	}
+}
+
+// lineDiff 逐行比较，列出不同的行
+func lineDiff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	var builder strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			fmt.Fprintf(&builder, "第%d行:\n  - %s\n  + %s\n", i+1, w, g)
+		}
+	}
+	return builder.String()
+}
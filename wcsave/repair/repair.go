@@ -0,0 +1,306 @@
+package repair
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/check"
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+)
+
+// Fix 一处修复
+type Fix struct {
+	Offset int64
+	Old    []byte // 修复前的字节，文件被截断时可能短于 New
+	New    []byte
+	Reason string
+}
+
+// Result 修复结果
+type Result struct {
+	Content []byte       // 修复后的完整内容
+	Fixes   []Fix        // 按偏移排列的修复列表
+	Report  check.Report // 修复后再次检查的结果
+}
+
+// DefaultReference 返回同一目录中同类存档的第一个文件作为参考（如 Save3.dat 对应 Save0.dat）
+// 文件名不以数字结尾或参考文件就是自身时返回空字符串
+func DefaultReference(filePath string) string {
+	ext := filepath.Ext(filePath)
+	base := strings.TrimSuffix(filepath.Base(filePath), ext)
+	if base == "" || base[len(base)-1] < '0' || base[len(base)-1] > '9' {
+		return ""
+	}
+	reference := filepath.Join(filepath.Dir(filePath), base[:len(base)-1]+"0"+ext)
+	if reference == filepath.Clean(filePath) {
+		return ""
+	}
+	return reference
+}
+
+// Plan 根据参考存档计算修复内容，不修改输入
+//
+// 修复规则:
+//   - 文件截断时用参考存档的对应字节补齐（角色记录区补0，不拼接参考存档的记录），过长时截断
+//   - 从第一条名字无效或不完整的记录开始视为结束，重写结束标记和队伍人数
+//   - 队伍成员编号表中结束标记之后的项清零，缺失或重复的项取参考存档中同名角色的编号
+//   - 超出合理范围的角色字段优先取参考存档中同名角色的值，否则限制在范围内
+//   - 当前生命值、内力值不超过最大值
+//   - 银两超出范围时限制在范围内，地图编号无效时使用参考存档的位置
+func Plan(content, reference []byte) (Result, error) {
+	if len(reference) != models.SaveFileSize {
+		return Result{}, fmt.Errorf("参考存档大小为%d字节，应为%d字节", len(reference), models.SaveFileSize)
+	}
+	if report := check.Bytes("reference", reference); !report.OK() {
+		return Result{}, fmt.Errorf("参考存档本身未通过检查")
+	}
+
+	repaired := make([]byte, models.SaveFileSize)
+	copy(repaired, content)
+	fixes := make([]Fix, 0)
+
+	// 文件大小
+	const areaEnd = models.CharacterStartPosition + models.MaxCharacters*models.CharacterRecordSize
+	if len(content) < models.SaveFileSize {
+		// 角色记录区只保留完整的记录，缺失部分补0，由下面的记录检查清空不完整的记录
+		reason := fmt.Sprintf("文件被截断，用参考存档补齐%d字节", models.SaveFileSize-len(content))
+		if len(content) < areaEnd {
+			reason = fmt.Sprintf("文件被截断，角色记录区补0，其余%d字节用参考存档补齐", models.SaveFileSize-areaEnd)
+		}
+		padStart := max(len(content), areaEnd)
+		copy(repaired[padStart:], reference[padStart:])
+		fixes = append(fixes, Fix{
+			Offset: int64(len(content)),
+			New:    append([]byte(nil), repaired[len(content):]...),
+			Reason: reason,
+		})
+	} else if len(content) > models.SaveFileSize {
+		fixes = append(fixes, Fix{
+			Offset: models.SaveFileSize,
+			Old:    content[models.SaveFileSize:],
+			Reason: fmt.Sprintf("删除多余的%d字节", len(content)-models.SaveFileSize),
+		})
+	}
+
+	// 修改 repaired 中的一段字节并记录
+	set := func(offset int64, data []byte, reason string) {
+		end := offset + int64(len(data))
+		if bytes.Equal(repaired[offset:end], data) {
+			return
+		}
+		old := make([]byte, len(data))
+		copy(old, repaired[offset:end])
+		copy(repaired[offset:end], data)
+		fixes = append(fixes, Fix{Offset: offset, Old: old, New: append([]byte(nil), data...), Reason: reason})
+	}
+
+	// 参考存档中的角色，按名字查找
+	referenceRecords := make(map[string][]byte)
+	for i := 0; i < models.MaxCharacters; i++ {
+		record := recordAt(reference, i)
+		if record[0] == 0 && record[1] == 0 {
+			break
+		}
+		referenceRecords[string(record[:6])] = record
+	}
+
+	// 角色记录
+	count := 0
+	invalid := false
+	for ; count < models.MaxCharacters; count++ {
+		record := recordAt(repaired, count)
+		if record[0] == 0 && record[1] == 0 {
+			break
+		}
+		if len(content) < models.CharacterStartPosition+(count+1)*models.CharacterRecordSize || check.ValidateName(record[:6]) != nil {
+			invalid = true
+			break
+		}
+		if err := repairRecord(record, count, referenceRecords[string(record[:6])], set); err != nil {
+			return Result{}, err
+		}
+	}
+	if count == 0 {
+		return Result{}, fmt.Errorf("第一条角色记录已损坏，无法修复")
+	}
+	if invalid {
+		start := models.CharacterStartPosition + int64(count*models.CharacterRecordSize)
+		end := models.CharacterStartPosition + int64(models.MaxCharacters*models.CharacterRecordSize)
+		set(start, make([]byte, end-start), fmt.Sprintf("第%d条之后的记录无效，清空并写入结束标记", count))
+	}
+	countBuffer := make([]byte, 2)
+	binary.LittleEndian.PutUint16(countBuffer, uint16(count))
+	set(models.PartyCountPosition, countBuffer, fmt.Sprintf("队伍人数设为%d", count))
+
+	if err := reconcileMembers(repaired, len(content), count, reference, set); err != nil {
+		return Result{}, err
+	}
+
+	// 银两
+	money := check.FieldValue(repaired, check.MoneyField)
+	if money < check.MoneyRange.Min || money > check.MoneyRange.Max {
+		moneyBytes, err := check.MoneyField.Encode(check.MoneyRange.Clamp(money))
+		if err != nil {
+			return Result{}, err
+		}
+		set(models.MoneyPosition, moneyBytes, fmt.Sprintf("银两%d超出范围", money))
+	}
+
+	// 队伍位置（地图编号和坐标一起替换）
+	mapID := check.FieldValue(repaired, check.MapField)
+	if mapID < check.MapRange.Min || mapID > check.MapRange.Max {
+		set(models.MapPosition, reference[models.MapPosition:models.MapPosition+4], fmt.Sprintf("地图编号%d无效，使用参考存档的位置", mapID))
+		set(models.MapPosition+models.PositionXOffset, reference[models.MapPosition+models.PositionXOffset:models.MapPosition+models.PositionYOffset+4], "使用参考存档的坐标")
+	}
+
+	sort.SliceStable(fixes, func(i, j int) bool { return fixes[i].Offset < fixes[j].Offset })
+	return Result{Content: repaired, Fixes: fixes, Report: check.Bytes("repaired", repaired)}, nil
+}
+
+// repairRecord 修复单条角色记录中超出范围的字段
+func repairRecord(record []byte, index int, referenceRecord []byte, set func(int64, []byte, string)) error {
+	start := models.CharacterStartPosition + int64(index*models.CharacterRecordSize)
+	name := strings.TrimSpace(layout.Decode(layout.CharacterFields[0], record[:6]))
+
+	for _, field := range layout.CharacterFields {
+		fieldRange, ok := check.FieldRanges[field.Name]
+		if !ok {
+			continue
+		}
+		value := check.FieldValue(record, field)
+		if value >= fieldRange.Min && value <= fieldRange.Max {
+			continue
+		}
+
+		fixed := fieldRange.Clamp(value)
+		source := "限制在合理范围内"
+		if referenceRecord != nil {
+			referenceValue := check.FieldValue(referenceRecord, field)
+			if referenceValue >= fieldRange.Min && referenceValue <= fieldRange.Max {
+				fixed = referenceValue
+				source = "使用参考存档中的值"
+			}
+		}
+		fixedBytes, err := field.Encode(fixed)
+		if err != nil {
+			return fmt.Errorf("修复%s时出错: %w", name, err)
+		}
+		set(start+field.Offset, fixedBytes, fmt.Sprintf("%s的%s为%d，%s", name, field.Label, value, source))
+	}
+
+	// 当前值不超过最大值（此时 record 已包含上面的修复）
+	for _, pair := range check.CurrentMaxPairs {
+		currentField, maximumField := check.CharacterField(pair[0]), check.CharacterField(pair[1])
+		current, maximum := check.FieldValue(record, currentField), check.FieldValue(record, maximumField)
+		if current > maximum {
+			fixedBytes, err := currentField.Encode(maximum)
+			if err != nil {
+				return fmt.Errorf("修复%s时出错: %w", name, err)
+			}
+			set(start+currentField.Offset, fixedBytes, fmt.Sprintf("%s的%s%d大于%s%d", name, currentField.Label, current, maximumField.Label, maximum))
+		}
+	}
+	return nil
+}
+
+// reconcileMembers 使队伍成员编号表与修复后的 count 条角色记录一致
+// 结束标记之后的项清零；原文件中缺失（被截断）或与前面重复的项取参考存档中同名角色的编号，参考存档中没有该角色时返回错误
+func reconcileMembers(repaired []byte, originalSize, count int, reference []byte, set func(int64, []byte, string)) error {
+	referenceIDs := make(map[string][]byte)
+	for i := 0; i < models.MaxCharacters; i++ {
+		record := recordAt(reference, i)
+		if record[0] == 0 && record[1] == 0 {
+			break
+		}
+		offset := models.PartyMemberPosition + i*2
+		referenceIDs[string(record[:6])] = reference[offset : offset+2]
+	}
+
+	seen := make(map[uint16]bool)
+	for i := 0; i < models.MaxCharacters; i++ {
+		offset := models.PartyMemberPosition + i*2
+		if i >= count {
+			set(int64(offset), []byte{0, 0}, fmt.Sprintf("队伍成员编号表第%d项位于结束标记之后，清零", i+1))
+			continue
+		}
+
+		name := string(recordAt(repaired, i)[:6])
+		memberID := binary.LittleEndian.Uint16(repaired[offset:])
+		missing := originalSize < offset+2
+		if missing || seen[memberID] {
+			referenceID, ok := referenceIDs[name]
+			if !ok {
+				return fmt.Errorf("无法确定%s的队伍成员编号，参考存档中没有该角色", strings.TrimSpace(layout.Decode(layout.CharacterFields[0], []byte(name))))
+			}
+			reason := "原文件中缺失"
+			if !missing {
+				reason = fmt.Sprintf("与前面的角色重复（%d）", memberID)
+			}
+			set(int64(offset), referenceID, fmt.Sprintf("队伍成员编号表第%d项%s，使用参考存档中同名角色的编号", i+1, reason))
+			memberID = binary.LittleEndian.Uint16(referenceID)
+			if seen[memberID] {
+				return fmt.Errorf("队伍成员编号表第%d项与前面的角色重复，参考存档也无法确定", i+1)
+			}
+		}
+		seen[memberID] = true
+	}
+	return nil
+}
+
+// recordAt 返回第 index 条角色记录（与 content 共享内存）
+func recordAt(content []byte, index int) []byte {
+	start := models.CharacterStartPosition + index*models.CharacterRecordSize
+	return content[start : start+models.CharacterRecordSize]
+}
+
+// File 读取存档和参考存档并计算修复内容
+func File(filePath, referencePath string) (Result, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return Result{}, err
+	}
+	reference, err := os.ReadFile(referencePath)
+	if err != nil {
+		return Result{}, fmt.Errorf("读取参考存档失败: %v", err)
+	}
+	return Plan(content, reference)
+}
+
+// Write 将修复后的内容写入 destFilePath，目标文件已存在时先用 wcsave.BackupFile 备份
+// 返回备份文件路径，目标文件原来不存在时为空
+func (r Result) Write(destFilePath string) (string, error) {
+	backupPath := ""
+	if _, err := os.Stat(destFilePath); err == nil {
+		backupPath, err = wcsave.BackupFile(destFilePath)
+		if err != nil {
+			return "", fmt.Errorf("备份%s失败: %w", destFilePath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	return backupPath, os.WriteFile(destFilePath, r.Content, 0644)
+}
+
+// String 返回修复的可读描述
+func (f Fix) String() string {
+	return fmt.Sprintf("@0x%05X %s\n    - %s\n    + %s", f.Offset, f.Reason, hexPreview(f.Old), hexPreview(f.New))
+}
+
+// hexPreview 以十六进制显示字节，过长时截断
+func hexPreview(data []byte) string {
+	const limit = 16
+	if len(data) == 0 {
+		return "(无)"
+	}
+	if len(data) > limit {
+		return fmt.Sprintf("% X ...（共%d字节）", data[:limit], len(data))
+	}
+	return fmt.Sprintf("% X", data)
+}
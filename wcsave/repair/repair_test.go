@@ -0,0 +1,205 @@
+package repair
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"wcediter/wcsave/models"
+	"wcediter/wcsave/testsave"
+)
+
+// 生成测试存档
+func testSaveBytes(t *testing.T, save testsave.Save) []byte {
+	t.Helper()
+	content, err := save.Bytes()
+	if err != nil {
+		t.Fatalf("生成测试存档失败: %v", err)
+	}
+	return content
+}
+
+// 参考存档：游戏开始时只有一个角色，与 testsave.Default 的第一个角色不同
+func referenceSave(t *testing.T) []byte {
+	save := testsave.Default()
+	save.Party = []testsave.Character{testsave.NewCharacter("一頁書", 1)}
+	return testSaveBytes(t, save)
+}
+
+// 测试正常存档不需要修复
+func TestPlanHealthy(t *testing.T) {
+	single := testsave.Default()
+	single.Party = single.Party[:1]
+	full := testsave.Default()
+	full.Party = append(full.Party, testsave.NewCharacter("素還真", 30), testsave.NewCharacter("雄霸", 45))
+	full.Party[3].MemberID, full.Party[4].MemberID = 3, 4
+	for name, save := range map[string]testsave.Save{"三人": testsave.Default(), "一人": single, "五人": full} {
+		result, err := Plan(testSaveBytes(t, save), referenceSave(t))
+		if err != nil {
+			t.Fatalf("%s: Plan失败: %v", name, err)
+		}
+		if len(result.Fixes) != 0 {
+			t.Errorf("%s: 正常存档不应有修复: %v", name, result.Fixes)
+		}
+	}
+}
+
+// 测试修复各类损坏
+func TestPlanCorrupted(t *testing.T) {
+	original := testSaveBytes(t, testsave.Default())
+	reference := referenceSave(t)
+
+	content := append([]byte(nil), original...)
+	// 第三个角色的名字被破坏
+	copy(content[models.CharacterStartPosition+2*models.CharacterRecordSize:], []byte{0xFF, 0x01, 0x02, 0x03, 0x04, 0x05})
+	// 第一个角色的等级和当前生命值异常
+	binary.LittleEndian.PutUint16(content[models.CharacterStartPosition+70:], 0xFFFF)
+	binary.LittleEndian.PutUint32(content[models.CharacterStartPosition+24:], 0x7FFFFFFF)
+	// 银两为负数
+	binary.LittleEndian.PutUint32(content[models.MoneyPosition:], 0xFFFFFFFF)
+	// 文件末尾被截断
+	content = content[:models.SaveFileSize-100]
+
+	result, err := Plan(content, reference)
+	if err != nil {
+		t.Fatalf("Plan失败: %v", err)
+	}
+	if !result.Report.OK() || len(result.Report.Issues) != 0 {
+		t.Errorf("修复后仍有问题: %v", result.Report.Issues)
+	}
+	if result.Report.Characters != 2 {
+		t.Errorf("修复后角色数量错误，预期2，实际%d", result.Report.Characters)
+	}
+	if len(result.Content) != models.SaveFileSize {
+		t.Errorf("修复后文件大小错误: %d", len(result.Content))
+	}
+
+	// 第一个角色在参考存档中不存在时等级被限制在范围内，生命值不超过最大值
+	record := result.Content[models.CharacterStartPosition:]
+	if level := int16(binary.LittleEndian.Uint16(record[70:])); level != 1 {
+		t.Errorf("等级修复错误: %d", level)
+	}
+	if binary.LittleEndian.Uint32(record[24:]) != binary.LittleEndian.Uint32(record[16:]) {
+		t.Error("当前生命值应修复为最大生命值")
+	}
+	if money := int32(binary.LittleEndian.Uint32(result.Content[models.MoneyPosition:])); money != 0 {
+		t.Errorf("银两修复错误: %d", money)
+	}
+
+	// 修复不修改输入
+	if content[models.CharacterStartPosition+70] != 0xFF {
+		t.Error("Plan不应修改输入内容")
+	}
+	for i := 1; i < len(result.Fixes); i++ {
+		if result.Fixes[i].Offset < result.Fixes[i-1].Offset {
+			t.Error("修复列表应按偏移排序")
+		}
+	}
+}
+
+// 测试文件在角色记录中间被截断时只保留完整的记录，并补全队伍成员编号表
+func TestPlanTruncatedRecord(t *testing.T) {
+	original := testSaveBytes(t, testsave.Default())
+	single := testsave.Default()
+	single.Party = single.Party[:1]
+	reference := testSaveBytes(t, single)
+
+	content := original[:models.CharacterStartPosition+models.CharacterRecordSize+40]
+	result, err := Plan(content, reference)
+	if err != nil {
+		t.Fatalf("Plan失败: %v", err)
+	}
+	if !result.Report.OK() || result.Report.Characters != 1 {
+		t.Errorf("修复后应只剩一个完整的角色: %d, %v", result.Report.Characters, result.Report.Issues)
+	}
+	second := result.Content[models.CharacterStartPosition+models.CharacterRecordSize:][:models.CharacterRecordSize]
+	for i, b := range second {
+		if b != 0 {
+			t.Fatalf("不完整的记录应被清空，第%d字节为%02X", i, b)
+		}
+	}
+	if id := binary.LittleEndian.Uint16(result.Content[models.PartyMemberPosition:]); id != 0 {
+		t.Errorf("缺失的成员编号应取自参考存档，实际%d", id)
+	}
+
+	// 参考存档中没有同名角色时无法确定成员编号
+	if _, err := Plan(content, referenceSave(t)); err == nil {
+		t.Error("无法确定成员编号时应该返回错误")
+	}
+}
+
+// 测试队伍成员编号表的修复
+func TestPlanMembers(t *testing.T) {
+	original := testSaveBytes(t, testsave.Default())
+	content := append([]byte(nil), original...)
+	// 第三个角色的编号与第二个重复，结束标记之后的项不为0
+	binary.LittleEndian.PutUint16(content[models.PartyMemberPosition+4:], 8)
+	binary.LittleEndian.PutUint16(content[models.PartyMemberPosition+6:], 5)
+
+	result, err := Plan(content, original)
+	if err != nil {
+		t.Fatalf("Plan失败: %v", err)
+	}
+	for i, want := range []uint16{0, 8, 9, 0, 0} {
+		if id := binary.LittleEndian.Uint16(result.Content[models.PartyMemberPosition+i*2:]); id != want {
+			t.Errorf("成员编号表第%d项应为%d，实际%d", i+1, want, id)
+		}
+	}
+	if len(result.Fixes) != 2 {
+		t.Errorf("应有2处修复，实际: %v", result.Fixes)
+	}
+}
+
+// 测试无法修复的情况
+func TestPlanErrors(t *testing.T) {
+	content := testSaveBytes(t, testsave.Default())
+	if _, err := Plan(content, content[:100]); err == nil {
+		t.Error("参考存档大小错误时应该返回错误")
+	}
+
+	broken := append([]byte(nil), content...)
+	copy(broken[models.CharacterStartPosition:], []byte{0xFF, 0x01})
+	if _, err := Plan(broken, content); err == nil {
+		t.Error("第一条记录损坏时应该返回错误")
+	}
+}
+
+// 测试默认参考存档
+func TestDefaultReference(t *testing.T) {
+	tests := map[string]string{
+		filepath.Join("saves", "Save3.dat"): filepath.Join("saves", "Save0.dat"),
+		filepath.Join("saves", "Sav03.dat"): filepath.Join("saves", "Sav00.dat"),
+		filepath.Join("saves", "Save0.dat"): "",
+		"backup.dat":                        "",
+	}
+	for input, expected := range tests {
+		if actual := DefaultReference(input); actual != expected {
+			t.Errorf("DefaultReference(%s) = %s，预期%s", input, actual, expected)
+		}
+	}
+}
+
+// 测试写入修复结果时备份已有的目标文件
+func TestResultWrite(t *testing.T) {
+	destPath := filepath.Join(t.TempDir(), "Save3.dat")
+	result := Result{Content: testSaveBytes(t, testsave.Default())}
+	if backupPath, err := result.Write(destPath); err != nil || backupPath != "" {
+		t.Fatalf("目标文件不存在时不应备份: %q, %v", backupPath, err)
+	}
+
+	old, _ := os.ReadFile(destPath)
+	result.Content = append([]byte(nil), result.Content...)
+	result.Content[models.MoneyPosition] ^= 0xFF
+	backupPath, err := result.Write(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup, err := os.ReadFile(backupPath); err != nil || !bytes.Equal(backup, old) {
+		t.Errorf("备份内容错误: %v", err)
+	}
+	if written, _ := os.ReadFile(destPath); !bytes.Equal(written, result.Content) {
+		t.Error("写入的内容错误")
+	}
+}
@@ -0,0 +1,215 @@
+package script
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+
+	"github.com/dop251/goja"
+)
+
+// DefaultTimeout 脚本默认的最长运行时间
+const DefaultTimeout = 5 * time.Second
+
+// Options 脚本运行选项
+type Options struct {
+	Timeout time.Duration // 最长运行时间，为0时使用 DefaultTimeout
+	Output  io.Writer     // log() 的输出，为 nil 时丢弃
+}
+
+// Result 脚本运行结果
+type Result struct {
+	Characters []string // 被修改的角色名
+	Money      bool     // 是否修改了银两
+	Position   bool     // 是否修改了队伍位置
+}
+
+// characterFields models.CharacterData 的字段列表
+var characterFields = func() []reflect.StructField {
+	t := reflect.TypeOf(models.CharacterData{})
+	fields := make([]reflect.StructField, t.NumField())
+	for i := range fields {
+		fields[i] = t.Field(i)
+	}
+	return fields
+}()
+
+// Run 在沙箱中运行脚本，并将脚本对角色、银两和位置的修改写回编辑器
+//
+// 脚本可使用的全局变量:
+//
+//	characters  角色数组，每个角色包含 index、name 以及 models.CharacterData 的各字段（如 Level、Attack）
+//	            可以修改角色的字段或整体替换角色，替换的对象需包含全部字段；角色数量不能改变
+//	money       银两，可直接赋值
+//	position    队伍位置 {mapID, x, y}，mapID 为游戏内部的地图编号，与 progress 的 locationID 不是同一套编号
+//	progress    WC.cfg 中的进度列表 [{progressID, locationID, locationName}]（只读）
+//	log(...)    输出调试信息
+//
+// 脚本运行环境中没有文件、网络等访问能力，超时后会被中断
+func Run(editor *wcsave.SaveEditor, source string, opts Options) (Result, error) {
+	var result Result
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	vm := goja.New()
+
+	// 绑定角色
+	characterValues := make([]interface{}, len(editor.Characters))
+	for i, char := range editor.Characters {
+		obj := vm.NewObject()
+		obj.Set("index", i+1)
+		obj.Set("name", strings.TrimSpace(char.Name))
+		data := reflect.ValueOf(char.Data)
+		for f, field := range characterFields {
+			obj.Set(field.Name, data.Field(f).Int())
+		}
+		characterValues[i] = obj
+	}
+	vm.Set("characters", vm.NewArray(characterValues...))
+
+	// 绑定银两和位置
+	vm.Set("money", editor.MoneyInfo.Value)
+	position := vm.NewObject()
+	position.Set("mapID", editor.PositionInfo.MapID)
+	position.Set("x", editor.PositionInfo.X)
+	position.Set("y", editor.PositionInfo.Y)
+	vm.Set("position", position)
+
+	// 绑定进度（冻结为只读）
+	progressValues := make([]interface{}, len(editor.ProgressInfos))
+	for i, info := range editor.ProgressInfos {
+		obj := vm.NewObject()
+		obj.Set("progressID", info.ProgressID)
+		obj.Set("locationID", info.LocationID)
+		obj.Set("locationName", strings.TrimSpace(info.LocationName))
+		progressValues[i] = obj
+	}
+	vm.Set("progress", vm.NewArray(progressValues...))
+	if _, err := vm.RunString("Object.freeze(progress); progress.forEach(Object.freeze);"); err != nil {
+		return result, err
+	}
+
+	// 绑定日志输出
+	vm.Set("log", func(call goja.FunctionCall) goja.Value {
+		if opts.Output != nil {
+			parts := make([]string, len(call.Arguments))
+			for i, arg := range call.Arguments {
+				parts[i] = arg.String()
+			}
+			fmt.Fprintln(opts.Output, strings.Join(parts, " "))
+		}
+		return goja.Undefined()
+	})
+
+	// 超时中断
+	timer := time.AfterFunc(timeout, func() {
+		vm.Interrupt(fmt.Sprintf("脚本运行超时（%v）", timeout))
+	})
+	defer timer.Stop()
+
+	if _, err := vm.RunString(source); err != nil {
+		return result, fmt.Errorf("脚本运行失败: %v", err)
+	}
+
+	// 先全部校验，再写回编辑器，避免部分修改
+	characterObjects, err := scriptCharacters(vm, len(editor.Characters))
+	if err != nil {
+		return result, err
+	}
+	newData := make([]models.CharacterData, len(editor.Characters))
+	for i, obj := range characterObjects {
+		data := reflect.ValueOf(&newData[i]).Elem()
+		for f, field := range characterFields {
+			value, err := toInt(obj.Get(field.Name), field.Type.Bits())
+			if err != nil {
+				return result, fmt.Errorf("角色%s的%s: %v", editor.Characters[i].Name, field.Name, err)
+			}
+			data.Field(f).SetInt(value)
+		}
+	}
+	money, err := toInt(vm.Get("money"), 32)
+	if err != nil {
+		return result, fmt.Errorf("money: %v", err)
+	}
+	var coords [3]int64
+	for i, name := range []string{"mapID", "x", "y"} {
+		coords[i], err = toInt(position.Get(name), 32)
+		if err != nil {
+			return result, fmt.Errorf("position.%s: %v", name, err)
+		}
+	}
+
+	moneyChanged := int32(money) != editor.MoneyInfo.Value
+	if moneyChanged && editor.MoneyInfo.Position == 0 {
+		return result, fmt.Errorf("存档中没有银两数据")
+	}
+	positionChanged := int32(coords[0]) != editor.PositionInfo.MapID || int32(coords[1]) != editor.PositionInfo.X || int32(coords[2]) != editor.PositionInfo.Y
+	if positionChanged && editor.PositionInfo.Position == 0 {
+		return result, fmt.Errorf("存档中没有位置数据")
+	}
+
+	for i, data := range newData {
+		if data != editor.Characters[i].Data {
+			editor.UpdateCharacter(i, data)
+			result.Characters = append(result.Characters, editor.Characters[i].Name)
+		}
+	}
+	if moneyChanged {
+		editor.UpdateMoney(int32(money))
+		result.Money = true
+	}
+	if positionChanged {
+		editor.UpdatePosition(int32(coords[0]), int32(coords[1]), int32(coords[2]))
+		result.Position = true
+	}
+
+	return result, nil
+}
+
+// scriptCharacters 读取脚本运行后的 characters 数组
+// 数组中的角色可以被整体替换（如 characters[0] = {...characters[0], Level: 99}），但角色数量不能改变
+func scriptCharacters(vm *goja.Runtime, count int) ([]*goja.Object, error) {
+	value := vm.Get("characters")
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil, fmt.Errorf("characters 不能被删除或置空")
+	}
+	array := value.ToObject(vm)
+	if array.ClassName() != "Array" {
+		return nil, fmt.Errorf("characters 应为数组")
+	}
+	if length := array.Get("length").ToInteger(); length != int64(count) {
+		return nil, fmt.Errorf("characters 的角色数量不能改变: %d -> %d", count, length)
+	}
+
+	objects := make([]*goja.Object, count)
+	for i := range objects {
+		entry := array.Get(strconv.Itoa(i))
+		if entry == nil || goja.IsUndefined(entry) || goja.IsNull(entry) {
+			return nil, fmt.Errorf("characters[%d] 不能为空", i)
+		}
+		objects[i] = entry.ToObject(vm)
+	}
+	return objects, nil
+}
+
+// toInt 将脚本中的数值转换为整数，超出字段位数可表示的范围时返回错误
+func toInt(value goja.Value, bits int) (int64, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return 0, fmt.Errorf("值为空")
+	}
+	number := value.ToFloat()
+	if math.IsNaN(number) {
+		return 0, fmt.Errorf("不是数值: %s", value.String())
+	}
+	return layout.IntValue(number, bits)
+}
@@ -0,0 +1,148 @@
+package script
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/models"
+)
+
+// 创建包含两个角色的测试编辑器
+func newTestEditor() *wcsave.SaveEditor {
+	editor := wcsave.NewSaveEditor()
+	editor.Characters = []models.CharacterInfo{
+		{Name: "步驚雲", Data: models.CharacterData{Level: 10, Attack: 50, MaxHP: 500, CurrentHP: 100}},
+		{Name: "聶  風", Data: models.CharacterData{Level: 8, Attack: 40, MaxHP: 400, CurrentHP: 400}},
+	}
+	editor.MoneyInfo = models.MoneyInfo{Value: 1000, RawBytes: make([]byte, 4), Position: models.MoneyPosition}
+	editor.PositionInfo = models.PositionInfo{MapID: 1, X: 10, Y: 20, RawBytes: make([]byte, 12), Position: models.MapPosition}
+	editor.ProgressInfos = []models.ProgressInfo{{ProgressID: 3, LocationID: 7, LocationName: "天下會"}}
+	return editor
+}
+
+// 测试脚本修改角色、银两和位置
+func TestRun(t *testing.T) {
+	editor := newTestEditor()
+	var output bytes.Buffer
+	source := `
+characters.forEach(function (c) {
+	c.Attack = c.Level * 2;
+	c.CurrentHP = c.MaxHP;
+});
+money += progress[0].locationID;
+position.x = 99;
+log("完成", characters.length);
+`
+	result, err := Run(editor, source, Options{Output: &output})
+	if err != nil {
+		t.Fatalf("Run失败: %v", err)
+	}
+
+	if editor.Characters[0].Data.Attack != 20 || editor.Characters[1].Data.Attack != 16 {
+		t.Errorf("攻击修改错误: %d, %d", editor.Characters[0].Data.Attack, editor.Characters[1].Data.Attack)
+	}
+	if editor.Characters[0].Data.CurrentHP != 500 {
+		t.Errorf("生命值修改错误: %d", editor.Characters[0].Data.CurrentHP)
+	}
+	if editor.MoneyInfo.Value != 1007 || !result.Money {
+		t.Errorf("银两修改错误: %d", editor.MoneyInfo.Value)
+	}
+	if editor.PositionInfo.X != 99 || editor.PositionInfo.Y != 20 || !result.Position {
+		t.Errorf("位置修改错误: %+v", editor.PositionInfo)
+	}
+	if len(result.Characters) != 2 {
+		t.Errorf("被修改的角色错误: %v", result.Characters)
+	}
+	if strings.TrimSpace(output.String()) != "完成 2" {
+		t.Errorf("日志输出错误: %q", output.String())
+	}
+}
+
+// 测试超出字段范围的数值返回错误，且不修改编辑器
+func TestRunOutOfRange(t *testing.T) {
+	editor := newTestEditor()
+	level, money := editor.Characters[0].Data.Level, editor.MoneyInfo.Value
+	if _, err := Run(editor, `characters[0].Level = 1e9;`, Options{}); err == nil {
+		t.Error("等级超出int16范围时应该返回错误")
+	}
+	if _, err := Run(editor, `characters[0].Level = 32767; money = -1e12;`, Options{}); err == nil {
+		t.Error("银两超出int32范围时应该返回错误")
+	}
+	if editor.Characters[0].Data.Level != level || editor.MoneyInfo.Value != money {
+		t.Errorf("出错时不应修改编辑器: 等级%d，银两%d", editor.Characters[0].Data.Level, editor.MoneyInfo.Value)
+	}
+
+	// 边界值和小数（四舍五入）可以写入
+	if _, err := Run(editor, `characters[0].Level = 32767; money = -2147483648.4;`, Options{}); err != nil {
+		t.Fatalf("Run失败: %v", err)
+	}
+	if editor.Characters[0].Data.Level != 32767 || editor.MoneyInfo.Value != -2147483648 {
+		t.Errorf("边界值写入错误: 等级%d，银两%d", editor.Characters[0].Data.Level, editor.MoneyInfo.Value)
+	}
+}
+
+// 测试整体替换角色
+func TestRunReplaceCharacter(t *testing.T) {
+	editor := newTestEditor()
+	result, err := Run(editor, `characters[0] = {...characters[0], Level: 99}; characters = characters.map(c => ({...c, Luck: 7}));`, Options{})
+	if err != nil {
+		t.Fatalf("Run失败: %v", err)
+	}
+	if editor.Characters[0].Data.Level != 99 {
+		t.Errorf("替换的角色未写回，等级%d", editor.Characters[0].Data.Level)
+	}
+	for _, char := range editor.Characters {
+		if char.Data.Luck != 7 {
+			t.Errorf("%s的运气未写回: %d", char.Name, char.Data.Luck)
+		}
+	}
+	if len(result.Characters) != len(editor.Characters) {
+		t.Errorf("修改的角色错误: %v", result.Characters)
+	}
+}
+
+// 测试脚本错误不会修改编辑器
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"语法错误", `characters[0].Level = ;`},
+		{"运行时异常", `characters[0].Level = 1; throw new Error("失败");`},
+		{"非数值", `characters[0].Level = 1; characters[1].Attack = "abc";`},
+		{"修改进度", `"use strict"; characters[0].Level = 1; progress[0].locationID = 1;`},
+		{"无法访问外部", `characters[0].Level = 1; require("fs");`},
+		{"替换的角色缺少字段", `characters[0] = {Level: 1};`},
+		{"改变角色数量", `characters[0].Level = 1; characters.push({...characters[0]});`},
+		{"角色置空", `characters[0].Level = 1; characters[1] = null;`},
+		{"替换角色数组", `characters = 1;`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor := newTestEditor()
+			if _, err := Run(editor, tt.source, Options{}); err == nil {
+				t.Fatal("应该返回错误")
+			}
+			if editor.Characters[0].Data.Level != 10 {
+				t.Errorf("出错时不应修改角色: %d", editor.Characters[0].Data.Level)
+			}
+		})
+	}
+}
+
+// 测试死循环会被超时中断
+func TestRunTimeout(t *testing.T) {
+	editor := newTestEditor()
+	start := time.Now()
+	_, err := Run(editor, `for (;;) {}`, Options{Timeout: 100 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "超时") {
+		t.Fatalf("应该返回超时错误，实际: %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("超时中断耗时过长: %v", time.Since(start))
+	}
+}
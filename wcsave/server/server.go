@@ -0,0 +1,410 @@
+package server
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// Server 以 HTTP/JSON 接口提供存档的读取与修改，所有路径都限制在 Root 目录内
+type Server struct {
+	Root string
+
+	mu sync.Mutex // 保证 ETag 校验与写入之间不会被其他请求插入
+}
+
+// SaveFile 存档文件列表项
+type SaveFile struct {
+	Path    string    `json:"path"` // 相对于根目录的路径
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// Character 角色数据
+type Character struct {
+	Index int                  `json:"index"` // 从1开始
+	Name  string               `json:"name"`
+	Data  models.CharacterData `json:"data"`
+}
+
+// Position 队伍位置
+type Position struct {
+	MapID int32 `json:"mapID"` // 游戏内部的地图编号，不是位置名称表的下标
+	X     int32 `json:"x"`
+	Y     int32 `json:"y"`
+}
+
+// Save 存档内容
+type Save struct {
+	Path       string      `json:"path"`
+	ETag       string      `json:"etag"`
+	Characters []Character `json:"characters"`
+	Money      *int32      `json:"money,omitempty"`
+	Position   *Position   `json:"position,omitempty"`
+	Backup     string      `json:"backup,omitempty"` // 保存修改时生成的备份文件
+}
+
+// CharacterEdit 单个角色的修改，Data 只需包含要修改的字段
+type CharacterEdit struct {
+	Index int              `json:"index"`
+	Data  map[string]int64 `json:"data"`
+}
+
+// Edit 存档修改请求
+type Edit struct {
+	Characters []CharacterEdit `json:"characters,omitempty"`
+	Money      *int32          `json:"money,omitempty"`
+	Position   *Position       `json:"position,omitempty"`
+}
+
+// Progress WC.cfg 中的进度信息
+type Progress struct {
+	Slot         int    `json:"slot"` // 从1开始，对应 xxx1.dat ~ xxx5.dat
+	ProgressID   int    `json:"progressID"`
+	LocationID   int    `json:"locationID"`
+	LocationName string `json:"locationName"`
+}
+
+// apiError 错误响应
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// NewServer 创建服务，root 为允许访问的存档根目录
+func NewServer(root string) (*Server, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	absRoot, err = filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(absRoot)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s 不是目录", root)
+	}
+	return &Server{Root: absRoot}, nil
+}
+
+// Handler 返回注册了所有接口的 http.Handler
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/openapi.json", s.handleOpenAPI)
+	mux.HandleFunc("GET /api/saves", s.handleListSaves)
+	mux.HandleFunc("GET /api/saves/{path...}", s.handleGetSave)
+	mux.HandleFunc("PATCH /api/saves/{path...}", s.handlePatchSave)
+	mux.HandleFunc("GET /api/progress/{path...}", s.handleGetProgress)
+	return mux
+}
+
+// resolve 将请求中的相对路径转换为根目录内的绝对路径，拒绝越界访问
+func (s *Server) resolve(relPath string) (string, error) {
+	if relPath == "" || filepath.IsAbs(relPath) || strings.Contains(relPath, "\\") {
+		return "", fmt.Errorf("无效的路径: %s", relPath)
+	}
+	cleaned := filepath.Clean(filepath.FromSlash(relPath))
+	if cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("路径超出根目录: %s", relPath)
+	}
+
+	fullPath := filepath.Join(s.Root, cleaned)
+
+	// 解析符号链接后再次确认仍在根目录内
+	realPath, err := filepath.EvalSymlinks(fullPath)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(s.Root, realPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("路径超出根目录: %s", relPath)
+	}
+	return realPath, nil
+}
+
+// computeETag 根据文件内容计算 ETag
+func computeETag(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		log.Printf("输出响应失败: %v", err)
+	}
+}
+
+// writeError 输出错误响应
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+// statusForPathError 路径不存在返回404，其余返回400
+func statusForPathError(err error) int {
+	if os.IsNotExist(err) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(openAPISpec)
+}
+
+// handleListSaves 列出根目录下所有 .dat 存档
+func (s *Server) handleListSaves(w http.ResponseWriter, r *http.Request) {
+	files := make([]SaveFile, 0)
+	err := filepath.WalkDir(s.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".dat") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.Root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, SaveFile{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	writeJSON(w, http.StatusOK, files)
+}
+
+// readSave 读取存档并转换为响应结构
+func (s *Server) readSave(relPath, filePath string) (*wcsave.SaveEditor, Save, error) {
+	editor := wcsave.NewSaveEditor()
+	if err := editor.ReadSave(filePath); err != nil {
+		return nil, Save{}, err
+	}
+	etag, err := computeETag(filePath)
+	if err != nil {
+		return nil, Save{}, err
+	}
+
+	save := Save{Path: relPath, ETag: etag, Characters: make([]Character, 0, len(editor.Characters))}
+	for i, char := range editor.Characters {
+		save.Characters = append(save.Characters, Character{Index: i + 1, Name: strings.TrimSpace(char.Name), Data: char.Data})
+	}
+	if editor.MoneyInfo.Position != 0 {
+		money := editor.MoneyInfo.Value
+		save.Money = &money
+	}
+	if editor.PositionInfo.Position != 0 {
+		save.Position = &Position{
+			MapID: editor.PositionInfo.MapID,
+			X:     editor.PositionInfo.X,
+			Y:     editor.PositionInfo.Y,
+		}
+	}
+	return editor, save, nil
+}
+
+// handleGetSave 读取单个存档的角色、银两和位置
+func (s *Server) handleGetSave(w http.ResponseWriter, r *http.Request) {
+	relPath := r.PathValue("path")
+	filePath, err := s.resolve(relPath)
+	if err != nil {
+		writeError(w, statusForPathError(err), err)
+		return
+	}
+
+	_, save, err := s.readSave(relPath, filePath)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("读取存档失败: %v", err))
+		return
+	}
+
+	w.Header().Set("ETag", save.ETag)
+	if match := r.Header.Get("If-None-Match"); match != "" && match == save.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, save)
+}
+
+// handlePatchSave 应用修改并保存，需要 If-Match 头与当前 ETag 一致
+func (s *Server) handlePatchSave(w http.ResponseWriter, r *http.Request) {
+	relPath := r.PathValue("path")
+	filePath, err := s.resolve(relPath)
+	if err != nil {
+		writeError(w, statusForPathError(err), err)
+		return
+	}
+
+	var edit Edit
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&edit); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("请求格式错误: %v", err))
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeError(w, http.StatusPreconditionRequired, fmt.Errorf("修改存档需要 If-Match 头"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	editor, save, err := s.readSave(relPath, filePath)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("读取存档失败: %v", err))
+		return
+	}
+	if ifMatch != "*" && ifMatch != save.ETag {
+		w.Header().Set("ETag", save.ETag)
+		writeError(w, http.StatusPreconditionFailed, fmt.Errorf("存档已被修改，请重新读取后再提交"))
+		return
+	}
+
+	if err := applyEdit(editor, edit); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// 保存前先备份原文件
+	backupPath, err := wcsave.BackupFile(filePath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("备份存档失败: %v", err))
+		return
+	}
+
+	if err := editor.SaveChanges(filePath, filePath); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("保存存档失败: %v", err))
+		return
+	}
+
+	_, save, err = s.readSave(relPath, filePath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("重新读取存档失败: %v", err))
+		return
+	}
+	save.Backup, _ = filepath.Rel(s.Root, backupPath)
+	save.Backup = filepath.ToSlash(save.Backup)
+
+	w.Header().Set("ETag", save.ETag)
+	writeJSON(w, http.StatusOK, save)
+}
+
+// handleGetProgress 读取目录中 WC.cfg 的进度信息
+func (s *Server) handleGetProgress(w http.ResponseWriter, r *http.Request) {
+	relPath := r.PathValue("path")
+	dirPath, err := s.resolve(relPath)
+	if err != nil {
+		writeError(w, statusForPathError(err), err)
+		return
+	}
+	cfgPath := dirPath
+	if info, err := os.Stat(dirPath); err == nil && info.IsDir() {
+		cfgPath = filepath.Join(dirPath, "WC.cfg")
+	}
+
+	progressInfos, err := wcsave.NewSaveEditor().ReadProgress(cfgPath)
+	if err != nil {
+		writeError(w, statusForPathError(err), fmt.Errorf("读取进度文件失败: %v", err))
+		return
+	}
+
+	progress := make([]Progress, 0, len(progressInfos))
+	for i, info := range progressInfos {
+		progress = append(progress, Progress{
+			Slot:         i + 1,
+			ProgressID:   info.ProgressID,
+			LocationID:   info.LocationID,
+			LocationName: strings.TrimSpace(info.LocationName),
+		})
+	}
+	writeJSON(w, http.StatusOK, progress)
+}
+
+// applyEdit 校验并应用修改，任何字段无效时不修改编辑器
+func applyEdit(editor *wcsave.SaveEditor, edit Edit) error {
+	newData := make(map[int]models.CharacterData)
+	for _, charEdit := range edit.Characters {
+		index := charEdit.Index - 1
+		char, ok := editor.GetCharacterByIndex(index)
+		if !ok {
+			return fmt.Errorf("无效的角色序号: %d", charEdit.Index)
+		}
+		data, exists := newData[index]
+		if !exists {
+			data = char.Data
+		}
+
+		value := reflect.ValueOf(&data).Elem()
+		for name, v := range charEdit.Data {
+			field := value.FieldByName(name)
+			if !field.IsValid() {
+				return fmt.Errorf("未知的字段: %s", name)
+			}
+			if _, err := layout.IntValue(float64(v), field.Type().Bits()); err != nil {
+				return fmt.Errorf("字段%s: %v", name, err)
+			}
+			field.SetInt(v)
+		}
+		newData[index] = data
+	}
+	if edit.Money != nil && editor.MoneyInfo.Position == 0 {
+		return fmt.Errorf("存档中没有银两数据")
+	}
+	if edit.Position != nil && editor.PositionInfo.Position == 0 {
+		return fmt.Errorf("存档中没有位置数据")
+	}
+
+	for index, data := range newData {
+		editor.UpdateCharacter(index, data)
+	}
+	if edit.Money != nil {
+		editor.UpdateMoney(*edit.Money)
+	}
+	if edit.Position != nil {
+		editor.UpdatePosition(edit.Position.MapID, edit.Position.X, edit.Position.Y)
+	}
+	return nil
+}
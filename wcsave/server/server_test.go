@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"wcediter/wcsave/testsave"
+)
+
+// 创建包含测试存档的根目录
+func newTestServer(t *testing.T) (*Server, string) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "Save"), 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	saves := map[string]testsave.Save{"Save4.dat": testsave.Default()}
+	if err := testsave.WriteDir(filepath.Join(root, "Save"), testsave.DefaultConfig(), saves); err != nil {
+		t.Fatalf("生成测试存档失败: %v", err)
+	}
+
+	srv, err := NewServer(root)
+	if err != nil {
+		t.Fatalf("NewServer失败: %v", err)
+	}
+	return srv, root
+}
+
+// 发送请求并返回响应
+func doRequest(t *testing.T, handler http.Handler, method, path string, body interface{}, header map[string]string) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("编码请求失败: %v", err)
+		}
+		reader = bytes.NewReader(content)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// 测试列出和读取存档
+func TestListAndGet(t *testing.T) {
+	srv, _ := newTestServer(t)
+	handler := srv.Handler()
+
+	rec := doRequest(t, handler, "GET", "/api/saves", nil, nil)
+	var files []SaveFile
+	if err := json.Unmarshal(rec.Body.Bytes(), &files); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "Save/Save4.dat" {
+		t.Fatalf("存档列表错误: %+v", files)
+	}
+
+	rec = doRequest(t, handler, "GET", "/api/saves/Save/Save4.dat", nil, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("状态码错误: %d %s", rec.Code, rec.Body.String())
+	}
+	var save Save
+	if err := json.Unmarshal(rec.Body.Bytes(), &save); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if len(save.Characters) == 0 || save.Money == nil || save.Position == nil {
+		t.Errorf("存档内容不完整: %+v", save)
+	}
+	if rec.Header().Get("ETag") != save.ETag || save.ETag == "" {
+		t.Errorf("ETag错误: %q", rec.Header().Get("ETag"))
+	}
+
+	rec = doRequest(t, handler, "GET", "/api/saves/Save/Save4.dat", nil, map[string]string{"If-None-Match": save.ETag})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("未修改时应返回304，实际%d", rec.Code)
+	}
+
+	rec = doRequest(t, handler, "GET", "/api/progress/Save", nil, nil)
+	var progress []Progress
+	if err := json.Unmarshal(rec.Body.Bytes(), &progress); err != nil || len(progress) != 5 {
+		t.Errorf("进度信息错误: %s", rec.Body.String())
+	}
+
+	rec = doRequest(t, handler, "GET", "/api/openapi.json", nil, nil)
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Error("OpenAPI 文档不是有效的 JSON")
+	}
+}
+
+// 测试路径不能超出根目录
+func TestPathConfinement(t *testing.T) {
+	srv, root := newTestServer(t)
+	outside := filepath.Join(filepath.Dir(root), "outside.dat")
+	if err := os.WriteFile(outside, []byte("x"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	defer os.Remove(outside)
+	if err := os.Symlink(outside, filepath.Join(root, "link.dat")); err != nil {
+		t.Fatalf("创建符号链接失败: %v", err)
+	}
+
+	for _, relPath := range []string{"../outside.dat", "Save/../../outside.dat", "link.dat", outside} {
+		if _, err := srv.resolve(relPath); err == nil {
+			t.Errorf("%s 应该被拒绝", relPath)
+		}
+	}
+	if _, err := srv.resolve("Save/Save4.dat"); err != nil {
+		t.Errorf("根目录内的文件应该允许访问: %v", err)
+	}
+
+	rec := doRequest(t, srv.Handler(), "GET", "/api/saves/Save/None.dat", nil, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("不存在的文件应返回404，实际%d", rec.Code)
+	}
+}
+
+// 测试修改存档及 ETag 冲突检测
+func TestPatch(t *testing.T) {
+	srv, root := newTestServer(t)
+	handler := srv.Handler()
+
+	rec := doRequest(t, handler, "GET", "/api/saves/Save/Save4.dat", nil, nil)
+	etag := rec.Header().Get("ETag")
+
+	money := int32(123456)
+	edit := Edit{
+		Characters: []CharacterEdit{{Index: 1, Data: map[string]int64{"Level": 77}}},
+		Money:      &money,
+	}
+
+	rec = doRequest(t, handler, "PATCH", "/api/saves/Save/Save4.dat", edit, nil)
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("缺少If-Match时应返回428，实际%d", rec.Code)
+	}
+
+	rec = doRequest(t, handler, "PATCH", "/api/saves/Save/Save4.dat", edit, map[string]string{"If-Match": etag})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("状态码错误: %d %s", rec.Code, rec.Body.String())
+	}
+	var save Save
+	if err := json.Unmarshal(rec.Body.Bytes(), &save); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if save.Characters[0].Data.Level != 77 || *save.Money != money {
+		t.Errorf("修改未生效: %+v", save)
+	}
+	if save.ETag == etag {
+		t.Error("修改后ETag应该变化")
+	}
+	if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(save.Backup))); err != nil {
+		t.Errorf("备份文件不存在: %v", err)
+	}
+
+	// 使用旧的ETag提交应被拒绝
+	rec = doRequest(t, handler, "PATCH", "/api/saves/Save/Save4.dat", edit, map[string]string{"If-Match": etag})
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("ETag不一致时应返回412，实际%d", rec.Code)
+	}
+
+	// 无效的修改
+	invalid := []Edit{
+		{Characters: []CharacterEdit{{Index: 9, Data: map[string]int64{"Level": 1}}}},
+		{Characters: []CharacterEdit{{Index: 1, Data: map[string]int64{"Foo": 1}}}},
+		{Characters: []CharacterEdit{{Index: 1, Data: map[string]int64{"Level": 40000}}}},
+	}
+	for _, edit := range invalid {
+		rec = doRequest(t, handler, "PATCH", "/api/saves/Save/Save4.dat", edit, map[string]string{"If-Match": save.ETag})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("无效的修改应返回400，实际%d: %+v", rec.Code, edit)
+		}
+	}
+}
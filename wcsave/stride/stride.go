@@ -0,0 +1,400 @@
+// Package stride 在多个存档的未解析区域中查找重复的定长记录（如物品表、NPC 状态表），
+// 报告候选的记录长度、起始位置和记录数
+//
+// 分析基于各存档"非零字节"的并集掩码:
+//   - 对每个候选长度 stride，比较相邻两个 stride 窗口的掩码（自相关），
+//     连续多个窗口都相似的位置构成一个候选记录表
+//   - 同一张表在各种相位上都会出现，记录边界取"本列在各记录中都非零、前一列大多为零"的相位
+//   - 按覆盖的字节数从多到少选取互不重叠的候选，长度的整数倍只在覆盖更多时才会胜出
+//   - 最后统计各列取值的熵，列熵明显低于整体的熵说明各记录的同一列类型一致
+package stride
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+)
+
+// Options 分析参数
+type Options struct {
+	MinStride int // 最小记录长度
+	MaxStride int // 最大记录长度
+	MinCount  int // 最少记录数
+}
+
+// DefaultOptions 返回默认的分析参数
+func DefaultOptions() Options {
+	return Options{MinStride: 16, MaxStride: 512, MinCount: 3}
+}
+
+// 相邻两条记录的非零字节掩码的 Jaccard 相似度不低于 recordSimilarity 时视为同一种记录；
+// 非零字节的比例不在 minDensity~maxDensity 之间的窗口不含有可用的结构信息
+const (
+	recordSimilarity = 0.7
+	minDensity       = 1.0 / 8
+	maxDensity       = 7.0 / 8
+)
+
+// Candidate 一个候选的记录表
+type Candidate struct {
+	Offset          int64   `json:"offset"`          // 第一条记录的文件偏移
+	Stride          int     `json:"stride"`          // 记录长度
+	Count           int     `json:"count"`           // 连续的记录数
+	Score           float64 `json:"score"`           // 相邻记录掩码的平均相似度（自相关），越接近1越规则
+	Entropy         float64 `json:"entropy"`         // 记录表的字节熵（比特）
+	ColumnEntropy   float64 `json:"columnEntropy"`   // 各列取值熵的平均值（比特）
+	ConstantColumns int     `json:"constantColumns"` // 在所有记录中取值相同的列数
+	Known           string  `json:"known,omitempty"` // 与已知结构重合时为其名称
+}
+
+// String 返回候选记录表的描述
+func (c Candidate) String() string {
+	text := fmt.Sprintf("0x%06X 长度%3d ×%4d  相关%.2f 熵%.2f 列熵%.2f 恒定列%d",
+		c.Offset, c.Stride, c.Count, c.Score, c.Entropy, c.ColumnEntropy, c.ConstantColumns)
+	if c.Known != "" {
+		text += "  [" + c.Known + "]"
+	}
+	return text
+}
+
+// End 返回记录表之后的第一个字节的偏移
+func (c Candidate) End() int64 {
+	return c.Offset + int64(c.Stride*c.Count)
+}
+
+// AnalyzeFiles 读取多个存档并分析
+func AnalyzeFiles(filePaths []string, options Options) ([]Candidate, error) {
+	files := make([][]byte, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, content)
+	}
+	return Analyze(files, options), nil
+}
+
+// Analyze 在存档内容中查找重复的定长记录，结果按偏移排列；文件长度不同时按最短的长度分析
+// 已知的文件级字段（队伍人数、银两、位置及自定义字段）不参与分析；
+// 角色记录区保留，作为检验分析结果的参照，对应的候选会标注 Known
+func Analyze(files [][]byte, options Options) []Candidate {
+	if len(files) == 0 {
+		return nil
+	}
+	size := len(files[0])
+	for _, content := range files {
+		size = min(size, len(content))
+	}
+
+	used := make([]bool, size)
+	for _, content := range files {
+		for i := 0; i < size; i++ {
+			if content[i] != 0 {
+				used[i] = true
+			}
+		}
+	}
+	for _, field := range knownFields() {
+		for i := field.Offset; i < field.Offset+int64(field.Size) && i < int64(size); i++ {
+			used[i] = false
+		}
+	}
+
+	minCount := max(options.MinCount, 2)
+	a := newAnalyzer(files, used)
+	var found []Candidate
+	for stride := max(options.MinStride, 1); stride <= options.MaxStride && stride*minCount <= size; stride++ {
+		found = append(found, a.chains(stride, minCount)...)
+	}
+
+	// 覆盖字节数多的优先，相同时取较短的记录长度
+	sort.SliceStable(found, func(i, j int) bool {
+		ci, cj := found[i].Stride*found[i].Count, found[j].Stride*found[j].Count
+		if ci != cj {
+			return ci > cj
+		}
+		return found[i].Stride < found[j].Stride
+	})
+	byStride := make(map[int][]Candidate)
+	for _, candidate := range found {
+		byStride[candidate.Stride] = append(byStride[candidate.Stride], candidate)
+	}
+	for _, list := range byStride {
+		sort.Slice(list, func(i, j int) bool { return list[i].Offset < list[j].Offset })
+	}
+
+	var candidates []Candidate
+	for _, candidate := range found {
+		if overlapsAny(candidate, candidates) || hasShorterPeriod(candidate, byStride, options.MinStride) {
+			continue
+		}
+		candidate.Entropy, candidate.ColumnEntropy, candidate.ConstantColumns = columnStatistics(files, candidate)
+		// 各记录完全相同的多为填充数据，不是记录表
+		if candidate.ConstantColumns == candidate.Stride {
+			continue
+		}
+		if candidate.Offset < models.CharacterStartPosition+models.CharacterRecordSize &&
+			candidate.End() > models.CharacterStartPosition {
+			candidate.Known = "角色记录"
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Offset < candidates[j].Offset
+	})
+	return candidates
+}
+
+// SelfTest 检查分析结果中是否重新找到了从 0x3177A 开始、长度为84的角色记录
+// 需要至少一个存档的队伍人数不少于 MinCount
+func SelfTest(candidates []Candidate, options Options) (Candidate, error) {
+	for _, candidate := range candidates {
+		if candidate.Known == "" {
+			continue
+		}
+		if candidate.Stride != models.CharacterRecordSize || candidate.Offset != models.CharacterStartPosition {
+			return candidate, fmt.Errorf("角色记录区的分析结果为 0x%X 处长度%d，应为 0x%X 处长度%d",
+				candidate.Offset, candidate.Stride, models.CharacterStartPosition, models.CharacterRecordSize)
+		}
+		return candidate, nil
+	}
+	return Candidate{}, fmt.Errorf("未在角色记录区找到重复的记录（需要至少一个存档的队伍人数不少于%d）", max(options.MinCount, 2))
+}
+
+// knownFields 已知的文件级字段，角色记录区除外
+func knownFields() []layout.Field {
+	fields := []layout.Field{
+		{Name: "PartyCount", Offset: models.PartyCountPosition, Size: 2},
+		{Name: "Money", Offset: models.MoneyPosition, Size: 4},
+		{Name: "MapID", Offset: models.MapPosition, Size: 4},
+		{Name: "X", Offset: models.MapPosition + models.PositionXOffset, Size: 4},
+		{Name: "Y", Offset: models.MapPosition + models.PositionYOffset, Size: 4},
+	}
+	return append(fields, layout.Extra.File...)
+}
+
+// hasShorterPeriod 判断同一位置是否有以 candidate.Stride 的约数为长度、覆盖至少一半字节的候选
+// 这时 candidate 多半是几条记录合并而成的，应使用较短的记录长度
+func hasShorterPeriod(candidate Candidate, byStride map[int][]Candidate, minStride int) bool {
+	for divisor := 2; candidate.Stride/divisor >= minStride; divisor++ {
+		if candidate.Stride%divisor != 0 {
+			continue
+		}
+		list := byStride[candidate.Stride/divisor]
+		i := sort.Search(len(list), func(i int) bool { return list[i].End() > candidate.Offset })
+		for ; i < len(list) && list[i].Offset < candidate.End(); i++ {
+			if 2*list[i].Stride*list[i].Count >= candidate.Stride*candidate.Count {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overlapsAny 判断候选是否与已选的候选重叠
+func overlapsAny(candidate Candidate, selected []Candidate) bool {
+	for _, other := range selected {
+		if candidate.Offset < other.End() && other.Offset < candidate.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzer 保存并集掩码及分析各个记录长度时复用的缓冲区
+type analyzer struct {
+	files   [][]byte
+	used    []bool
+	nonzero []int // 非零字节数的前缀计数
+	both    []int // 相隔 stride 的两字节同为非零的位置数的前缀计数
+	either  []int // 相隔 stride 的两字节至少一个非零的位置数的前缀计数
+	similar []float64
+	length  []int
+}
+
+// newAnalyzer 创建分析器
+func newAnalyzer(files [][]byte, used []bool) *analyzer {
+	size := len(used)
+	a := &analyzer{
+		files:   files,
+		used:    used,
+		nonzero: make([]int, size+1),
+		both:    make([]int, size+1),
+		either:  make([]int, size+1),
+		similar: make([]float64, size+1),
+		length:  make([]int, size+1),
+	}
+	for i := 0; i < size; i++ {
+		a.nonzero[i+1] = a.nonzero[i]
+		if used[i] {
+			a.nonzero[i+1]++
+		}
+	}
+	return a
+}
+
+// chains 查找长度为 stride 的连续相似窗口
+// 同一张表的各个相位中只保留记录边界得分最高的一个
+func (a *analyzer) chains(stride, minCount int) []Candidate {
+	used, nonzero, both, either := a.used, a.nonzero, a.both, a.either
+	size := len(used)
+	last := size - 2*stride
+	if last < 0 {
+		return nil
+	}
+
+	for i := 0; i+stride < size; i++ {
+		both[i+1], either[i+1] = both[i], either[i]
+		if used[i] && used[i+stride] {
+			both[i+1]++
+		}
+		if used[i] || used[i+stride] {
+			either[i+1]++
+		}
+	}
+
+	// similar[p] 为窗口 [p, p+stride) 与下一个窗口的相似度，窗口不含结构信息时为 -1
+	similar := a.similar
+	for p := 0; p <= last; p++ {
+		similar[p] = -1
+		density := float64(nonzero[p+stride]-nonzero[p]) / float64(stride)
+		e := either[p+stride] - either[p]
+		if density < minDensity || density > maxDensity || e == 0 {
+			continue
+		}
+		similar[p] = float64(both[p+stride]-both[p]) / float64(e)
+	}
+
+	// length[p] 为从 p 开始相似的相邻窗口对数，即记录数减一
+	length := a.length
+	for p := last; p >= 0; p-- {
+		length[p] = 0
+		if similar[p] >= recordSimilarity {
+			length[p] = 1
+			if p+stride <= last {
+				length[p] += length[p+stride]
+			}
+		}
+	}
+	start := func(p int) bool {
+		return length[p]+1 >= minCount && (p < stride || length[p-stride] == 0)
+	}
+
+	var result []Candidate
+	for p := 0; p <= last; p++ {
+		if !start(p) {
+			continue
+		}
+		// 同一张表在相邻的相位上都会出现，在其中选择记录边界得分最高的相位
+		group := []int{p}
+		end := p + (length[p]+1)*stride
+		for q := p + 1; q <= last && q < end; q++ {
+			if start(q) {
+				group = append(group, q)
+				end = max(end, q+(length[q]+1)*stride)
+			}
+		}
+		best, bestScore := p, math.Inf(-1)
+		for _, q := range group {
+			count := length[q] + 1
+			// 得分相同时取记录较多的相位
+			score := a.nonzeroRatio(q, stride, count, 0) - a.nonzeroRatio(q, stride, count, -1) + float64(count)*1e-6
+			if score > bestScore {
+				best, bestScore = q, score
+			}
+		}
+
+		count := length[best] + 1
+		total := 0.0
+		for k := 0; k < count-1; k++ {
+			total += similar[best+k*stride]
+		}
+		result = append(result, Candidate{Offset: int64(best), Stride: stride, Count: count, Score: total / float64(count-1)})
+		p = group[len(group)-1]
+	}
+	return result
+}
+
+// nonzeroRatio 返回从 p 开始的 count 条记录中，相对位置 column 的字节在各存档中非零的比例
+// column 为 -1 时统计每条记录之前的一个字节；只统计该存档中不全为零的记录，已知字段视为零
+func (a *analyzer) nonzeroRatio(p, stride, count, column int) float64 {
+	nonzero, total := 0, 0
+	for _, content := range a.files {
+		for k := 0; k < count; k++ {
+			q := p + k*stride
+			if q+stride > len(content) || q+column < 0 || allZero(content, q, stride) {
+				continue
+			}
+			total++
+			if a.used[q+column] && content[q+column] != 0 {
+				nonzero++
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(nonzero) / float64(total)
+}
+
+// allZero 判断 content[offset:offset+length] 是否全为零
+func allZero(content []byte, offset, length int) bool {
+	for i := offset; i < offset+length; i++ {
+		if content[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// columnStatistics 统计记录表的字节熵、各列的平均熵和恒定列数，只统计不全为零的记录
+func columnStatistics(files [][]byte, candidate Candidate) (float64, float64, int) {
+	stride := candidate.Stride
+	var all [256]int
+	columns := make([][256]int, stride)
+	total := 0
+	for _, content := range files {
+		for k := 0; k < candidate.Count; k++ {
+			p := int(candidate.Offset) + k*stride
+			if p+stride > len(content) || allZero(content, p, stride) {
+				continue
+			}
+			total++
+			for i := 0; i < stride; i++ {
+				all[content[p+i]]++
+				columns[i][content[p+i]]++
+			}
+		}
+	}
+	if total == 0 {
+		return 0, 0, stride
+	}
+
+	columnEntropy, constant := 0.0, 0
+	for i := range columns {
+		h := entropy(&columns[i], total)
+		columnEntropy += h
+		if h == 0 {
+			constant++
+		}
+	}
+	return entropy(&all, total*stride), columnEntropy / float64(stride), constant
+}
+
+// entropy 返回字节取值分布的熵（比特）
+func entropy(counts *[256]int, n int) float64 {
+	h := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(n)
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
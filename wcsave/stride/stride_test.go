@@ -0,0 +1,101 @@
+package stride
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"wcediter/wcsave/models"
+	"wcediter/wcsave/testsave"
+)
+
+// tableOffset、tableStride 测试中写入的物品表
+const (
+	tableOffset = 0x1000
+	tableStride = 40
+	tableCount  = 12
+)
+
+// generatedSaves 生成若干个队伍人数不同、并带有一张物品表的存档
+func generatedSaves(t *testing.T) [][]byte {
+	t.Helper()
+	var files [][]byte
+	for n := 1; n <= 4; n++ {
+		save := testsave.Default()
+		for len(save.Party) < n+1 {
+			save.Party = append(save.Party, testsave.NewCharacter("聶風", int16(10*n)))
+		}
+		content, err := save.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k := 0; k < tableCount; k++ {
+			record := content[tableOffset+k*tableStride:]
+			// 名字、数量、价格和几个标记，与真实的记录一样各列类型固定
+			copy(record, []byte{byte(0xA4 + k), byte(0x40 + n), 0xA5, byte(0x41 + k)})
+			binary.LittleEndian.PutUint16(record[8:], uint16(0x101*(k+1)+n))
+			binary.LittleEndian.PutUint32(record[12:], uint32(0x10101*(k+1)+n))
+			for i := 20; i < 30; i += 2 {
+				record[i] = byte(k + i)
+			}
+			record[32], record[36] = 1, 2
+		}
+		files = append(files, content)
+	}
+	return files
+}
+
+func TestAnalyze(t *testing.T) {
+	candidates := Analyze(generatedSaves(t), DefaultOptions())
+
+	var table Candidate
+	for _, candidate := range candidates {
+		if candidate.Offset <= tableOffset && candidate.End() > tableOffset {
+			table = candidate
+		}
+	}
+	if table.Offset != tableOffset || table.Stride != tableStride || table.Count != tableCount {
+		t.Errorf("物品表识别错误: %v，全部候选: %v", table, candidates)
+	}
+	if table.ConstantColumns == 0 || table.ColumnEntropy >= table.Entropy {
+		t.Errorf("物品表的列统计不合理: %v", table)
+	}
+
+	known, err := SelfTest(candidates, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if known.Count != 5 {
+		t.Errorf("角色记录数应为5，实际%d", known.Count)
+	}
+}
+
+func TestSelfTestBundledSaves(t *testing.T) {
+	files, err := filepath.Glob("../../data/*.dat")
+	if err != nil || len(files) == 0 {
+		t.Skip("没有附带的存档")
+	}
+	candidates, err := AnalyzeFiles(files, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	known, err := SelfTest(candidates, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if known.Offset != models.CharacterStartPosition || known.Stride != models.CharacterRecordSize {
+		t.Errorf("自检结果错误: %v", known)
+	}
+}
+
+func TestSelfTestMissing(t *testing.T) {
+	save := testsave.Default()
+	save.Party = save.Party[:1]
+	content, err := save.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SelfTest(Analyze([][]byte{content}, DefaultOptions()), DefaultOptions()); err == nil {
+		t.Error("只有一个角色时不应通过自检")
+	}
+}
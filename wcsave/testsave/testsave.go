@@ -0,0 +1,185 @@
+// Package testsave 在内存中生成结构有效的存档和 WC.cfg，供测试和演示使用，
+// 不需要附带真实玩家的存档文件
+package testsave
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"wcediter/wcsave/models"
+	"wcediter/wcsave/writer"
+)
+
+// Character 队伍中的一个角色
+type Character struct {
+	Name     string // 最多3个汉字（6字节Big5）
+	Data     models.CharacterData
+	MemberID int16 // 队伍成员编号表中的编号
+}
+
+// Region 存档中的一段字节
+type Region struct {
+	Offset int64
+	Length int64
+}
+
+// Save 要生成的存档内容
+type Save struct {
+	Party []Character // 1到5个角色
+	Money int32
+	MapID int32
+	X, Y  int32
+
+	// Template 不为空时，将 Regions 中的字节从模板复制到生成的存档，
+	// 用于保留尚未解析的数据（如物品、剧情标记）
+	Template []byte
+	Regions  []Region
+}
+
+// Config 要生成的 WC.cfg 内容
+type Config struct {
+	ProgressIDs [5]int32 // 各进度对应的存档编号
+	LocationIDs [5]int32 // 各进度的位置编号（位置名称表的下标，与存档中的地图编号不是同一套编号）
+}
+
+// ConfigSize WC.cfg 的文件大小
+const ConfigSize = models.ConfigFileSize
+
+// NewCharacter 按等级生成属性合理的角色
+func NewCharacter(name string, level int16) Character {
+	maxHP := int32(level)*40 + 100
+	maxMP := int32(level)*20 + 50
+	stat := level*3 + 10
+	return Character{
+		Name: name,
+		Data: models.CharacterData{
+			CurrentExp:   int32(level) * int32(level) * 100,
+			NextLevelExp: int32(level+1) * int32(level+1) * 100,
+			MaxHP:        maxHP,
+			MaxMP:        maxMP,
+			CurrentHP:    maxHP,
+			CurrentMP:    maxMP,
+			Strength:     stat,
+			Reaction:     stat - 2,
+			Constitution: stat + 1,
+			Speed:        stat - 1,
+			Attack:       stat * 2,
+			Defense:      stat + 5,
+			Luck:         10,
+			Level:        level,
+		},
+	}
+}
+
+// Default 返回一个三人队伍的存档，成员编号与游戏中的三人队伍一致（0、8、9）
+func Default() Save {
+	party := []Character{
+		NewCharacter("葉小釵", 12),
+		NewCharacter("素還真", 15),
+		NewCharacter("一頁書", 20),
+	}
+	party[1].MemberID = 8
+	party[2].MemberID = 9
+	return Save{
+		Party: party,
+		Money: 12345,
+		MapID: 90,
+		X:     320,
+		Y:     240,
+	}
+}
+
+// DefaultConfig 返回五个进度都有位置名称的 WC.cfg
+// 位置编号只决定进度选择界面显示的名称，与 Default 存档的地图编号无关
+func DefaultConfig() Config {
+	return Config{
+		ProgressIDs: [5]int32{0, 1, 2, 3, 4},
+		LocationIDs: [5]int32{91, 90, 4, 15, 89},
+	}
+}
+
+// Bytes 生成完整的存档内容
+func (s Save) Bytes() ([]byte, error) {
+	if len(s.Party) == 0 || len(s.Party) > models.MaxCharacters {
+		return nil, fmt.Errorf("队伍人数应在1到%d之间: %d", models.MaxCharacters, len(s.Party))
+	}
+
+	content := make([]byte, models.SaveFileSize)
+	if s.Template != nil {
+		for _, region := range s.Regions {
+			end := region.Offset + region.Length
+			if region.Offset < 0 || end > models.SaveFileSize || end > int64(len(s.Template)) {
+				return nil, fmt.Errorf("模板区域超出范围: 0x%X+%d", region.Offset, region.Length)
+			}
+			copy(content[region.Offset:end], s.Template[region.Offset:end])
+		}
+	}
+
+	// 角色记录区整体重写，未使用的记录全部为0（首2字节为0即结束标记）
+	area := content[models.CharacterStartPosition : models.CharacterStartPosition+models.MaxCharacters*models.CharacterRecordSize]
+	for i := range area {
+		area[i] = 0
+	}
+	for i, char := range s.Party {
+		record := area[i*models.CharacterRecordSize : (i+1)*models.CharacterRecordSize]
+		name, err := writer.EncodeName(char.Name)
+		if err != nil {
+			return nil, fmt.Errorf("角色%d: %v", i+1, err)
+		}
+		copy(record, name)
+		if err := writer.EncodeCharacterData(record, char.Data); err != nil {
+			return nil, fmt.Errorf("角色%d: %v", i+1, err)
+		}
+		binary.LittleEndian.PutUint16(content[models.PartyMemberPosition+i*2:], uint16(char.MemberID))
+	}
+	binary.LittleEndian.PutUint16(content[models.PartyCountPosition:], uint16(len(s.Party)))
+
+	binary.LittleEndian.PutUint32(content[models.MoneyPosition:], uint32(s.Money))
+	binary.LittleEndian.PutUint32(content[models.MapPosition:], uint32(s.MapID))
+	binary.LittleEndian.PutUint32(content[models.MapPosition+models.PositionXOffset:], uint32(s.X))
+	binary.LittleEndian.PutUint32(content[models.MapPosition+models.PositionYOffset:], uint32(s.Y))
+	return content, nil
+}
+
+// WriteFile 生成存档并写入文件
+func (s Save) WriteFile(filePath string) error {
+	content, err := s.Bytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, content, 0644)
+}
+
+// Bytes 生成 WC.cfg 内容
+// 前36字节为游戏的设置标记，按游戏默认值填写
+func (c Config) Bytes() []byte {
+	content := make([]byte, ConfigSize)
+	for i := 2; i < models.ProgressIDPosition; i++ {
+		content[i] = 1
+	}
+	for i := 0; i < models.MaxProgress; i++ {
+		binary.LittleEndian.PutUint32(content[models.ProgressIDPosition+i*4:], uint32(c.ProgressIDs[i]))
+		binary.LittleEndian.PutUint32(content[models.ProgressLocationPosition+i*4:], uint32(c.LocationIDs[i]))
+	}
+	return content
+}
+
+// WriteFile 生成 WC.cfg 并写入文件
+func (c Config) WriteFile(filePath string) error {
+	return os.WriteFile(filePath, c.Bytes(), 0644)
+}
+
+// WriteDir 在目录中写入 WC.cfg 和若干存档（键为文件名）
+func WriteDir(dir string, config Config, saves map[string]Save) error {
+	if err := config.WriteFile(filepath.Join(dir, "WC.cfg")); err != nil {
+		return err
+	}
+	for name, save := range saves {
+		if err := save.WriteFile(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
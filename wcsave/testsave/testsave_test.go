@@ -0,0 +1,104 @@
+package testsave
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wcediter/wcsave"
+	"wcediter/wcsave/check"
+	"wcediter/wcsave/models"
+)
+
+// 测试生成的存档能通过检查并被正确读取
+func TestDefault(t *testing.T) {
+	save := Default()
+	content, err := save.Bytes()
+	if err != nil {
+		t.Fatalf("生成存档失败: %v", err)
+	}
+	if len(content) != models.SaveFileSize {
+		t.Fatalf("存档大小预期%d，实际%d", models.SaveFileSize, len(content))
+	}
+	if report := check.Bytes("generated", content); len(report.Issues) != 0 {
+		t.Fatalf("生成的存档未通过检查:\n%s", report)
+	}
+
+	editor := wcsave.NewSaveEditor()
+	if err := editor.ReadSaveFrom(bytes.NewReader(content)); err != nil {
+		t.Fatalf("读取生成的存档失败: %v", err)
+	}
+	if editor.GetCharacterCount() != len(save.Party) {
+		t.Fatalf("角色数量预期%d，实际%d", len(save.Party), editor.GetCharacterCount())
+	}
+	for i, char := range save.Party {
+		got := editor.Characters[i]
+		if strings.TrimSpace(got.Name) != char.Name {
+			t.Errorf("角色%d的名字预期%q，实际%q", i, char.Name, got.Name)
+		}
+		if got.Data != char.Data {
+			t.Errorf("角色%d的属性不一致\n预期: %+v\n实际: %+v", i, char.Data, got.Data)
+		}
+	}
+	if editor.MoneyInfo.Value != save.Money || editor.PositionInfo.MapID != save.MapID ||
+		editor.PositionInfo.X != save.X || editor.PositionInfo.Y != save.Y {
+		t.Errorf("银两或位置不一致: %+v %+v", editor.MoneyInfo, editor.PositionInfo)
+	}
+}
+
+// 测试从模板复制区域
+func TestTemplateRegions(t *testing.T) {
+	template := make([]byte, models.SaveFileSize)
+	for i := range template {
+		template[i] = byte(i)
+	}
+	save := Default()
+	save.Party = save.Party[:1]
+	save.Template = template
+	save.Regions = []Region{{Offset: 0, Length: models.SaveFileSize}}
+
+	content, err := save.Bytes()
+	if err != nil {
+		t.Fatalf("生成存档失败: %v", err)
+	}
+	// 区域外的内容来自模板，角色记录区和银两由生成器写入
+	if content[100] != template[100] {
+		t.Error("模板区域未被复制")
+	}
+	second := models.CharacterStartPosition + models.CharacterRecordSize
+	if content[second] != 0 || content[second+1] != 0 {
+		t.Error("第二条记录应为结束标记")
+	}
+
+	save.Regions = []Region{{Offset: models.SaveFileSize - 2, Length: 4}}
+	if _, err := save.Bytes(); err == nil {
+		t.Error("超出范围的模板区域应返回错误")
+	}
+	save.Party = nil
+	if _, err := save.Bytes(); err == nil {
+		t.Error("空队伍应返回错误")
+	}
+}
+
+// 测试生成 WC.cfg 和存档目录
+func TestWriteDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteDir(dir, DefaultConfig(), map[string]Save{"Save0.dat": Default()}); err != nil {
+		t.Fatalf("WriteDir失败: %v", err)
+	}
+
+	editor := wcsave.NewSaveEditor()
+	progress, err := editor.ReadProgress(filepath.Join(dir, "WC.cfg"))
+	if err != nil {
+		t.Fatalf("读取生成的WC.cfg失败: %v", err)
+	}
+	for i, info := range progress {
+		if info.ProgressID != i || info.LocationID != int(DefaultConfig().LocationIDs[i]) || info.LocationName == "" {
+			t.Errorf("进度%d不一致: %+v", i, info)
+		}
+	}
+	if err := editor.ReadSave(filepath.Join(dir, "Save0.dat")); err != nil {
+		t.Fatalf("读取生成的存档失败: %v", err)
+	}
+}
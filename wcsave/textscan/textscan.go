@@ -0,0 +1,157 @@
+// Package textscan 在存档中查找可能的 Big5 字符串，
+// 用于发现尚未解析的文字结构（物品名、NPC 名、地名等）
+package textscan
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"wcediter/assets"
+	"wcediter/wcsave/layout"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// DefaultMinChars 默认的最少字数，单个汉字在二进制数据中太容易偶然出现
+const DefaultMinChars = 2
+
+// Match 找到的一段 Big5 文本
+type Match struct {
+	Offset      int64  `json:"offset"`                // 文件内偏移
+	Length      int    `json:"length"`                // 字节数（不含首尾空格）
+	Text        string `json:"text"`                  // 转换后的 UTF-8 文本
+	LocationIDs []int  `json:"locationIds,omitempty"` // 位置名称表中文字相同的条目
+	Field       string `json:"field,omitempty"`       // 所在的已知区域，由 Annotate 设置
+}
+
+// locationIndex 位置名称表，键为去掉空格后的 Big5 字节
+var locationIndex = make(map[string][]int)
+
+// init 从 assets 中的 Big5 位置名称表建立索引
+func init() {
+	for id, line := range bytes.Split(assets.LocationNameBig5Bytes, []byte("\n")) {
+		key := normalize(line)
+		if len(key) > 0 {
+			locationIndex[string(key)] = append(locationIndex[string(key)], id)
+		}
+	}
+}
+
+// normalize 去掉 Big5 文本中的半角空格和全角空格（A1 40），地名在游戏中常以空格分隔各字
+func normalize(b []byte) []byte {
+	result := make([]byte, 0, len(b))
+	for i := 0; i < len(b); {
+		switch {
+		case b[i] == ' ' || b[i] == '\r':
+			i++
+		case i+1 < len(b) && b[i] == 0xA1 && b[i+1] == 0x40:
+			i += 2
+		case i+1 < len(b) && isLead(b[i]):
+			result = append(result, b[i], b[i+1])
+			i += 2
+		default:
+			result = append(result, b[i])
+			i++
+		}
+	}
+	return result
+}
+
+// isLead 是否为常用 Big5 字符的首字节（符号区 A1~A3 和汉字区 A4~F9）
+func isLead(b byte) bool {
+	return b >= 0xA1 && b <= 0xF9
+}
+
+// isTrail 是否为 Big5 字符的第二字节
+func isTrail(b byte) bool {
+	return (b >= 0x40 && b <= 0x7E) || (b >= 0xA1 && b <= 0xFE)
+}
+
+// Scan 查找数据中至少包含 minChars 个字的 Big5 文本
+// 字与字之间可以有空格；能解码但整段是同一个字的内容多为数值数据，不视为文本
+func Scan(data []byte, minChars int) []Match {
+	if minChars < 1 {
+		minChars = 1
+	}
+	decoder := traditionalchinese.Big5.NewDecoder()
+
+	var matches []Match
+	for i := 0; i < len(data); {
+		end, chars := i, 0
+		for end < len(data) {
+			if data[end] == ' ' {
+				end++
+			} else if end+1 < len(data) && isLead(data[end]) && isTrail(data[end+1]) {
+				end += 2
+				chars++
+			} else {
+				break
+			}
+		}
+		if end == i {
+			i++
+			continue
+		}
+
+		start, stop := i, end
+		for start < stop && data[start] == ' ' {
+			start++
+		}
+		for stop > start && data[stop-1] == ' ' {
+			stop--
+		}
+		i = end
+
+		if chars < minChars {
+			continue
+		}
+		text, err := decoder.Bytes(data[start:stop])
+		if err != nil || bytes.ContainsRune(text, utf8.RuneError) || repeated(string(text)) {
+			continue
+		}
+		matches = append(matches, Match{
+			Offset:      int64(start),
+			Length:      stop - start,
+			Text:        string(text),
+			LocationIDs: locationIndex[string(normalize(data[start:stop]))],
+		})
+	}
+	return matches
+}
+
+// ScanFile 读取文件并查找其中的 Big5 文本
+func ScanFile(filePath string, minChars int) ([]Match, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Scan(data, minChars), nil
+}
+
+// repeated 去掉空格后是否为同一个字重复多次（或只有空格）
+func repeated(text string) bool {
+	runes := []rune(strings.NewReplacer(" ", "", "　", "").Replace(text))
+	if len(runes) < 2 {
+		return len(runes) == 0
+	}
+	for _, r := range runes[1:] {
+		if r != runes[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// Annotate 为位于已知区域中的文本标注区域名称，regions 需按位置排序（见 layout.Regions）
+func Annotate(matches []Match, regions []layout.Region) {
+	for i := range matches {
+		if region, ok := layout.Find(regions, matches[i].Offset); ok {
+			matches[i].Field = region.Field.Label
+			if region.Group != "" {
+				matches[i].Field = region.Group + " " + region.Field.Label
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package textscan
+
+import (
+	"testing"
+
+	"wcediter/wcsave/layout"
+	"wcediter/wcsave/models"
+	"wcediter/wcsave/testsave"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func big5(t *testing.T, text string) []byte {
+	t.Helper()
+	b, err := traditionalchinese.Big5.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestScan(t *testing.T) {
+	data := make([]byte, 64)
+	copy(data[4:], big5(t, " 無 名 居 "))     // 位置名称表中的写法
+	copy(data[20:], big5(t, "降龍十八掌"))      // 普通文本
+	copy(data[32:], big5(t, "瑾瑾瑾"))        // 重复的字多为数值
+	copy(data[44:], big5(t, "劍"))          // 不足最少字数
+	copy(data[50:], []byte{0xA4, 0x40, 0}) // 单字
+
+	matches := Scan(data, DefaultMinChars)
+	if len(matches) != 2 {
+		t.Fatalf("应找到2段文本，实际%d: %+v", len(matches), matches)
+	}
+	if m := matches[0]; m.Offset != 5 || m.Length != 8 || m.Text != "無 名 居" || len(m.LocationIDs) == 0 || m.LocationIDs[0] != 4 {
+		t.Errorf("地名识别错误: %+v", m)
+	}
+	if m := matches[1]; m.Offset != 20 || m.Length != 10 || m.Text != "降龍十八掌" || m.LocationIDs != nil {
+		t.Errorf("文本识别错误: %+v", m)
+	}
+
+	if matches := Scan(data, 1); len(matches) != 4 {
+		t.Errorf("最少1字时应找到4段文本，实际%d: %+v", len(matches), matches)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	content, err := testsave.Default().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := Scan(content, DefaultMinChars)
+
+	characters := []models.CharacterInfo{
+		{Name: "葉小釵", Position: models.CharacterStartPosition},
+		{Name: "素還真", Position: models.CharacterStartPosition + models.CharacterRecordSize},
+		{Name: "一頁書", Position: models.CharacterStartPosition + 2*models.CharacterRecordSize},
+	}
+	Annotate(matches, layout.Regions(characters, models.MoneyInfo{}, models.PositionInfo{}))
+
+	found := 0
+	for _, m := range matches {
+		for i, char := range characters {
+			if m.Text == char.Name {
+				found++
+				if m.Offset != char.Position || m.Field != "角色"+string(rune('1'+i))+" "+char.Name+" 名字" {
+					t.Errorf("角色名标注错误: %+v", m)
+				}
+			}
+		}
+	}
+	if found != len(characters) {
+		t.Errorf("应找到%d个角色名，实际%d: %+v", len(characters), found, matches)
+	}
+}
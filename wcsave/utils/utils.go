@@ -1,32 +1,19 @@
 package utils
 
 import (
+	"encoding/binary"
 	"io"
-	"os"
 )
 
-// skipBytes 跳过n字节
-func SkipBytes(file *os.File, n int) error {
-	_, err := file.Seek(int64(n), 1) // 从当前位置跳过n字节
-	if err != nil {
-		// 如果Seek失败，尝试通过读取来跳过
-		buffer := make([]byte, n)
-		_, err = file.Read(buffer)
-		if err != nil && err != io.EOF {
-			return err
-		}
-	}
-	return nil
-}
-
 // Converter 泛型转换函数类型
 type Converter[T any] func([]byte) (T, error)
 
-// ReadAndConvert 泛型读取方法，支持自定义转换函数
-func ReadAndConvert[T any](file *os.File, n int, converter Converter[T]) (T, []byte, error) {
+// ReadAndConvert 泛型读取方法，从 offset 处读取 n 字节，支持自定义转换函数
+// 数据不足 n 字节时不调用转换函数，返回已读取的字节和 io.ErrUnexpectedEOF，读取方据此区分截断与其他错误
+func ReadAndConvert[T any](r io.ReaderAt, offset int64, n int, converter Converter[T]) (T, []byte, error) {
 	var zero T
 	buffer := make([]byte, n)
-	readCount, err := file.Read(buffer)
+	readCount, err := r.ReadAt(buffer, offset)
 	if err != nil && err != io.EOF {
 		return zero, nil, err
 	}
@@ -34,6 +21,11 @@ func ReadAndConvert[T any](file *os.File, n int, converter Converter[T]) (T, []b
 	rawBytes := make([]byte, readCount)
 	copy(rawBytes, buffer[:readCount])
 
+	// 已到数据末尾，数据不足
+	if readCount < n {
+		return zero, rawBytes, io.ErrUnexpectedEOF
+	}
+
 	// 如果converter为nil，直接返回零值
 	if converter == nil {
 		return zero, rawBytes, nil
@@ -46,4 +38,14 @@ func ReadAndConvert[T any](file *os.File, n int, converter Converter[T]) (T, []b
 	}
 
 	return value, rawBytes, nil
-}
\ No newline at end of file
+}
+
+// Int32Converter 将4字节小端数据转换为 int32
+func Int32Converter(b []byte) (int32, error) {
+	return int32(binary.LittleEndian.Uint32(b)), nil
+}
+
+// Int16Converter 将2字节小端数据转换为 int16
+func Int16Converter(b []byte) (int16, error) {
+	return int16(binary.LittleEndian.Uint16(b)), nil
+}
@@ -0,0 +1,120 @@
+package watcher
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDelay 默认的防抖时间，游戏写入存档时会产生多次写事件
+const DefaultDelay = 300 * time.Millisecond
+
+// Watcher 监视目录中指定文件的外部修改
+type Watcher struct {
+	fsWatcher *fsnotify.Watcher
+	names     map[string]bool // 小写的文件名
+	delay     time.Duration
+	onChange  func(path string)
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+	done   chan struct{}
+}
+
+// New 监视 dir 目录中名为 names 的文件（不区分大小写），文件被写入、创建或替换后调用 onChange
+// 同一文件在 delay 时间内的多次事件只触发一次，onChange 在后台协程中调用
+func New(dir string, names []string, delay time.Duration, onChange func(path string)) (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// 监视目录而不是文件本身，以便发现先写临时文件再改名替换的保存方式
+	if err := fsWatcher.Add(dir); err != nil {
+		fsWatcher.Close()
+		return nil, err
+	}
+
+	w := &Watcher{
+		fsWatcher: fsWatcher,
+		names:     make(map[string]bool),
+		delay:     delay,
+		onChange:  onChange,
+		timers:    make(map[string]*time.Timer),
+		done:      make(chan struct{}),
+	}
+	for _, name := range names {
+		w.names[strings.ToLower(name)] = true
+	}
+
+	go w.loop()
+	return w, nil
+}
+
+// loop 处理文件系统事件
+func (w *Watcher) loop() {
+	for {
+		select {
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			if !w.names[strings.ToLower(filepath.Base(event.Name))] {
+				continue
+			}
+			w.schedule(event.Name)
+		case _, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// schedule 在防抖时间后触发回调，期间的新事件会重新计时
+func (w *Watcher) schedule(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if timer, ok := w.timers[path]; ok {
+		timer.Reset(w.delay)
+		return
+	}
+	w.timers[path] = time.AfterFunc(w.delay, func() {
+		w.mu.Lock()
+		delete(w.timers, path)
+		w.mu.Unlock()
+
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+		w.onChange(path)
+	})
+}
+
+// Close 停止监视
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	select {
+	case <-w.done:
+		w.mu.Unlock()
+		return nil
+	default:
+	}
+	close(w.done)
+	for _, timer := range w.timers {
+		timer.Stop()
+	}
+	w.mu.Unlock()
+
+	return w.fsWatcher.Close()
+}
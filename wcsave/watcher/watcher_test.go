@@ -0,0 +1,76 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 测试只对指定文件触发，并对连续写入防抖
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	savePath := filepath.Join(dir, "Save3.dat")
+	if err := os.WriteFile(savePath, []byte("a"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	changes := make(chan string, 10)
+	w, err := New(dir, []string{"save3.dat", "WC.cfg"}, 100*time.Millisecond, func(path string) {
+		changes <- path
+	})
+	if err != nil {
+		t.Fatalf("New失败: %v", err)
+	}
+	defer w.Close()
+
+	// 连续写入只触发一次
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(savePath, []byte{byte(i)}, 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+	}
+	// 其他文件不触发
+	if err := os.WriteFile(filepath.Join(dir, "Save4.dat"), []byte("b"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+
+	select {
+	case path := <-changes:
+		if filepath.Base(path) != "Save3.dat" {
+			t.Errorf("触发的文件错误: %s", path)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("没有检测到文件修改")
+	}
+
+	select {
+	case path := <-changes:
+		t.Errorf("不应重复触发: %s", path)
+	case <-time.After(400 * time.Millisecond):
+	}
+
+	// 改名替换也应触发
+	tmpPath := filepath.Join(dir, "WC.tmp")
+	if err := os.WriteFile(tmpPath, []byte("c"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(dir, "WC.cfg")); err != nil {
+		t.Fatalf("改名失败: %v", err)
+	}
+	select {
+	case path := <-changes:
+		if filepath.Base(path) != "WC.cfg" {
+			t.Errorf("触发的文件错误: %s", path)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("没有检测到文件替换")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("Close失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("重复Close失败: %v", err)
+	}
+}
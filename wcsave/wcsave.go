@@ -1,10 +1,17 @@
 package wcsave
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
 	"wcediter/wcsave/models"
 	"wcediter/wcsave/reader"
+	"wcediter/wcsave/utils"
 	"wcediter/wcsave/writer"
 )
 
@@ -12,7 +19,14 @@ import (
 type SaveEditor struct {
 	Characters    []models.CharacterInfo
 	MoneyInfo     models.MoneyInfo
+	PositionInfo  models.PositionInfo
 	ProgressInfos []models.ProgressInfo
+	RawEdits      []models.RawEdit // 尚未解析的字段通过原始字节修改，保存时最后写入
+
+	partyChanged bool      // 队伍成员是否被增删或调整顺序
+	loaded       *snapshot // 读取存档时的状态，用于检测外部修改
+	progress     *snapshot // 读取 WC.cfg 时的状态，写入前确认其未被修改
+	content      []byte    // 读取时的存档内容，用于读取自定义字段
 }
 
 // NewSaveEditor 创建一个新的存档编辑器实例
@@ -25,33 +39,127 @@ func NewSaveEditor() *SaveEditor {
 
 // ReadSave 从文件中读取存档数据
 func (e *SaveEditor) ReadSave(filePath string) error {
-	file, err := os.Open(filePath)
+	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+
+	err = e.ReadSaveFrom(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	// 记录读取时的状态
+	e.takeSnapshot(filePath, content)
+	return nil
+}
+
+// ReadSaveFrom 从内存、压缩包等任意来源读取存档数据
+// 不对应磁盘文件，因此不会检测外部修改
+// 返回错误时已读取成功的部分仍保留在编辑器中，可用 errors.Is 判断是否为 ErrTruncated 或 ErrBadLayout
+func (e *SaveEditor) ReadSaveFrom(r io.ReaderAt) error {
+	// 重新读取后之前的修改不再适用
+	e.loaded = nil
+	e.RawEdits = nil
+	e.partyChanged = false
+
+	// 保留读取时的内容，内容不完整时由下面的读取报告错误
+	_, content, err := utils.ReadAndConvert[struct{}](r, 0, models.SaveFileSize, nil)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	e.content = content
 
 	// 读取角色数据
-	characters, err := reader.ReadCharacters(file)
+	characters, err := reader.ReadCharacters(r)
 	if err != nil {
 		return err
 	}
 	e.Characters = characters
 
+	// 队伍人数应与角色记录数一致
+	partyCount, err := reader.ReadPartyCount(r)
+	if err != nil {
+		return err
+	}
+	if partyCount != len(characters) {
+		return fmt.Errorf("%w: 队伍人数为%d，但读取到%d条角色记录", ErrBadLayout, partyCount, len(characters))
+	}
+
 	// 读取银两数据
-	moneyInfo, err := reader.ReadMoneyData(file, 203054)
+	moneyInfo, err := reader.ReadMoneyData(r, models.MoneyPosition)
 	if err != nil {
-		// 银两数据读取失败不会中断整体操作
-		moneyInfo = models.MoneyInfo{}
+		return err
 	}
 	e.MoneyInfo = moneyInfo
 
+	// 读取队伍位置数据
+	positionInfo, err := reader.ReadPosition(r, models.MapPosition)
+	if err != nil {
+		return err
+	}
+	e.PositionInfo = positionInfo
+
 	return nil
 }
 
 // SaveChanges 将修改保存到新文件
 func (e *SaveEditor) SaveChanges(sourceFilePath, destFilePath string) error {
-	return writer.SaveChanges(sourceFilePath, destFilePath, e.Characters, e.MoneyInfo)
+	// 不覆盖读取后被其他程序修改过的存档
+	if err := e.checkNotModified(sourceFilePath, destFilePath); err != nil {
+		return err
+	}
+
+	if e.partyChanged {
+		// 先复制文件并重写队伍记录区，再写入各字段
+		err := writer.SaveChanges(sourceFilePath, destFilePath, nil, e.MoneyInfo)
+		if err != nil {
+			return err
+		}
+		err = writer.SaveParty(destFilePath, e.Characters)
+		if err != nil {
+			return err
+		}
+		sourceFilePath = destFilePath
+	}
+
+	err := writer.SaveChanges(sourceFilePath, destFilePath, e.Characters, e.MoneyInfo)
+	if err != nil {
+		return err
+	}
+	err = writer.SavePosition(destFilePath, e.PositionInfo)
+	if err != nil {
+		return err
+	}
+	err = writer.SaveRawEdits(destFilePath, e.RawEdits)
+	if err != nil {
+		return err
+	}
+
+	// 原始字节修改已写入，并入读取的内容和角色记录后清空，不会在之后的保存中覆盖新的修改
+	e.content = e.ApplyRawEdits(e.content)
+	for i, char := range e.Characters {
+		record := make([]byte, len(char.RecordBytes))
+		copy(record, char.RecordBytes)
+		for _, edit := range e.RawEdits {
+			overlay(record, char.Position, edit.Data, edit.Position)
+		}
+		e.Characters[i].RecordBytes = record
+	}
+	e.RawEdits = nil
+
+	// 覆盖了读取的存档时更新记录，使自身的写入不被视为外部修改
+	// 队伍记录已写入，之后的保存不必再重写队伍记录区
+	if e.loaded != nil && samePath(destFilePath, e.loaded.path) {
+		content, err := os.ReadFile(destFilePath)
+		if err != nil {
+			return err
+		}
+		e.content = content
+		e.takeSnapshot(destFilePath, content)
+		e.partyChanged = false
+	}
+	return nil
 }
 
 // GetCharacterCount 获取角色数量
@@ -72,6 +180,37 @@ func (e *SaveEditor) UpdateMoney(value int32) {
 	e.MoneyInfo.Value = value
 }
 
+// UpdatePosition 更新队伍所在地图及坐标，不修改 WC.cfg 中的进度位置编号
+func (e *SaveEditor) UpdatePosition(mapID, x, y int32) {
+	e.PositionInfo.MapID = mapID
+	e.PositionInfo.X = x
+	e.PositionInfo.Y = y
+}
+
+// UpdateRawBytes 记录一次原始字节修改，保存时覆盖对应位置的内容
+func (e *SaveEditor) UpdateRawBytes(position int64, data []byte) error {
+	if position < 0 || position+int64(len(data)) > models.SaveFileSize {
+		return fmt.Errorf("修改位置超出存档范围: %d", position)
+	}
+
+	edit := models.RawEdit{Position: position, Data: make([]byte, len(data))}
+	copy(edit.Data, data)
+	e.RawEdits = append(e.RawEdits, edit)
+	return nil
+}
+
+// ApplyRawEdits 将尚未保存的原始字节修改叠加到文件内容上
+func (e *SaveEditor) ApplyRawEdits(content []byte) []byte {
+	result := make([]byte, len(content))
+	copy(result, content)
+	for _, edit := range e.RawEdits {
+		if edit.Position >= 0 && edit.Position+int64(len(edit.Data)) <= int64(len(result)) {
+			copy(result[edit.Position:], edit.Data)
+		}
+	}
+	return result
+}
+
 // UpdateCharacter 更新角色信息
 func (e *SaveEditor) UpdateCharacter(index int, data models.CharacterData) bool {
 	if index >= 0 && index < len(e.Characters) {
@@ -81,18 +220,182 @@ func (e *SaveEditor) UpdateCharacter(index int, data models.CharacterData) bool
 	return false
 }
 
+// AddCharacter 在队伍末尾添加角色
+// 若 character.RecordBytes 为完整的角色记录，则以其作为模板（属性由调用方指定）；
+// 否则以队伍中第一个角色为模板，沿用其记录和属性，只替换名字
+// 成员编号始终由调用方指定，不能与队伍中已有角色重复
+func (e *SaveEditor) AddCharacter(character models.CharacterInfo) error {
+	if len(e.Characters) >= models.MaxCharacters {
+		return fmt.Errorf("队伍已满，最多%d个角色", models.MaxCharacters)
+	}
+	for i, char := range e.Characters {
+		if char.MemberID == character.MemberID {
+			return fmt.Errorf("%w: 成员编号%d已被角色%d使用", models.ErrBadLayout, character.MemberID, i+1)
+		}
+	}
+
+	nameBytes, err := writer.EncodeName(character.Name)
+	if err != nil {
+		return err
+	}
+
+	if len(character.RecordBytes) != models.CharacterRecordSize {
+		if len(e.Characters) == 0 {
+			return fmt.Errorf("没有可作为模板的角色记录")
+		}
+		template := e.Characters[0]
+		character.Data = template.Data
+		character.RecordBytes = template.RecordBytes
+	}
+	recordBytes := make([]byte, models.CharacterRecordSize)
+	copy(recordBytes, character.RecordBytes)
+	copy(recordBytes, nameBytes)
+
+	character.RecordBytes = recordBytes
+	character.Position = -1 // 新角色没有原来的记录位置
+	e.Characters = append(e.Characters, character)
+	e.renumberCharacters()
+	e.partyChanged = true
+	return nil
+}
+
+// RemoveCharacter 移除指定索引的角色，队伍至少保留一个角色
+func (e *SaveEditor) RemoveCharacter(index int) error {
+	if index < 0 || index >= len(e.Characters) {
+		return fmt.Errorf("无效的角色索引: %d", index)
+	}
+	if len(e.Characters) <= 1 {
+		return fmt.Errorf("队伍至少需要保留一个角色")
+	}
+
+	e.Characters = append(e.Characters[:index], e.Characters[index+1:]...)
+	e.renumberCharacters()
+	e.partyChanged = true
+	return nil
+}
+
+// MoveCharacter 将角色从 from 位置移动到 to 位置
+func (e *SaveEditor) MoveCharacter(from, to int) error {
+	if from < 0 || from >= len(e.Characters) {
+		return fmt.Errorf("无效的角色索引: %d", from)
+	}
+	if to < 0 || to >= len(e.Characters) {
+		return fmt.Errorf("无效的目标索引: %d", to)
+	}
+	if from == to {
+		return nil
+	}
+
+	char := e.Characters[from]
+	e.Characters = append(e.Characters[:from], e.Characters[from+1:]...)
+	e.Characters = append(e.Characters[:to], append([]models.CharacterInfo{char}, e.Characters[to:]...)...)
+	e.renumberCharacters()
+	e.partyChanged = true
+	return nil
+}
+
+// renumberCharacters 按当前顺序重新计算每个角色记录的位置，角色记录区内的原始字节修改随所属角色移动
+func (e *SaveEditor) renumberCharacters() {
+	oldPositions := make([]int64, len(e.Characters))
+	for i := range e.Characters {
+		oldPositions[i] = e.Characters[i].Position
+		e.Characters[i].Position = models.CharacterStartPosition + int64(i*models.CharacterRecordSize)
+	}
+	e.moveRawEdits(oldPositions)
+}
+
+// moveRawEdits 将角色记录区内的原始字节修改移动到所属角色的新记录位置
+// oldPositions[i] 为第 i 个角色调整前的记录位置；修改按记录边界拆分，不属于现有角色（已移除或原为空记录）的部分被丢弃
+func (e *SaveEditor) moveRawEdits(oldPositions []int64) {
+	const areaStart = models.CharacterStartPosition
+	const areaEnd = areaStart + models.MaxCharacters*models.CharacterRecordSize
+
+	edits := make([]models.RawEdit, 0, len(e.RawEdits))
+	for _, edit := range e.RawEdits {
+		for start := int64(0); start < int64(len(edit.Data)); {
+			position := edit.Position + start
+			// 截取到下一个边界（记录区起止或记录之间）为止的部分
+			end := int64(len(edit.Data))
+			switch {
+			case position < areaStart:
+				end = min(end, areaStart-edit.Position)
+			case position < areaEnd:
+				recordEnd := areaStart + ((position-areaStart)/models.CharacterRecordSize+1)*models.CharacterRecordSize
+				end = min(end, recordEnd-edit.Position)
+			}
+			piece := models.RawEdit{Position: position, Data: edit.Data[start:end]}
+			start = end
+
+			if position < areaStart || position >= areaEnd {
+				edits = append(edits, piece)
+				continue
+			}
+			oldBase := areaStart + (position-areaStart)/models.CharacterRecordSize*models.CharacterRecordSize
+			for i, oldPosition := range oldPositions {
+				if oldPosition == oldBase {
+					piece.Position = e.Characters[i].Position + position - oldBase
+					edits = append(edits, piece)
+					break
+				}
+			}
+		}
+	}
+	e.RawEdits = edits
+}
+
 // ReadProgress 从 WC.cfg 文件中读取进度信息
 func (e *SaveEditor) ReadProgress(cfgFilePath string) ([]models.ProgressInfo, error) {
-	file, err := os.Open(cfgFilePath)
+	content, err := os.ReadFile(cfgFilePath)
+	if err != nil {
+		return nil, err
+	}
+	progressInfos, err := e.ReadProgressFrom(bytes.NewReader(content))
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	e.progress = &snapshot{path: cfgFilePath, sum: sha256.Sum256(content)}
+	return progressInfos, nil
+}
 
-	progressInfos, err := reader.ReadProgress(file)
+// ReadProgressFrom 从任意来源读取 WC.cfg 内容中的进度信息
+func (e *SaveEditor) ReadProgressFrom(r io.ReaderAt) ([]models.ProgressInfo, error) {
+	progressInfos, err := reader.ReadProgress(r)
 	if err != nil {
 		return nil, err
 	}
 	e.ProgressInfos = progressInfos
 	return e.ProgressInfos, nil
 }
+
+// UpdateProgressLocation 修改 WC.cfg 中指定进度的位置编号，返回修改前的备份文件路径
+// locationID 为 WC.cfg 中的位置编号，与存档中的地图编号不是同一套编号，不能直接使用队伍位置
+// 必须先用 ReadProgress 读取同一文件，读取后文件被游戏修改时返回 ErrFileChanged
+func (e *SaveEditor) UpdateProgressLocation(cfgFilePath string, progressIndex int, locationID int) (string, error) {
+	if e.progress == nil || !samePath(cfgFilePath, e.progress.path) {
+		return "", fmt.Errorf("请先读取 %s 的进度信息", filepath.Base(cfgFilePath))
+	}
+	sum, err := fileSum(cfgFilePath)
+	if err != nil {
+		return "", err
+	}
+	if sum != e.progress.sum {
+		return "", ErrFileChanged
+	}
+
+	backupPath, err := BackupFile(cfgFilePath)
+	if err != nil {
+		return "", fmt.Errorf("备份 %s 失败: %w", filepath.Base(cfgFilePath), err)
+	}
+	if err := writer.SaveProgressLocation(cfgFilePath, progressIndex, locationID); err != nil {
+		return backupPath, err
+	}
+	if e.progress.sum, err = fileSum(cfgFilePath); err != nil {
+		return backupPath, err
+	}
+
+	if progressIndex >= 0 && progressIndex < len(e.ProgressInfos) {
+		e.ProgressInfos[progressIndex].LocationID = locationID
+		e.ProgressInfos[progressIndex].LocationName = reader.GetLocationNameByID(locationID)
+	}
+	return backupPath, nil
+}
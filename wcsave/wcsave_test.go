@@ -1,10 +1,18 @@
 package wcsave
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"wcediter/wcsave/layout"
 	"wcediter/wcsave/models"
+	"wcediter/wcsave/reader"
+	"wcediter/wcsave/testsave"
 )
 
 // 测试NewSaveEditor函数
@@ -23,7 +31,7 @@ func TestGetCharacterCount(t *testing.T) {
 	editor := NewSaveEditor()
 	editor.Characters = append(editor.Characters, models.CharacterInfo{Name: "Test"})
 	editor.Characters = append(editor.Characters, models.CharacterInfo{Name: "Test2"})
-	
+
 	if count := editor.GetCharacterCount(); count != 2 {
 		t.Errorf("GetCharacterCount应返回2，实际返回%d", count)
 	}
@@ -34,24 +42,24 @@ func TestGetCharacterByIndex(t *testing.T) {
 	editor := NewSaveEditor()
 	editor.Characters = append(editor.Characters, models.CharacterInfo{Name: "Test"})
 	editor.Characters = append(editor.Characters, models.CharacterInfo{Name: "Test2"})
-	
+
 	// 测试有效索引
 	char, found := editor.GetCharacterByIndex(0)
 	if !found || char.Name != "Test" {
 		t.Errorf("获取索引0的角色失败")
 	}
-	
+
 	char, found = editor.GetCharacterByIndex(1)
 	if !found || char.Name != "Test2" {
 		t.Errorf("获取索引1的角色失败")
 	}
-	
+
 	// 测试无效索引
 	_, found = editor.GetCharacterByIndex(-1)
 	if found {
 		t.Errorf("索引-1应该返回false")
 	}
-	
+
 	_, found = editor.GetCharacterByIndex(2)
 	if found {
 		t.Errorf("索引2应该返回false")
@@ -62,7 +70,7 @@ func TestGetCharacterByIndex(t *testing.T) {
 func TestUpdateMoney(t *testing.T) {
 	editor := NewSaveEditor()
 	editor.MoneyInfo.Value = 100
-	
+
 	editor.UpdateMoney(200)
 	if editor.MoneyInfo.Value != 200 {
 		t.Errorf("UpdateMoney失败，预期200，实际%d", editor.MoneyInfo.Value)
@@ -77,7 +85,7 @@ func TestUpdateCharacter(t *testing.T) {
 		Data: models.CharacterData{Strength: 10},
 	}
 	editor.Characters = append(editor.Characters, char)
-	
+
 	// 更新有效索引
 	newData := models.CharacterData{Strength: 20}
 	result := editor.UpdateCharacter(0, newData)
@@ -87,7 +95,7 @@ func TestUpdateCharacter(t *testing.T) {
 	if editor.Characters[0].Data.Strength != 20 {
 		t.Errorf("角色数据更新失败，预期20，实际%d", editor.Characters[0].Data.Strength)
 	}
-	
+
 	// 更新无效索引
 	result = editor.UpdateCharacter(1, newData)
 	if result {
@@ -97,20 +105,804 @@ func TestUpdateCharacter(t *testing.T) {
 
 // 测试ReadSave和SaveChanges功能（集成测试）
 func TestSaveEditorIntegration(t *testing.T) {
-	// 这个测试需要一个有效的测试数据文件
-	testFilePath := "../data/Save4.dat"
-	if _, err := os.Stat(testFilePath); os.IsNotExist(err) {
-		t.Skip("测试数据文件不存在，跳过集成测试")
-	}
-	
+	testFilePath := copyTestSave(t)
+
 	editor := NewSaveEditor()
 	err := editor.ReadSave(testFilePath)
 	if err != nil {
 		t.Fatalf("读取测试文件失败: %v", err)
 	}
-	
+
 	// 验证是否读取到了数据
 	if len(editor.Characters) == 0 {
 		t.Error("没有读取到角色数据")
 	}
-}
\ No newline at end of file
+
+	// 验证是否读取到了位置数据
+	if len(editor.PositionInfo.RawBytes) == 0 {
+		t.Error("没有读取到位置数据")
+	}
+}
+
+// 测试UpdatePosition函数
+func TestUpdatePosition(t *testing.T) {
+	editor := NewSaveEditor()
+	editor.UpdatePosition(4, 52, 56)
+
+	if editor.PositionInfo.MapID != 4 || editor.PositionInfo.X != 52 || editor.PositionInfo.Y != 56 {
+		t.Errorf("UpdatePosition失败，实际(%d, %d, %d)", editor.PositionInfo.MapID, editor.PositionInfo.X, editor.PositionInfo.Y)
+	}
+}
+
+// 测试AddCharacter、RemoveCharacter和MoveCharacter函数
+func TestPartyComposition(t *testing.T) {
+	if err := NewSaveEditor().AddCharacter(models.CharacterInfo{Name: "雄霸"}); err == nil {
+		t.Error("没有模板角色时添加应该返回错误")
+	}
+
+	editor := readTestSave(t, testsave.Default())
+	if err := editor.MoveCharacter(1, 0); err != nil {
+		t.Fatalf("MoveCharacter失败: %v", err)
+	}
+	if editor.Characters[0].Name != "素還真" || editor.Characters[1].Name != "葉小釵" {
+		t.Errorf("角色顺序错误: %s, %s", editor.Characters[0].Name, editor.Characters[1].Name)
+	}
+	if editor.Characters[1].Position != models.CharacterStartPosition+models.CharacterRecordSize {
+		t.Errorf("角色位置未重新计算，实际%d", editor.Characters[1].Position)
+	}
+
+	for editor.GetCharacterCount() > 1 {
+		if err := editor.RemoveCharacter(0); err != nil {
+			t.Fatalf("RemoveCharacter失败: %v", err)
+		}
+	}
+	if editor.Characters[0].Name != "一頁書" || editor.Characters[0].Position != models.CharacterStartPosition {
+		t.Errorf("移除角色后队伍数据错误")
+	}
+
+	// 最后一个角色不允许移除
+	if err := editor.RemoveCharacter(0); err == nil {
+		t.Error("移除最后一个角色应该返回错误")
+	}
+
+	// 成员编号不能与已有角色重复
+	if err := editor.AddCharacter(models.CharacterInfo{Name: "雄霸", MemberID: editor.Characters[0].MemberID}); !errors.Is(err, models.ErrBadLayout) {
+		t.Errorf("成员编号重复时应该返回ErrBadLayout，实际: %v", err)
+	}
+
+	// 不指定模板时沿用第一个角色的记录和属性，只替换名字，成员编号由调用方指定
+	if err := editor.AddCharacter(models.CharacterInfo{Name: "雄霸", MemberID: 1}); err != nil {
+		t.Fatalf("AddCharacter失败: %v", err)
+	}
+	added, first := editor.Characters[1], editor.Characters[0]
+	if added.Name != "雄霸" || added.Data != first.Data || added.MemberID != 1 || !bytes.Equal(added.RecordBytes[6:], first.RecordBytes[6:]) {
+		t.Errorf("添加的角色应以第一个角色为模板: %+v", added)
+	}
+	if bytes.Equal(added.RecordBytes[:6], first.RecordBytes[:6]) {
+		t.Error("添加的角色应使用新名字")
+	}
+
+	// 队伍已满时不允许添加
+	for editor.GetCharacterCount() < models.MaxCharacters {
+		if err := editor.AddCharacter(models.CharacterInfo{Name: "雄霸", MemberID: int16(editor.GetCharacterCount())}); err != nil {
+			t.Fatalf("AddCharacter失败: %v", err)
+		}
+	}
+	if err := editor.AddCharacter(models.CharacterInfo{Name: "雄霸", MemberID: 10}); err == nil {
+		t.Error("队伍已满时添加角色应该返回错误")
+	}
+}
+
+// 测试调整队伍后成员编号表随角色记录一起写入，保存后不再重写队伍记录区
+func TestPartyMemberTable(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "Save4.dat")
+	if err := testsave.Default().WriteFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	members := func() []uint16 {
+		t.Helper()
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		table := make([]uint16, models.MaxCharacters)
+		for i := range table {
+			table[i] = binary.LittleEndian.Uint16(content[models.PartyMemberPosition+i*2:])
+		}
+		return table
+	}
+	editor := NewSaveEditor()
+	if err := editor.ReadSave(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if ids := [3]int16{editor.Characters[0].MemberID, editor.Characters[1].MemberID, editor.Characters[2].MemberID}; ids != [3]int16{0, 8, 9} {
+		t.Fatalf("成员编号读取错误: %v", ids)
+	}
+
+	// 移除第一个角色
+	if err := editor.RemoveCharacter(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.SaveChanges(filePath, filePath); err != nil {
+		t.Fatal(err)
+	}
+	if table := members(); table[0] != 8 || table[1] != 9 || table[2] != 0 {
+		t.Errorf("移除角色后的成员编号表错误: %v", table)
+	}
+
+	// 调整顺序
+	if err := editor.MoveCharacter(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.SaveChanges(filePath, filePath); err != nil {
+		t.Fatal(err)
+	}
+	if table := members(); table[0] != 9 || table[1] != 8 || table[2] != 0 {
+		t.Errorf("调整顺序后的成员编号表错误: %v", table)
+	}
+	reloaded := NewSaveEditor()
+	if err := reloaded.ReadSave(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Characters[0].Name != "一頁書" || reloaded.Characters[0].MemberID != 9 || reloaded.Characters[1].MemberID != 8 {
+		t.Errorf("重新读取的队伍错误: %+v", reloaded.Characters)
+	}
+
+	// 保存后队伍不再视为已调整，游戏写入的成员编号不会被之后的保存覆盖
+	if editor.partyChanged {
+		t.Error("保存后应清除队伍调整标记")
+	}
+	content, _ := os.ReadFile(filePath)
+	binary.LittleEndian.PutUint16(content[models.PartyMemberPosition+4:], 7)
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := editor.Merge(); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.SaveChanges(filePath, filePath); err != nil {
+		t.Fatal(err)
+	}
+	if table := members(); table[2] != 7 {
+		t.Errorf("未调整队伍时不应重写成员编号表: %v", table)
+	}
+
+	// 成员编号重复的队伍不允许写入
+	editor.Characters[1].MemberID = editor.Characters[0].MemberID
+	if err := editor.MoveCharacter(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.SaveChanges(filePath, filePath); !errors.Is(err, ErrBadLayout) {
+		t.Errorf("成员编号重复时应该返回ErrBadLayout，实际: %v", err)
+	}
+	if table := members(); table[0] != 9 || table[1] != 8 {
+		t.Errorf("写入失败后成员编号表不应改变: %v", table)
+	}
+}
+
+// readTestSave 从生成的存档内容读取编辑器
+func readTestSave(t *testing.T, save testsave.Save) *SaveEditor {
+	t.Helper()
+	content, err := save.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor := NewSaveEditor()
+	if err := editor.ReadSaveFrom(bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	return editor
+}
+
+// 测试调整队伍成员后保存（集成测试）
+func TestPartyCompositionIntegration(t *testing.T) {
+	testFilePath := copyTestSave(t)
+
+	editor := NewSaveEditor()
+	if err := editor.ReadSave(testFilePath); err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+	originalCount := editor.GetCharacterCount()
+	originalMoney := editor.MoneyInfo.Value
+	lastName := editor.Characters[originalCount-1].Name
+	lastLevel := editor.Characters[originalCount-1].Data.Level
+
+	// 移除第一个角色并修改新的第一个角色的等级
+	if err := editor.RemoveCharacter(0); err != nil {
+		t.Fatalf("RemoveCharacter失败: %v", err)
+	}
+	data := editor.Characters[0].Data
+	data.Level = 99
+	editor.UpdateCharacter(0, data)
+
+	destFilePath := filepath.Join(t.TempDir(), "Save_party.dat")
+	if err := editor.SaveChanges(testFilePath, destFilePath); err != nil {
+		t.Fatalf("SaveChanges失败: %v", err)
+	}
+
+	reloaded := NewSaveEditor()
+	if err := reloaded.ReadSave(destFilePath); err != nil {
+		t.Fatalf("重新读取文件失败: %v", err)
+	}
+	if reloaded.GetCharacterCount() != originalCount-1 {
+		t.Fatalf("角色数量错误，预期%d，实际%d", originalCount-1, reloaded.GetCharacterCount())
+	}
+	if reloaded.Characters[0].Data.Level != 99 {
+		t.Errorf("等级未写入，实际%d", reloaded.Characters[0].Data.Level)
+	}
+	last := reloaded.Characters[len(reloaded.Characters)-1]
+	if last.Name != lastName || last.Data.Level != lastLevel {
+		t.Errorf("末尾角色数据错误: %s %d", last.Name, last.Data.Level)
+	}
+	if reloaded.MoneyInfo.Value != originalMoney {
+		t.Errorf("银两数据被破坏，预期%d，实际%d", originalMoney, reloaded.MoneyInfo.Value)
+	}
+
+	// 验证队伍人数
+	content, err := os.ReadFile(destFilePath)
+	if err != nil {
+		t.Fatalf("读取文件失败: %v", err)
+	}
+	if count := binary.LittleEndian.Uint16(content[models.PartyCountPosition:]); int(count) != originalCount-1 {
+		t.Errorf("队伍人数错误，预期%d，实际%d", originalCount-1, count)
+	}
+}
+
+// 测试UpdateRawBytes和ApplyRawEdits函数
+func TestRawEdits(t *testing.T) {
+	editor := NewSaveEditor()
+	if err := editor.UpdateRawBytes(2, []byte{0xAA, 0xBB}); err != nil {
+		t.Fatalf("UpdateRawBytes失败: %v", err)
+	}
+	if err := editor.UpdateRawBytes(3, []byte{0xCC}); err != nil {
+		t.Fatalf("UpdateRawBytes失败: %v", err)
+	}
+
+	result := editor.ApplyRawEdits(make([]byte, 6))
+	expected := []byte{0, 0, 0xAA, 0xCC, 0, 0}
+	if string(result) != string(expected) {
+		t.Errorf("叠加结果错误，预期% X，实际% X", expected, result)
+	}
+
+	// 越界修改
+	if err := editor.UpdateRawBytes(models.SaveFileSize-1, []byte{1, 2}); err == nil {
+		t.Error("越界修改应该返回错误")
+	}
+}
+
+// 测试原始字节修改在调整队伍、保存和重新读取时的处理
+func TestRawEditsLifecycle(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "Save4.dat")
+	if err := testsave.Default().WriteFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	editor := NewSaveEditor()
+	if err := editor.ReadSave(filePath); err != nil {
+		t.Fatal(err)
+	}
+	record := func(i int) int64 {
+		return models.CharacterStartPosition + int64(i*models.CharacterRecordSize)
+	}
+
+	// 第三个角色记录末尾到下一条记录开头、第一个角色记录中以及记录区之外各有一处修改
+	if err := editor.UpdateRawBytes(record(3)-1, []byte{0xA1, 0xA2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.UpdateRawBytes(record(0)+60, []byte{0xB1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.UpdateRawBytes(models.MoneyPosition, []byte{0xC1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第三个角色移到最前，修改随角色移动；空记录中的部分被丢弃
+	if err := editor.MoveCharacter(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	expected := []models.RawEdit{
+		{Position: record(1) - 1, Data: []byte{0xA1}},
+		{Position: record(1) + 60, Data: []byte{0xB1}},
+		{Position: models.MoneyPosition, Data: []byte{0xC1}},
+	}
+	if !reflect.DeepEqual(editor.RawEdits, expected) {
+		t.Fatalf("调整顺序后的原始字节修改错误: %+v", editor.RawEdits)
+	}
+
+	// 移除角色时丢弃其记录中的修改
+	if err := editor.RemoveCharacter(0); err != nil {
+		t.Fatal(err)
+	}
+	expected = []models.RawEdit{
+		{Position: record(0) + 60, Data: []byte{0xB1}},
+		{Position: models.MoneyPosition, Data: []byte{0xC1}},
+	}
+	if !reflect.DeepEqual(editor.RawEdits, expected) {
+		t.Fatalf("移除角色后的原始字节修改错误: %+v", editor.RawEdits)
+	}
+
+	// 保存后清空修改，之后字段的修改不会被旧的原始字节覆盖
+	if err := editor.SaveChanges(filePath, filePath); err != nil {
+		t.Fatal(err)
+	}
+	if len(editor.RawEdits) != 0 {
+		t.Fatalf("保存后应清空原始字节修改: %+v", editor.RawEdits)
+	}
+	if current, err := editor.CurrentBytes(record(0)+60, 1); err != nil || current[0] != 0xB1 {
+		t.Errorf("保存后的当前内容错误: % X %v", current, err)
+	}
+	editor.MoneyInfo.Value = 0x1234
+	if err := editor.SaveChanges(filePath, filePath); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if money := binary.LittleEndian.Uint32(content[models.MoneyPosition:]); money != 0x1234 {
+		t.Errorf("金钱被旧的原始字节修改覆盖: 0x%X", money)
+	}
+
+	// 重新读取时清空未保存的修改
+	if err := editor.UpdateRawBytes(models.MoneyPosition, []byte{0xD1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.ReadSave(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if len(editor.RawEdits) != 0 {
+		t.Errorf("重新读取后应清空原始字节修改: %+v", editor.RawEdits)
+	}
+}
+
+// 在临时目录生成测试存档
+func copyTestSave(t *testing.T) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "Save4.dat")
+	if err := testsave.Default().WriteFile(filePath); err != nil {
+		t.Fatalf("生成测试存档失败: %v", err)
+	}
+	return filePath
+}
+
+// 模拟游戏修改存档中第一个角色的等级和银两
+func modifyExternally(t *testing.T, filePath string, level int16, money int32) {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	buffer := make([]byte, 4)
+	binary.LittleEndian.PutUint16(buffer, uint16(level))
+	if _, err := file.WriteAt(buffer[:2], models.CharacterStartPosition+70); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	binary.LittleEndian.PutUint32(buffer, uint32(money))
+	if _, err := file.WriteAt(buffer, models.MoneyPosition); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+}
+
+// 测试存档被外部修改后阻止覆盖
+func TestExternalModification(t *testing.T) {
+	filePath := copyTestSave(t)
+	editor := NewSaveEditor()
+	if err := editor.ReadSave(filePath); err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+
+	// 自身的保存不视为外部修改
+	editor.UpdateMoney(100)
+	if err := editor.SaveChanges(filePath, filePath); err != nil {
+		t.Fatalf("SaveChanges失败: %v", err)
+	}
+	if modified, err := editor.ExternallyModified(); err != nil || modified {
+		t.Fatalf("自身保存后不应视为外部修改: %v, %v", modified, err)
+	}
+
+	modifyExternally(t, filePath, 50, 200)
+	if modified, _ := editor.ExternallyModified(); !modified {
+		t.Fatal("应该检测到外部修改")
+	}
+	if err := editor.SaveChanges(filePath, filePath); !errors.Is(err, ErrFileChanged) {
+		t.Fatalf("应该返回ErrFileChanged，实际: %v", err)
+	}
+
+	// 以被修改的存档为源另存为其他文件同样被阻止
+	if err := editor.SaveChanges(filePath, filepath.Join(t.TempDir(), "copy.dat")); !errors.Is(err, ErrFileChanged) {
+		t.Errorf("源文件被修改时另存也应返回ErrFileChanged，实际: %v", err)
+	}
+}
+
+// 测试合并外部修改
+func TestMerge(t *testing.T) {
+	filePath := copyTestSave(t)
+	editor := NewSaveEditor()
+	if err := editor.ReadSave(filePath); err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+
+	// 编辑器中修改第二个角色的攻击和银两，游戏修改第一个角色的等级和银两
+	data := editor.Characters[1].Data
+	data.Attack = 999
+	editor.UpdateCharacter(1, data)
+	editor.UpdateMoney(300)
+	modifyExternally(t, filePath, 50, 200)
+
+	conflicts, err := editor.Merge()
+	if err != nil {
+		t.Fatalf("Merge失败: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != "银两" {
+		t.Errorf("冲突列表错误: %v", conflicts)
+	}
+	if editor.Characters[0].Data.Level != 50 {
+		t.Errorf("应保留游戏的修改，等级为%d", editor.Characters[0].Data.Level)
+	}
+	if editor.Characters[1].Data.Attack != 999 || editor.MoneyInfo.Value != 300 {
+		t.Errorf("应保留编辑器的修改: 攻击%d，银两%d", editor.Characters[1].Data.Attack, editor.MoneyInfo.Value)
+	}
+
+	// 合并后可以保存
+	if err := editor.SaveChanges(filePath, filePath); err != nil {
+		t.Fatalf("合并后SaveChanges失败: %v", err)
+	}
+
+	// 调整队伍后不能合并
+	if err := editor.RemoveCharacter(0); err != nil {
+		t.Fatalf("RemoveCharacter失败: %v", err)
+	}
+	if _, err := editor.Merge(); err == nil {
+		t.Error("调整队伍后合并应该返回错误")
+	}
+}
+
+// 测试从内存读取存档
+func TestReadSaveFrom(t *testing.T) {
+	testFilePath := copyTestSave(t)
+	content, err := os.ReadFile(testFilePath)
+	if err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+
+	fromFile := NewSaveEditor()
+	if err := fromFile.ReadSave(testFilePath); err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+	fromMemory := NewSaveEditor()
+	if err := fromMemory.ReadSaveFrom(bytes.NewReader(content)); err != nil {
+		t.Fatalf("从内存读取失败: %v", err)
+	}
+
+	if len(fromMemory.Characters) != len(fromFile.Characters) {
+		t.Fatalf("角色数量不一致: %d != %d", len(fromMemory.Characters), len(fromFile.Characters))
+	}
+	for i := range fromFile.Characters {
+		if fromMemory.Characters[i].Data != fromFile.Characters[i].Data {
+			t.Errorf("角色%d的数据不一致", i)
+		}
+	}
+	if fromMemory.MoneyInfo.Value != fromFile.MoneyInfo.Value || fromMemory.PositionInfo.MapID != fromFile.PositionInfo.MapID {
+		t.Error("银两或位置不一致")
+	}
+	// 内存中的存档没有对应的文件
+	if fromMemory.LoadedPath() != "" {
+		t.Errorf("从内存读取后不应记录路径，实际%q", fromMemory.LoadedPath())
+	}
+}
+
+// 测试读取错误的类型
+func TestReadSaveErrors(t *testing.T) {
+	content, err := testsave.Default().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 在银两之前截断
+	editor := NewSaveEditor()
+	err = editor.ReadSaveFrom(bytes.NewReader(content[:models.MoneyPosition+2]))
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatalf("预期返回 ErrTruncated，实际: %v", err)
+	}
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "银两" || fieldErr.Offset != models.MoneyPosition {
+		t.Errorf("预期银两字段的 FieldError，实际: %v", err)
+	}
+	if editor.GetCharacterCount() == 0 {
+		t.Error("截断位置之前的角色数据应已读取")
+	}
+	if Advice(err) == "" {
+		t.Error("ErrTruncated 应有处理建议")
+	}
+
+	// 队伍人数与角色记录不一致
+	modified := append([]byte(nil), content...)
+	binary.LittleEndian.PutUint16(modified[models.PartyCountPosition:], 5)
+	err = NewSaveEditor().ReadSaveFrom(bytes.NewReader(modified))
+	if !errors.Is(err, ErrBadLayout) {
+		t.Errorf("预期返回 ErrBadLayout，实际: %v", err)
+	}
+
+	// 文件不存在不属于存档内容错误
+	err = NewSaveEditor().ReadSave(filepath.Join(t.TempDir(), "missing.dat"))
+	if errors.Is(err, ErrTruncated) || errors.Is(err, ErrBadLayout) || Advice(err) == "" {
+		t.Errorf("文件不存在时的错误类型不正确: %v", err)
+	}
+}
+
+// 测试自定义字段的读取和修改
+func TestCustomValue(t *testing.T) {
+	content, err := testsave.Default().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第二个角色记录中尚未解析的字节
+	binary.LittleEndian.PutUint16(content[models.CharacterStartPosition+models.CharacterRecordSize+44:], 321)
+	editor := NewSaveEditor()
+	if err := editor.ReadSaveFrom(bytes.NewReader(content)); err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+
+	field := layout.Field{Name: "Unknown44", Label: "未知44", Offset: 44, Size: 2, Type: layout.TypeUint16}
+	second := field.At(editor.Characters[1].Position)
+	original := int64(binary.LittleEndian.Uint16(content[second.Offset:]))
+	if value, err := editor.CustomValue(second); err != nil || value != original {
+		t.Fatalf("读取自定义字段错误: %d %v，预期%d", value, err, original)
+	}
+
+	// 相同的值不产生修改
+	if err := editor.SetCustomValue(second, original); err != nil || len(editor.RawEdits) != 0 {
+		t.Fatalf("写入相同的值不应产生修改: %v %d", err, len(editor.RawEdits))
+	}
+	if err := editor.SetCustomValue(second, 70000); err == nil {
+		t.Error("超出范围的值应返回错误")
+	}
+	if err := editor.SetCustomValue(second, 1234); err != nil {
+		t.Fatalf("写入自定义字段失败: %v", err)
+	}
+	if value, _ := editor.CustomValue(second); value != 1234 {
+		t.Errorf("修改后读取到%d，预期1234", value)
+	}
+
+	// 调整队伍顺序后，字段和尚未保存的修改随角色记录移动
+	if err := editor.MoveCharacter(1, 0); err != nil {
+		t.Fatalf("MoveCharacter失败: %v", err)
+	}
+	first := field.At(editor.Characters[0].Position)
+	if value, _ := editor.CustomValue(first); value != 1234 {
+		t.Errorf("移动后的角色字段为%d，预期1234", value)
+	}
+	editor.RawEdits = nil
+	if value, _ := editor.CustomValue(first); value != original {
+		t.Errorf("移动后的角色字段为%d，预期%d", value, original)
+	}
+}
+
+// 测试游戏目录中存档与进度的关联
+func TestGameDir(t *testing.T) {
+	dir := t.TempDir()
+	config := testsave.DefaultConfig()
+	saves := map[string]testsave.Save{"Sald0.dat": testsave.Default()}
+	for i := 1; i < SlotCount; i++ {
+		save := testsave.Default()
+		save.MapID = int32(i)
+		saves[SlotFileName("Sav0", i)] = save
+	}
+	if err := testsave.WriteDir(dir, config, saves); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := OpenGameDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.ConfigErr != nil || len(g.Progress) != models.MaxProgress {
+		t.Fatalf("读取进度失败: %v", g.ConfigErr)
+	}
+	if names := g.VariantNames(); len(names) != 2 || names[0] != "Sald" || names[1] != "Sav0" {
+		t.Errorf("版本识别错误: %v", names)
+	}
+	if indexes := g.Set("Sav0").Indexes(); len(indexes) != SlotCount-1 || indexes[0] != 1 {
+		t.Errorf("存档编号错误: %v", indexes)
+	}
+
+	slot, err := g.Set("Sav0").Slot(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slot.HasProgress || slot.ProgressIndex() != 2 || slot.Progress.ProgressID != int(config.ProgressIDs[2]) {
+		t.Errorf("3号存档应对应进度3: %+v", slot.Progress)
+	}
+	if slot.Editor.PositionInfo.MapID != 3 || len(slot.Editor.ProgressInfos) != models.MaxProgress {
+		t.Errorf("存档内容错误: %+v", slot.Editor.PositionInfo)
+	}
+
+	// 0号存档没有进度，缺少的存档读取失败
+	slot, err = g.Set("Sald").Slot(0)
+	if err != nil || slot.HasProgress || slot.ProgressIndex() != -1 {
+		t.Errorf("0号存档不应有进度: %+v, %v", slot, err)
+	}
+	if _, err := g.Set("Sald").Slot(1); err == nil {
+		t.Error("不存在的存档应返回错误")
+	}
+
+	// 并行读取的概况与编号顺序一致，缺少的存档记录错误
+	summaries := g.Set("Sav0").Summaries([]int{0, 1, 2, 3, 4, 5})
+	if len(summaries) != SlotCount || summaries[0].Err == nil {
+		t.Fatalf("0号存档不存在时应记录错误: %+v", summaries)
+	}
+	for _, summary := range summaries[1:] {
+		if summary.Err != nil || summary.Position.MapID != int32(summary.Index) || len(summary.Characters) != 3 || summary.Money != testsave.Default().Money || summary.ModTime.IsZero() {
+			t.Errorf("%d号存档概况错误: %+v", summary.Index, summary)
+		}
+		if !summary.HasProgress || summary.Progress != g.Progress[summary.Index-1] {
+			t.Errorf("%d号存档的进度错误: %+v", summary.Index, summary.Progress)
+		}
+	}
+
+	// 从存档文件名找到所属的版本
+	_, set, index, err := OpenSlotFile(filepath.Join(dir, "Sav04.dat"))
+	if err != nil || set.Variant != "Sav0" || index != 4 || set.SlotPath(1) != filepath.Join(dir, "Sav01.dat") {
+		t.Errorf("解析存档文件名错误: %+v %d %v", set, index, err)
+	}
+	if _, _, _, err := OpenSlotFile(filepath.Join(dir, "WC.cfg")); err == nil {
+		t.Error("非存档文件应返回错误")
+	}
+
+	// 修改进度的位置编号只改变 WC.cfg 中对应的进度，不修改存档的地图编号
+	slot, err = g.Set("Sav0").Slot(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupPath, err := slot.UpdateProgressLocation(52)
+	if err != nil {
+		t.Fatalf("修改进度位置失败: %v", err)
+	}
+	if _, err := os.Stat(backupPath); err != nil {
+		t.Errorf("应备份 WC.cfg: %v", err)
+	}
+	reopened, err := OpenGameDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, info := range reopened.Progress {
+		expected := int(config.LocationIDs[i])
+		if i == 1 {
+			expected = 52
+		}
+		if info.LocationID != expected {
+			t.Errorf("进度%d的位置编号为%d，预期%d", i+1, info.LocationID, expected)
+		}
+	}
+	if slot.Progress.LocationID != 52 || slot.Progress.LocationName != reader.GetLocationNameByID(52) || slot.Editor.PositionInfo.MapID != 2 {
+		t.Errorf("修改后的进度或地图编号错误: %+v %d", slot.Progress, slot.Editor.PositionInfo.MapID)
+	}
+
+	// WC.cfg 中该进度被游戏修改后不覆盖
+	changed := config
+	changed.LocationIDs[1] = 60
+	if err := changed.WriteFile(filepath.Join(dir, ConfigFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := slot.UpdateProgressLocation(53); !errors.Is(err, ErrFileChanged) {
+		t.Errorf("WC.cfg 被修改后应返回ErrFileChanged，实际: %v", err)
+	}
+	slot, _ = g.Set("Sald").Slot(0)
+	if _, err := slot.UpdateProgressLocation(53); err == nil {
+		t.Error("0号存档没有进度，修改应返回错误")
+	}
+}
+
+// 测试在两个存档之间复制角色字段
+func TestCopyCharacter(t *testing.T) {
+	layout.Register(layout.Custom{Character: []layout.Field{
+		{Name: "Unknown44", Label: "未知44", Offset: 44, Size: 2, Type: layout.TypeUint16},
+	}})
+	defer layout.Register(layout.Custom{})
+
+	read := func(save testsave.Save) *SaveEditor {
+		t.Helper()
+		content, err := save.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		editor := NewSaveEditor()
+		if err := editor.ReadSaveFrom(bytes.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+		return editor
+	}
+	src := read(testsave.Default())
+	dst := read(testsave.Default())
+
+	// 源存档中尚未保存的修改也会被复制
+	char, _ := src.GetCharacterByIndex(2)
+	char.Data.Attack = 999
+	src.UpdateCharacter(2, char.Data)
+	custom := layout.Extra.Character[0]
+	if err := src.SetCustomValue(custom.At(char.Position), 321); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只复制选中的字段
+	changed, err := dst.CopyCharacter(src, 2, 0, []string{"Attack"})
+	if err != nil || len(changed) != 1 || changed[0] != "攻击" {
+		t.Fatalf("复制攻击失败: %v %v", changed, err)
+	}
+	if got, _ := dst.GetCharacterByIndex(0); got.Data.Attack != 999 || got.Data.Level != 12 {
+		t.Errorf("只应复制攻击: %+v", got.Data)
+	}
+
+	// 复制全部字段，包括自定义字段
+	changed, err = dst.CopyCharacter(src, 2, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := src.GetCharacterByIndex(2)
+	got, _ := dst.GetCharacterByIndex(0)
+	if got.Data != want.Data || got.Name != "葉小釵" {
+		t.Errorf("复制后的角色数据错误: %s %+v", got.Name, got.Data)
+	}
+	if value, _ := dst.CustomValue(custom.At(got.Position)); value != 321 {
+		t.Errorf("自定义字段为%d，预期321", value)
+	}
+	if len(changed) == 0 {
+		t.Error("应返回发生变化的字段")
+	}
+
+	// 无效的字段和角色不做修改
+	if _, err := dst.CopyCharacter(src, 2, 1, []string{"Level", "Name"}); err == nil {
+		t.Error("名字字段不能复制")
+	}
+	if got, _ := dst.GetCharacterByIndex(1); got.Data.Level != 15 {
+		t.Errorf("校验失败时不应修改: %+v", got.Data)
+	}
+	if _, err := dst.CopyCharacter(src, 5, 0, nil); err == nil {
+		t.Error("不存在的角色应返回错误")
+	}
+}
+
+// 测试修改 WC.cfg 中的进度位置：先备份，读取后被修改时拒绝写入
+func TestUpdateProgressLocation(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, ConfigFileName)
+	if err := testsave.DefaultConfig().WriteFile(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+	original, _ := os.ReadFile(cfgPath)
+
+	editor := NewSaveEditor()
+	if _, err := editor.UpdateProgressLocation(cfgPath, 2, 52); err == nil {
+		t.Fatal("未读取进度信息时应返回错误")
+	}
+	if _, err := editor.ReadProgress(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+	backupPath, err := editor.UpdateProgressLocation(cfgPath, 2, 52)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup, _ := os.ReadFile(backupPath); !bytes.Equal(backup, original) {
+		t.Error("备份内容应为修改前的 WC.cfg")
+	}
+	progress, err := NewSaveEditor().ReadProgress(cfgPath)
+	if err != nil || progress[2].LocationID != 52 || progress[1].LocationID != 90 || editor.ProgressInfos[2].LocationID != 52 {
+		t.Errorf("进度位置修改错误: %+v %v", progress, err)
+	}
+
+	// 自身的写入不算外部修改，可以连续修改
+	if _, err := editor.UpdateProgressLocation(cfgPath, 3, 82); err != nil {
+		t.Fatalf("连续修改失败: %v", err)
+	}
+
+	// 游戏在读取后写入了 WC.cfg
+	if err := os.WriteFile(cfgPath, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := editor.UpdateProgressLocation(cfgPath, 2, 91); !errors.Is(err, ErrFileChanged) {
+		t.Errorf("WC.cfg 被外部修改时应返回 ErrFileChanged，实际: %v", err)
+	}
+	if current, _ := os.ReadFile(cfgPath); !bytes.Equal(current, original) {
+		t.Error("被外部修改时不应写入")
+	}
+}
@@ -2,10 +2,15 @@ package writer
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 	"os"
+	"reflect"
 
+	"wcediter/wcsave/layout"
 	"wcediter/wcsave/models"
+
+	"golang.org/x/text/encoding/traditionalchinese"
 )
 
 // writeToFilePosition 写入数据到文件指定位置
@@ -44,6 +49,119 @@ func copyFile(src, dst string) error {
 	return copyErr
 }
 
+// SavePosition 将队伍位置写入存档文件
+func SavePosition(filePath string, positionInfo models.PositionInfo) error {
+	if positionInfo.Position == 0 || len(positionInfo.RawBytes) == 0 {
+		return nil
+	}
+
+	buffer := make([]byte, 4)
+
+	// 地图编号
+	binary.LittleEndian.PutUint32(buffer, uint32(positionInfo.MapID))
+	err := writeToFilePosition(filePath, positionInfo.Position, buffer)
+	if err != nil {
+		return err
+	}
+
+	// 横坐标
+	binary.LittleEndian.PutUint32(buffer, uint32(positionInfo.X))
+	err = writeToFilePosition(filePath, positionInfo.Position+models.PositionXOffset, buffer)
+	if err != nil {
+		return err
+	}
+
+	// 纵坐标
+	binary.LittleEndian.PutUint32(buffer, uint32(positionInfo.Y))
+	return writeToFilePosition(filePath, positionInfo.Position+models.PositionYOffset, buffer)
+}
+
+// SaveProgressLocation 更新 WC.cfg 中指定进度的位置编号
+func SaveProgressLocation(cfgFilePath string, progressIndex int, locationID int) error {
+	if progressIndex < 0 || progressIndex >= models.MaxProgress {
+		return fmt.Errorf("无效的进度索引: %d", progressIndex)
+	}
+
+	buffer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buffer, uint32(int32(locationID)))
+	// 位置编号每个进度4字节
+	return writeToFilePosition(cfgFilePath, models.ProgressLocationPosition+int64(progressIndex)*4, buffer)
+}
+
+// EncodeName 将角色名编码为6字节的Big5名字字段，不足部分以空格补齐
+func EncodeName(name string) ([]byte, error) {
+	big5Bytes, err := traditionalchinese.Big5.NewEncoder().Bytes([]byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("角色名无法编码为Big5: %w", err)
+	}
+	if len(big5Bytes) == 0 {
+		return nil, fmt.Errorf("角色名不能为空")
+	}
+	if len(big5Bytes) > 6 {
+		return nil, fmt.Errorf("角色名过长，最多6字节（3个汉字）")
+	}
+
+	nameBytes := []byte("      ")
+	copy(nameBytes, big5Bytes)
+	return nameBytes, nil
+}
+
+// SaveParty 按角色顺序重写队伍记录区和队伍成员编号表，并写入结束标记和队伍人数
+// 角色记录区长度固定，之后的数据（如银两）位置不变
+func SaveParty(filePath string, characters []models.CharacterInfo) error {
+	if len(characters) > models.MaxCharacters {
+		return fmt.Errorf("%w: 队伍人数超过上限: %d", models.ErrBadLayout, len(characters))
+	}
+
+	// 整个记录区先清零，未使用的记录首2字节为0即为结束标记
+	area := make([]byte, models.CharacterRecordSize*models.MaxCharacters)
+	for i, char := range characters {
+		if len(char.RecordBytes) != models.CharacterRecordSize {
+			return fmt.Errorf("%w: 角色%d的记录长度错误: %d", models.ErrBadLayout, i+1, len(char.RecordBytes))
+		}
+		if char.Position != models.CharacterStartPosition+int64(i*models.CharacterRecordSize) {
+			return fmt.Errorf("%w: 角色%d的记录位置错误: %d", models.ErrBadLayout, i+1, char.Position)
+		}
+		for j := range i {
+			if characters[j].MemberID == char.MemberID {
+				return fmt.Errorf("%w: 角色%d与角色%d的成员编号重复: %d", models.ErrBadLayout, j+1, i+1, char.MemberID)
+			}
+		}
+		copy(area[i*models.CharacterRecordSize:], char.RecordBytes)
+	}
+
+	err := writeToFilePosition(filePath, models.CharacterStartPosition, area)
+	if err != nil {
+		return err
+	}
+
+	// 队伍成员编号表与角色记录顺序一致，未使用的项为0
+	members := make([]byte, 2*models.MaxCharacters)
+	for i, char := range characters {
+		binary.LittleEndian.PutUint16(members[i*2:], uint16(char.MemberID))
+	}
+	err = writeToFilePosition(filePath, models.PartyMemberPosition, members)
+	if err != nil {
+		return err
+	}
+
+	// 队伍人数（2字节）
+	countBuffer := make([]byte, 2)
+	binary.LittleEndian.PutUint16(countBuffer, uint16(len(characters)))
+	return writeToFilePosition(filePath, models.PartyCountPosition, countBuffer)
+}
+
+// SaveRawEdits 将原始字节修改写入文件
+func SaveRawEdits(filePath string, edits []models.RawEdit) error {
+	for _, edit := range edits {
+		err := writeToFilePosition(filePath, edit.Position, edit.Data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SaveChanges 保存修改到新文件
 func SaveChanges(sourceFilePath, destFilePath string, characters []models.CharacterInfo, moneyInfo models.MoneyInfo) error {
 	var err error
@@ -70,108 +188,43 @@ func SaveChanges(sourceFilePath, destFilePath string, characters []models.Charac
 
 	// 保存每个角色的属性修改
 	for _, char := range characters {
-		// 当前经验值
-		buffer := make([]byte, 4)
-		binary.LittleEndian.PutUint32(buffer, uint32(char.Data.CurrentExp))
-		err = writeToFilePosition(destFilePath, char.Position+8, buffer)
-		if err != nil {
-			return err
-		}
-
-		// 升级经验值
-		binary.LittleEndian.PutUint32(buffer, uint32(char.Data.NextLevelExp))
-		err = writeToFilePosition(destFilePath, char.Position+12, buffer)
-		if err != nil {
-			return err
-		}
-
-		// 最大生命值
-		binary.LittleEndian.PutUint32(buffer, uint32(char.Data.MaxHP))
-		err = writeToFilePosition(destFilePath, char.Position+16, buffer)
-		if err != nil {
-			return err
-		}
-
-		// 最大内力值
-		binary.LittleEndian.PutUint32(buffer, uint32(char.Data.MaxMP))
-		err = writeToFilePosition(destFilePath, char.Position+20, buffer)
-		if err != nil {
-			return err
-		}
-
-		// 当前生命值
-		binary.LittleEndian.PutUint32(buffer, uint32(char.Data.CurrentHP))
-		err = writeToFilePosition(destFilePath, char.Position+24, buffer)
-		if err != nil {
-			return err
-		}
-
-		// 当前内力值
-		binary.LittleEndian.PutUint32(buffer, uint32(char.Data.CurrentMP))
-		err = writeToFilePosition(destFilePath, char.Position+28, buffer)
-		if err != nil {
-			return err
-		}
-
-		// 2字节属性
-		buffer16 := make([]byte, 2)
-
-		// 力量
-		binary.LittleEndian.PutUint16(buffer16, uint16(char.Data.Strength))
-		err = writeToFilePosition(destFilePath, char.Position+32, buffer16)
-		if err != nil {
-			return err
-		}
-
-		// 反应
-		binary.LittleEndian.PutUint16(buffer16, uint16(char.Data.Reaction))
-		err = writeToFilePosition(destFilePath, char.Position+34, buffer16)
-		if err != nil {
-			return err
-		}
-
-		// 体质
-		binary.LittleEndian.PutUint16(buffer16, uint16(char.Data.Constitution))
-		err = writeToFilePosition(destFilePath, char.Position+36, buffer16)
+		err = encodeCharacterFields(char.Data, func(field layout.Field, encoded []byte) error {
+			return writeToFilePosition(destFilePath, char.Position+field.Offset, encoded)
+		})
 		if err != nil {
 			return err
 		}
+	}
 
-		// 速度
-		binary.LittleEndian.PutUint16(buffer16, uint16(char.Data.Speed))
-		err = writeToFilePosition(destFilePath, char.Position+38, buffer16)
-		if err != nil {
-			return err
-		}
+	return nil
+}
 
-		// 攻击
-		binary.LittleEndian.PutUint16(buffer16, uint16(char.Data.Attack))
-		err = writeToFilePosition(destFilePath, char.Position+40, buffer16)
-		if err != nil {
-			return err
-		}
+// EncodeCharacterData 将角色属性写入一条角色记录（record 从记录起始位置开始），记录中的其他字节不变
+func EncodeCharacterData(record []byte, data models.CharacterData) error {
+	if len(record) < models.CharacterRecordSize {
+		return fmt.Errorf("%w: 角色记录长度错误: %d", models.ErrBadLayout, len(record))
+	}
+	return encodeCharacterFields(data, func(field layout.Field, encoded []byte) error {
+		copy(record[field.Offset:], encoded)
+		return nil
+	})
+}
 
-		// 防御
-		binary.LittleEndian.PutUint16(buffer16, uint16(char.Data.Defense))
-		err = writeToFilePosition(destFilePath, char.Position+42, buffer16)
-		if err != nil {
-			return err
+// encodeCharacterFields 按 layout.CharacterFields 编码角色的整数属性，依次交给 put 写入
+func encodeCharacterFields(data models.CharacterData, put func(field layout.Field, encoded []byte) error) error {
+	value := reflect.ValueOf(data)
+	for _, field := range layout.CharacterFields {
+		target := value.FieldByName(field.Name)
+		if !field.Type.Integer() || !target.IsValid() {
+			continue
 		}
-
-		// 运气
-		binary.LittleEndian.PutUint16(buffer16, uint16(char.Data.Luck))
-		err = writeToFilePosition(destFilePath, char.Position+52, buffer16)
+		encoded, err := field.Encode(target.Int())
 		if err != nil {
 			return err
 		}
-
-		// 等级
-		binary.LittleEndian.PutUint16(buffer16, uint16(char.Data.Level))
-		err = writeToFilePosition(destFilePath, char.Position+70, buffer16)
-		if err != nil {
+		if err := put(field, encoded); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
@@ -1,12 +1,17 @@
 package writer
 
 import (
+	"bytes"
 	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"wcediter/wcsave/layout"
 	"wcediter/wcsave/models"
+	"wcediter/wcsave/reader"
 )
 
 // 测试copyFile函数
@@ -114,4 +119,196 @@ func TestSaveChanges(t *testing.T) {
 	if _, err := os.Stat(destFilePath); os.IsNotExist(err) {
 		t.Fatal("目标文件未创建")
 	}
-}
\ No newline at end of file
+}
+
+// 测试SavePosition函数
+func TestSavePosition(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test_position.dat")
+	if err := os.WriteFile(filePath, make([]byte, 60), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	positionInfo := models.PositionInfo{
+		MapID:    92,
+		X:        47,
+		Y:        74,
+		RawBytes: make([]byte, 12),
+		Position: 10,
+	}
+	if err := SavePosition(filePath, positionInfo); err != nil {
+		t.Fatalf("SavePosition失败: %v", err)
+	}
+
+	resultData, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+
+	if v := binary.LittleEndian.Uint32(resultData[10:]); v != 92 {
+		t.Errorf("地图编号错误，预期92，实际%d", v)
+	}
+	if v := binary.LittleEndian.Uint32(resultData[46:]); v != 47 {
+		t.Errorf("横坐标错误，预期47，实际%d", v)
+	}
+	if v := binary.LittleEndian.Uint32(resultData[50:]); v != 74 {
+		t.Errorf("纵坐标错误，预期74，实际%d", v)
+	}
+}
+
+// 测试SaveProgressLocation函数
+func TestSaveProgressLocation(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "WC.cfg")
+	if err := os.WriteFile(cfgPath, make([]byte, 76), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	if err := SaveProgressLocation(cfgPath, 2, 53); err != nil {
+		t.Fatalf("SaveProgressLocation失败: %v", err)
+	}
+
+	resultData, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatalf("读取测试文件失败: %v", err)
+	}
+	if v := binary.LittleEndian.Uint32(resultData[64:]); v != 53 {
+		t.Errorf("位置编号错误，预期53，实际%d", v)
+	}
+
+	// 无效的进度索引
+	if err := SaveProgressLocation(cfgPath, 5, 53); err == nil {
+		t.Error("无效的进度索引应该返回错误")
+	}
+}
+
+// 测试EncodeName函数
+func TestEncodeName(t *testing.T) {
+	nameBytes, err := EncodeName("步驚雲")
+	if err != nil {
+		t.Fatalf("EncodeName失败: %v", err)
+	}
+	expected := []byte{0xA8, 0x42, 0xC5, 0xE5, 0xB6, 0xB3}
+	if string(nameBytes) != string(expected) {
+		t.Errorf("编码结果错误，预期% X，实际% X", expected, nameBytes)
+	}
+
+	// 不足6字节时补空格
+	nameBytes, err = EncodeName("風")
+	if err != nil {
+		t.Fatalf("EncodeName失败: %v", err)
+	}
+	if len(nameBytes) != 6 || nameBytes[5] != ' ' {
+		t.Errorf("补齐结果错误: % X", nameBytes)
+	}
+
+	// 过长或为空的名字
+	if _, err := EncodeName("步驚雲步"); err == nil {
+		t.Error("过长的名字应该返回错误")
+	}
+	if _, err := EncodeName(""); err == nil {
+		t.Error("空名字应该返回错误")
+	}
+}
+
+// randomCharacterData 生成随机的角色属性，覆盖各字段类型的全部取值
+func randomCharacterData(rng *rand.Rand) models.CharacterData {
+	int32Value := func() int32 { return int32(rng.Uint32()) }
+	int16Value := func() int16 { return int16(rng.Uint32()) }
+	return models.CharacterData{
+		CurrentExp:   int32Value(),
+		NextLevelExp: int32Value(),
+		CurrentHP:    int32Value(),
+		CurrentMP:    int32Value(),
+		MaxHP:        int32Value(),
+		MaxMP:        int32Value(),
+		Strength:     int16Value(),
+		Reaction:     int16Value(),
+		Constitution: int16Value(),
+		Speed:        int16Value(),
+		Attack:       int16Value(),
+		Defense:      int16Value(),
+		Luck:         int16Value(),
+		Level:        int16Value(),
+	}
+}
+
+// 属性测试：写入随机的角色属性和银两后重新读取，应与写入的值一致，且不修改其他字节
+func TestSaveChangesRoundTrip(t *testing.T) {
+	paths, _ := filepath.Glob("../../data/*.dat")
+	if len(paths) == 0 {
+		t.Skip("测试数据文件不存在，跳过属性测试")
+	}
+
+	for _, sourcePath := range paths {
+		original, err := os.ReadFile(sourcePath)
+		if err != nil {
+			t.Fatalf("读取测试文件失败: %v", err)
+		}
+		file, err := os.Open(sourcePath)
+		if err != nil {
+			t.Fatalf("打开测试文件失败: %v", err)
+		}
+		characters, err := reader.ReadCharacters(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: ReadCharacters失败: %v", sourcePath, err)
+		}
+
+		// 以文件名作为随机种子，失败时可以复现
+		seed := fnv.New64a()
+		seed.Write([]byte(filepath.Base(sourcePath)))
+		rng := rand.New(rand.NewSource(int64(seed.Sum64())))
+		for iteration := 0; iteration < 20; iteration++ {
+			for i := range characters {
+				characters[i].Data = randomCharacterData(rng)
+			}
+			moneyInfo := models.MoneyInfo{Value: int32(rng.Uint32()), RawBytes: make([]byte, 4), Position: models.MoneyPosition}
+
+			destPath := filepath.Join(t.TempDir(), filepath.Base(sourcePath))
+			if err := SaveChanges(sourcePath, destPath, characters, moneyInfo); err != nil {
+				t.Fatalf("%s: SaveChanges失败: %v", sourcePath, err)
+			}
+
+			written, err := os.ReadFile(destPath)
+			if err != nil {
+				t.Fatalf("读取写入的文件失败: %v", err)
+			}
+			reread, err := reader.ReadCharacters(bytes.NewReader(written))
+			if err != nil {
+				t.Fatalf("%s: 重新读取失败: %v", sourcePath, err)
+			}
+			if len(reread) != len(characters) {
+				t.Fatalf("%s: 角色数量由%d变为%d", sourcePath, len(characters), len(reread))
+			}
+			for i := range characters {
+				if reread[i].Data != characters[i].Data {
+					t.Fatalf("%s 第%d轮: 角色%d的数据不一致\n写入: %+v\n读取: %+v", sourcePath, iteration, i, characters[i].Data, reread[i].Data)
+				}
+			}
+			money, err := reader.ReadMoneyData(bytes.NewReader(written), models.MoneyPosition)
+			if err != nil || money.Value != moneyInfo.Value {
+				t.Fatalf("%s 第%d轮: 银两写入%d，读取%d（%v）", sourcePath, iteration, moneyInfo.Value, money.Value, err)
+			}
+
+			// 除写入的字段外，其余字节保持不变
+			changed := make(map[int64]bool)
+			for _, char := range characters {
+				for _, field := range layout.CharacterFields {
+					if !field.Type.Integer() {
+						continue
+					}
+					for b := int64(0); b < int64(field.Size); b++ {
+						changed[char.Position+field.Offset+b] = true
+					}
+				}
+			}
+			for b := int64(0); b < 4; b++ {
+				changed[models.MoneyPosition+b] = true
+			}
+			for i := range original {
+				if !changed[int64(i)] && original[i] != written[i] {
+					t.Fatalf("%s: 偏移0x%X处的字节被意外修改", sourcePath, i)
+				}
+			}
+		}
+	}
+}